@@ -0,0 +1,83 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SilenceSpec defines which incidents are silenced and when. All the non-empty
+// matchers must match an incident for it to be silenced.
+type SilenceSpec struct {
+	// Namespaces is a list of namespace globs such as "prod-*"
+	//+optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// Releases is a list of release name globs
+	//+optional
+	Releases []string `json:"releases,omitempty"`
+
+	// Reasons is a list of Kubernetes reasons such as OOMKilled or ErrImagePull
+	//+optional
+	Reasons []string `json:"reasons,omitempty"`
+
+	// Selector matches the labels of the pods of the incident
+	//+optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// StartsAt is the time the silence becomes active, immediately if unset
+	//+optional
+	StartsAt *metav1.Time `json:"startsAt,omitempty"`
+
+	// EndsAt is the time the silence stops being active, never if unset, which
+	// is only allowed for scheduled silences
+	//+optional
+	EndsAt *metav1.Time `json:"endsAt,omitempty"`
+
+	// Schedule is a cron expression starting a recurring maintenance window,
+	// such as "0 2 * * SAT". A time zone can be given with a "CRON_TZ=" prefix.
+	// The silence is only active within StartsAt and EndsAt and while a window
+	// is open.
+	//+optional
+	Schedule string `json:"schedule,omitempty"`
+
+	// Duration is the length of each maintenance window started by Schedule
+	//+optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+
+	// CreatedBy is the creator of the silence
+	//+optional
+	CreatedBy string `json:"createdBy,omitempty"`
+
+	// Comment explains why the silence was created
+	//+optional
+	Comment string `json:"comment,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Namespaces",type=string,JSONPath=`.spec.namespaces`
+//+kubebuilder:printcolumn:name="Starts",type=date,JSONPath=`.spec.startsAt`
+//+kubebuilder:printcolumn:name="Ends",type=date,JSONPath=`.spec.endsAt`
+//+kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.schedule`
+//+kubebuilder:printcolumn:name="Comment",type=string,JSONPath=`.spec.comment`
+
+// Silence suppresses the notifications of matching incidents. The incidents
+// are still recorded while a silence is active.
+type Silence struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec SilenceSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// SilenceList contains a list of Silence
+type SilenceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Silence `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Silence{}, &SilenceList{})
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+func newArchiveCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "archive",
+		Short: "Manage the incidents and logs archived before they expired",
+	}
+
+	cmd.AddCommand(newArchiveRestoreCmd())
+
+	return cmd
+}
+
+func newArchiveRestoreCmd() *cobra.Command {
+	var (
+		req          models.RestoreArchiveRequest
+		since, until string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Restore archived incidents and logs",
+		Long: `Restore archived incidents and logs into the agent, where they are kept for
+the restore retention. --since and --until are either RFC 3339 times or
+durations before now, such as 720h.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			if req.Since, err = parseTime(since); err != nil {
+				return fmt.Errorf("invalid --since: %w", err)
+			}
+
+			if req.Until, err = parseTime(until); err != nil {
+				return fmt.Errorf("invalid --until: %w", err)
+			}
+
+			res, err := newClient().RestoreArchive(cmd.Context(), &req)
+			if err != nil {
+				return err
+			}
+
+			return printObject(os.Stdout, res, func(w *tabwriter.Writer) {
+				printRow(w, "INCIDENTS", "LOGS", "SKIPPED")
+				printRow(w, strconv.Itoa(res.Incidents), strconv.Itoa(res.Logs), strconv.Itoa(res.Skipped))
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&req.Namespace, "namespace", "n", "", "only restore the incidents and logs of this namespace")
+	cmd.Flags().StringVar(&since, "since", "", "only restore incidents created and logs captured after this time")
+	cmd.Flags().StringVar(&until, "until", "", "only restore incidents created and logs captured before this time")
+
+	return cmd
+}
+
+// parseTime parses an RFC 3339 time or a duration before now as a unix
+// timestamp, where an empty value is zero
+func parseTime(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d).Unix(), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is neither an RFC 3339 time nor a duration", value)
+	}
+
+	return t.Unix(), nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/porter-dev/porter-agent/pkg/client"
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+// how long to wait before reconnecting to the change stream
+const streamRetryInterval = 2 * time.Second
+
+func newEventsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "events",
+		Short: "Follow the changes of incidents",
+	}
+
+	cmd.AddCommand(newEventsTailCmd())
+
+	return cmd
+}
+
+func newEventsTailCmd() *cobra.Command {
+	opts := &client.StreamIncidentsOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "tail",
+		Short: "Print the changes of incidents as they happen",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// the table header is only printed once, the following rows are
+			// aligned with a fixed width instead
+			if output == outputTable {
+				fmt.Printf("%-20s %-8s %-15s %-45s %-20s %s\n", "ID", "AGE", "TYPE", "INCIDENT", "STATE", "REASON")
+			}
+
+			return watchIncidentChanges(cmd.Context(), opts, func(change *models.IncidentChange) error {
+				return printObject(os.Stdout, change, func(w *tabwriter.Writer) {
+					fmt.Fprintf(w, "%-20s %-8s %-15s %-45s %-20s %s\n",
+						change.ID,
+						age(change.Timestamp),
+						change.Type,
+						change.IncidentID,
+						valueOrNone(change.State),
+						changeReason(change),
+					)
+				})
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Namespace, "namespace", "n", "", "only print the changes of incidents in this namespace")
+	cmd.Flags().StringVar(&opts.Release, "release", "", "only print the changes of incidents of this release")
+	cmd.Flags().StringVar(&opts.LastEventID, "since-id", "", "start after the change with this ID instead of with the next change")
+
+	return cmd
+}
+
+func changeReason(change *models.IncidentChange) string {
+	if change.Event == nil {
+		return "<none>"
+	}
+
+	return truncate(change.Event.Reason, 60)
+}
+
+// watchIncidentChanges calls fn with each change of incidents until the
+// context is done, reconnecting to the stream after the last change it read
+// if the connection is lost
+func watchIncidentChanges(ctx context.Context, opts *client.StreamIncidentsOptions,
+	fn func(change *models.IncidentChange) error) error {
+	streamOpts := *opts
+
+	for {
+		err := newClient().StreamIncidentChanges(ctx, &streamOpts, func(change *models.IncidentChange) error {
+			streamOpts.LastEventID = change.ID
+			return fn(change)
+		})
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		// errors returned by the API, such as authentication errors, are
+		// not retried unless the agent is unavailable
+		var apiErr *client.APIError
+
+		if errors.As(err, &apiErr) && !client.IsUnavailable(err) {
+			return err
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "lost connection to the agent, reconnecting: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(streamRetryInterval):
+		}
+	}
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/porter-dev/porter-agent/pkg/client"
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/stacktrace"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+func newIncidentsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "incidents",
+		Aliases: []string{"incident", "inc"},
+		Short:   "List, inspect and act on incidents",
+	}
+
+	cmd.AddCommand(
+		newIncidentsListCmd(),
+		newIncidentsGetCmd(),
+		newIncidentsSnapshotCmd(),
+		newIncidentsRemediationsCmd(),
+		newIncidentActionCmd("ack", "Acknowledge an ongoing incident", (*client.Client).AcknowledgeIncident),
+		newIncidentActionCmd("resolve", "Resolve an ongoing incident", (*client.Client).ResolveIncident),
+	)
+
+	return cmd
+}
+
+func newIncidentsListCmd() *cobra.Command {
+	var (
+		states     []string
+		severities []string
+		namespaces []string
+		releases   []string
+		reasons    []string
+		prints     []string
+		rollout    bool
+		sortBy     string
+		ascending  bool
+		since      time.Duration
+		limit      int
+		watch      bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List incidents, most recent first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts := &client.ListIncidentsOptions{
+				Namespaces:   namespaces,
+				Releases:     releases,
+				Reasons:      reasons,
+				Fingerprints: prints,
+				AfterRollout: rollout,
+				SortBy:       sortBy,
+				Ascending:    ascending,
+				Limit:        limit,
+			}
+
+			for _, state := range states {
+				opts.States = append(opts.States, strings.ToUpper(state))
+			}
+
+			for _, severity := range severities {
+				opts.Severities = append(opts.Severities, models.Severity(severity))
+			}
+
+			list := func(ctx context.Context) error {
+				if since > 0 {
+					opts.UpdatedAfter = time.Now().Add(-since)
+				}
+
+				incidents, err := listIncidents(ctx, opts)
+				if err != nil {
+					return err
+				}
+
+				return printIncidents(incidents)
+			}
+
+			if err := list(cmd.Context()); err != nil {
+				return err
+			}
+
+			if !watch {
+				return nil
+			}
+
+			// the stream can only be filtered on a single namespace and release
+			streamOpts := &client.StreamIncidentsOptions{}
+
+			if len(namespaces) == 1 {
+				streamOpts.Namespace = namespaces[0]
+			}
+
+			if len(releases) == 1 {
+				streamOpts.Release = releases[0]
+			}
+
+			return watchIncidentChanges(cmd.Context(), streamOpts, func(change *models.IncidentChange) error {
+				fmt.Println()
+				return list(cmd.Context())
+			})
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&states, "state", nil, "only list incidents in these states, such as ongoing or resolved")
+	cmd.Flags().StringSliceVar(&severities, "severity", nil, "only list incidents with these severities")
+	cmd.Flags().StringSliceVarP(&namespaces, "namespace", "n", nil, "only list incidents in these namespaces")
+	cmd.Flags().StringSliceVar(&releases, "release", nil, "only list incidents of these releases")
+	cmd.Flags().StringSliceVar(&reasons, "reason", nil, "only list incidents with these reasons")
+	cmd.Flags().StringSliceVar(&prints, "fingerprint", nil, "only list incidents whose latest crash has these stack trace fingerprints")
+	cmd.Flags().BoolVar(&rollout, "after-rollout", false, "only list incidents which started shortly after a rollout")
+	cmd.Flags().StringVar(&sortBy, "sort", "created_at", "sort by created_at or updated_at")
+	cmd.Flags().BoolVar(&ascending, "asc", false, "sort oldest first")
+	cmd.Flags().DurationVar(&since, "since", 0, "only list incidents updated within this duration, such as 24h")
+	cmd.Flags().IntVar(&limit, "limit", 0, "maximum number of incidents to list, at most 500, all of them by default")
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "list the incidents again whenever they change")
+
+	return cmd
+}
+
+func listIncidents(ctx context.Context, opts *client.ListIncidentsOptions) ([]*models.Incident, error) {
+	if opts.Limit > 0 {
+		res, err := newClient().ListIncidents(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		return res.Incidents, nil
+	}
+
+	return newClient().ListAllIncidents(ctx, opts)
+}
+
+func printIncidents(incidents []*models.Incident) error {
+	if incidents == nil {
+		incidents = []*models.Incident{}
+	}
+
+	return printObject(os.Stdout, incidents, func(w *tabwriter.Writer) {
+		printRow(w, "ID", "NAMESPACE", "RELEASE", "SEVERITY", "STATE", "REASON", "CREATED", "UPDATED")
+
+		for _, incident := range incidents {
+			printRow(w,
+				incident.ID,
+				incident.Namespace,
+				incident.ReleaseName,
+				valueOrNone(string(incident.Severity)),
+				incidentState(incident),
+				truncate(incident.LatestReason, 40),
+				age(incident.CreatedAt),
+				age(incident.UpdatedAt),
+			)
+		}
+	})
+}
+
+// incidentState is the state of an incident along with whether it is
+// acknowledged or silenced
+func incidentState(incident *models.Incident) string {
+	state := incident.LatestState
+
+	if incident.Acknowledged {
+		state += ",ACKNOWLEDGED"
+	}
+
+	if incident.Silenced {
+		state += ",SILENCED"
+	}
+
+	return state
+}
+
+func newIncidentsGetCmd() *cobra.Command {
+	var watch bool
+
+	cmd := &cobra.Command{
+		Use:   "get INCIDENT_ID",
+		Short: "Show an incident and its events",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			incidentID := args[0]
+
+			get := func(ctx context.Context) error {
+				incident, err := newClient().GetIncident(ctx, incidentID)
+				if err != nil {
+					return err
+				}
+
+				return printIncident(incident)
+			}
+
+			if err := get(cmd.Context()); err != nil {
+				return err
+			}
+
+			if !watch {
+				return nil
+			}
+
+			return watchIncidentChanges(cmd.Context(), &client.StreamIncidentsOptions{}, func(change *models.IncidentChange) error {
+				if change.IncidentID != incidentID {
+					return nil
+				}
+
+				fmt.Println()
+				return get(cmd.Context())
+			})
+		},
+	}
+
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "show the incident again whenever it changes")
+
+	return cmd
+}
+
+func printIncident(incident *models.IncidentEventsResponse) error {
+	return printObject(os.Stdout, incident, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "ID:\t%s\n", incident.IncidentID)
+		fmt.Fprintf(w, "Namespace:\t%s\n", incident.Namespace)
+		fmt.Fprintf(w, "Release:\t%s\n", incident.ReleaseName)
+		fmt.Fprintf(w, "Chart:\t%s\n", valueOrNone(incident.ChartName))
+		fmt.Fprintf(w, "State:\t%s\n", incident.LatestState)
+		fmt.Fprintf(w, "Reason:\t%s\n", incident.LatestReason)
+		fmt.Fprintf(w, "Message:\t%s\n", truncate(incident.LatestMessage, 120))
+		fmt.Fprintf(w, "Created:\t%s ago\n", age(incident.CreatedAt))
+		fmt.Fprintf(w, "Updated:\t%s ago\n", age(incident.UpdatedAt))
+
+		// events are listed newest first
+		for _, event := range incident.Events {
+			if event.StackTrace == nil {
+				continue
+			}
+
+			fmt.Fprintf(w, "Crash:\t%s\n", stacktrace.Summary(event.StackTrace))
+
+			if len(event.StackTrace.Frames) > 0 {
+				frame := event.StackTrace.Frames[0]
+				fmt.Fprintf(w, "At:\t%s (%s:%d)\n", frame.Function, valueOrNone(frame.File), frame.Line)
+			}
+
+			fmt.Fprintf(w, "Fingerprint:\t%s\n", event.StackTrace.Fingerprint)
+
+			break
+		}
+
+		// the rollout is described by the first event, listed last
+		for i := len(incident.Events) - 1; i >= 0; i-- {
+			if rollout := incident.Events[i].Rollout; rollout != nil {
+				printRollout(w, rollout, incident.CreatedAt)
+				break
+			}
+		}
+
+		fmt.Fprintln(w)
+
+		printRow(w, "EVENT", "AGE", "POD", "SEVERITY", "REASON", "CONTAINER", "LOG ID")
+
+		for _, event := range incident.Events {
+			containerNames := make([]string, 0, len(event.ContainerEvents))
+
+			for name := range event.ContainerEvents {
+				containerNames = append(containerNames, name)
+			}
+
+			sort.Strings(containerNames)
+
+			if len(containerNames) == 0 {
+				printRow(w, event.EventID, age(event.Timestamp), event.PodName,
+					valueOrNone(string(event.Severity)), truncate(event.Reason, 40), "<none>", "<none>")
+				continue
+			}
+
+			// one row per container, as each container has its own logs
+			for _, name := range containerNames {
+				containerEvent := event.ContainerEvents[name]
+
+				printRow(w, event.EventID, age(event.Timestamp), event.PodName,
+					valueOrNone(string(event.Severity)), truncate(containerEvent.Reason, 40),
+					name, valueOrNone(containerEvent.LogID))
+			}
+		}
+	})
+}
+
+func printRollout(w *tabwriter.Writer, rollout *models.Rollout, createdAt int64) {
+	after := duration.HumanDuration(time.Unix(createdAt, 0).Sub(time.Unix(rollout.RolledOutAt, 0)))
+
+	fmt.Fprintf(w, "Rollout:\trevision %s, %s before the incident\n", valueOrNone(rollout.Revision), after)
+
+	if rollout.PreviousRevision == "" {
+		return
+	}
+
+	if len(rollout.Changes) == 0 {
+		fmt.Fprintf(w, "Changes:\tnone since revision %s\n", rollout.PreviousRevision)
+		return
+	}
+
+	fmt.Fprintf(w, "Changes:\tsince revision %s\n", rollout.PreviousRevision)
+
+	for _, change := range rollout.Changes {
+		field := change.Field
+		if change.Container != "" {
+			field = change.Container + " " + field
+		}
+
+		switch {
+		case change.Previous == "":
+			fmt.Fprintf(w, "  + %s:\t%s\n", field, change.Current)
+		case change.Current == "":
+			fmt.Fprintf(w, "  - %s:\t%s\n", field, change.Previous)
+		default:
+			fmt.Fprintf(w, "  ~ %s:\t%s -> %s\n", field, change.Previous, change.Current)
+		}
+	}
+}
+
+type incidentAction func(c *client.Client, ctx context.Context, incidentID, user string) (*models.Incident, error)
+
+func newIncidentActionCmd(use, short string, action incidentAction) *cobra.Command {
+	var user string
+
+	cmd := &cobra.Command{
+		Use:   use + " INCIDENT_ID",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			incident, err := action(newClient(), cmd.Context(), args[0], user)
+			if err != nil {
+				return err
+			}
+
+			return printIncidents([]*models.Incident{incident})
+		},
+	}
+
+	cmd.Flags().StringVar(&user, "user", os.Getenv("USER"), "user recorded for the action when authenticating with an API token")
+
+	return cmd
+}
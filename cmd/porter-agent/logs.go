@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/porter-dev/porter-agent/pkg/client"
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+func newLogsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "logs",
+		Short: "Read and search the logs captured for incidents",
+	}
+
+	cmd.AddCommand(newLogsGetCmd(), newLogsSearchCmd())
+
+	return cmd
+}
+
+func newLogsGetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get LOG_ID",
+		Short: "Print captured logs",
+		Long:  "Print captured logs. The log IDs of an incident are listed by \"incidents get\".",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			contents, err := newClient().GetLogs(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			// the table output is the logs as they were captured
+			if output == outputTable {
+				_, err := fmt.Fprint(os.Stdout, contents)
+				return err
+			}
+
+			return printObject(os.Stdout, &models.LogsResponse{Contents: contents}, nil)
+		},
+	}
+}
+
+func newLogsSearchCmd() *cobra.Command {
+	var (
+		opts  client.SearchLogsOptions
+		since time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "search QUERY",
+		Short: "Search the captured logs, newest first",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if since > 0 {
+				opts.Since = time.Now().Add(-since)
+			}
+
+			res, err := newClient().SearchLogs(cmd.Context(), args[0], &opts)
+			if err != nil {
+				return err
+			}
+
+			if res.Truncated {
+				fmt.Fprintln(os.Stderr, "the search stopped before reading all the logs, narrow it with --namespace or --since to find older matches")
+			}
+
+			results := res.Results
+			if results == nil {
+				results = []*models.LogSearchResult{}
+			}
+
+			return printObject(os.Stdout, results, func(w *tabwriter.Writer) {
+				printRow(w, "LOG ID", "NAMESPACE", "RELEASE", "CONTAINER", "AGE", "LINE", "MATCH")
+
+				for _, result := range results {
+					for _, match := range result.Matches {
+						printRow(w,
+							result.LogID,
+							result.Namespace,
+							result.ReleaseName,
+							valueOrNone(result.Container),
+							age(result.Timestamp),
+							strconv.Itoa(match.LineNumber),
+							truncate(match.Line, 100),
+						)
+					}
+				}
+			})
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.Regex, "regex", false, "treat the query as a regular expression instead of words")
+	cmd.Flags().StringVarP(&opts.Namespace, "namespace", "n", "", "only search the logs of this namespace")
+	cmd.Flags().DurationVar(&since, "since", 0, "only search the logs captured within this duration, such as 24h")
+	cmd.Flags().IntVar(&opts.Limit, "limit", 0, "maximum number of logs to return")
+
+	return cmd
+}
@@ -0,0 +1,68 @@
+// Command porter-agent is a command-line client for the agent API, which is
+// reached through a port-forward or the in-cluster service of the agent.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/porter-dev/porter-agent/pkg/client"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	server string
+	token  string
+	output string
+)
+
+func init() {
+	viper.SetDefault("PORTER_AGENT_SERVER", "http://localhost:10001")
+	viper.AutomaticEnv()
+}
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if err := runWith(ctx, os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runWith(ctx context.Context, args []string) error {
+	rootCmd := &cobra.Command{
+		Use:   "porter-agent",
+		Short: "Query the incidents and logs of a porter agent",
+		Long: `Query the incidents and logs of a porter agent.
+
+The agent API is reached at --server, for example after running:
+
+  kubectl -n porter-agent-system port-forward svc/porter-agent-controller-manager 10001`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateOutput(output)
+		},
+	}
+
+	rootCmd.PersistentFlags().StringVar(&server, "server", viper.GetString("PORTER_AGENT_SERVER"),
+		"URL of the agent API, defaults to $PORTER_AGENT_SERVER")
+	rootCmd.PersistentFlags().StringVar(&token, "token", viper.GetString("PORTER_AGENT_TOKEN"),
+		"bearer token for the agent API, defaults to $PORTER_AGENT_TOKEN")
+	rootCmd.PersistentFlags().StringVarP(&output, "output", "o", outputTable, "output format, one of table, json or yaml")
+
+	rootCmd.AddCommand(newIncidentsCmd(), newLogsCmd(), newEventsCmd(), newArchiveCmd())
+	rootCmd.SetArgs(args)
+
+	return rootCmd.ExecuteContext(ctx)
+}
+
+func newClient() *client.Client {
+	return client.NewClient(server, token)
+}
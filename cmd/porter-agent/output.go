@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+func validateOutput(format string) error {
+	switch format {
+	case outputTable, outputJSON, outputYAML:
+		return nil
+	default:
+		return fmt.Errorf("invalid output format %q, must be one of table, json or yaml", format)
+	}
+}
+
+// printObject prints obj as JSON or YAML, or calls printTable for the table
+// output. Objects printed one after the other, such as in watch mode, are
+// separate JSON values or YAML documents.
+func printObject(w io.Writer, obj interface{}, printTable func(w *tabwriter.Writer)) error {
+	switch output {
+	case outputJSON:
+		objJSON, err := json.MarshalIndent(obj, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintln(w, string(objJSON))
+		return err
+	case outputYAML:
+		objYAML, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(w, "---\n%s", objYAML)
+		return err
+	default:
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		printTable(tw)
+		return tw.Flush()
+	}
+}
+
+func printRow(w io.Writer, columns ...string) {
+	fmt.Fprintln(w, strings.Join(columns, "\t"))
+}
+
+// age formats a unix timestamp as the time since then, like kubectl does
+func age(timestamp int64) string {
+	if timestamp == 0 {
+		return "<unknown>"
+	}
+
+	return duration.HumanDuration(time.Since(time.Unix(timestamp, 0)))
+}
+
+// truncate shortens a string to a single line of at most max characters
+func truncate(s string, max int) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i] + "..."
+	}
+
+	if runes := []rune(s); len(runes) > max {
+		s = string(runes[:max-3]) + "..."
+	}
+
+	return s
+}
+
+func valueOrNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+
+	return s
+}
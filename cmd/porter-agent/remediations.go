@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+func newIncidentsRemediationsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "remediations INCIDENT_ID",
+		Short: "List the actions taken to remediate an incident",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			remediations, err := newClient().ListIncidentRemediations(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			return printRemediations(remediations)
+		},
+	}
+}
+
+func printRemediations(remediations []*models.Remediation) error {
+	return printObject(os.Stdout, remediations, func(w *tabwriter.Writer) {
+		printRow(w, "AGE", "ACTION", "TARGET", "CONTAINER", "STATUS", "CHANGES", "REASON")
+
+		for _, remediation := range remediations {
+			// the error of failed actions is more useful than their reason
+			reason := remediation.Reason
+			if remediation.Error != "" {
+				reason = remediation.Error
+			}
+
+			printRow(w, age(remediation.Timestamp), remediation.Action, remediation.Target,
+				valueOrNone(remediation.Container), string(remediation.Status),
+				valueOrNone(formatChanges(remediation.Changes)), truncate(reason, 80))
+		}
+	})
+}
+
+// formatChanges formats changes as "field: previous -> current"
+func formatChanges(changes []*models.RevisionChange) string {
+	formatted := make([]string, 0, len(changes))
+
+	for _, change := range changes {
+		formatted = append(formatted, change.Field+": "+valueOrNone(change.Previous)+" -> "+valueOrNone(change.Current))
+	}
+
+	return strings.Join(formatted, ", ")
+}
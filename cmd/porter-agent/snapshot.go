@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+func newIncidentsSnapshotCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "snapshot INCIDENT_ID EVENT_ID",
+		Short: "Describe the pod of an event as it was when the event was recorded",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			snapshot, err := newClient().GetEventSnapshot(cmd.Context(), args[0], args[1])
+			if err != nil {
+				return err
+			}
+
+			return printSnapshot(snapshot)
+		},
+	}
+}
+
+// printSnapshot prints a snapshot along the lines of kubectl describe pod
+func printSnapshot(snapshot *models.PodSnapshot) error {
+	return printObject(os.Stdout, snapshot, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "Name:\t%s\n", snapshot.PodName)
+		fmt.Fprintf(w, "Namespace:\t%s\n", snapshot.Namespace)
+		fmt.Fprintf(w, "Node:\t%s\n", valueOrNone(snapshot.NodeName))
+		fmt.Fprintf(w, "Captured:\t%s ago\n", age(snapshot.CapturedAt))
+		fmt.Fprintf(w, "Started:\t%s ago\n", age(snapshot.StartTime))
+		fmt.Fprintf(w, "Labels:\t%s\n", formatMap(snapshot.Labels, ","))
+		fmt.Fprintf(w, "Phase:\t%s\n", snapshot.Phase)
+		fmt.Fprintf(w, "QoS Class:\t%s\n", valueOrNone(snapshot.QOSClass))
+
+		if snapshot.Reason != "" {
+			fmt.Fprintf(w, "Reason:\t%s\n", snapshot.Reason)
+			fmt.Fprintf(w, "Message:\t%s\n", snapshot.Message)
+		}
+
+		if len(snapshot.InitContainers) > 0 {
+			fmt.Fprintln(w, "Init Containers:")
+			printContainerSnapshots(w, snapshot.InitContainers)
+		}
+
+		fmt.Fprintln(w, "Containers:")
+		printContainerSnapshots(w, snapshot.Containers)
+
+		fmt.Fprintln(w, "Conditions:")
+		printConditions(w, snapshot.Conditions)
+
+		if node := snapshot.Node; node != nil {
+			fmt.Fprintf(w, "Node %s:\n", node.Name)
+			fmt.Fprintf(w, "  Unschedulable:\t%t\n", node.Unschedulable)
+			fmt.Fprintf(w, "  Taints:\t%s\n", valueOrNone(strings.Join(node.Taints, ", ")))
+			fmt.Fprintf(w, "  Allocatable:\t%s\n", formatMap(node.Allocatable, ", "))
+			fmt.Fprintln(w, "  Conditions:")
+			printConditions(w, node.Conditions)
+		}
+
+		fmt.Fprintln(w, "Events:")
+
+		if len(snapshot.Events) == 0 {
+			fmt.Fprintln(w, "  <none>")
+			return
+		}
+
+		printRow(w, "  TYPE", "REASON", "AGE", "FROM", "MESSAGE")
+
+		for _, event := range snapshot.Events {
+			eventAge := age(event.LastTimestamp)
+
+			if event.Count > 1 {
+				eventAge = fmt.Sprintf("%s (x%d over %s)", eventAge, event.Count, age(event.FirstTimestamp))
+			}
+
+			printRow(w, "  "+event.Type, event.Reason, eventAge, valueOrNone(event.Source), truncate(event.Message, 120))
+		}
+	})
+}
+
+func printContainerSnapshots(w *tabwriter.Writer, containers []*models.ContainerSnapshot) {
+	for _, container := range containers {
+		fmt.Fprintf(w, "  %s:\n", container.Name)
+		fmt.Fprintf(w, "    Image:\t%s\n", container.Image)
+		fmt.Fprintf(w, "    Image ID:\t%s\n", valueOrNone(container.ImageID))
+		fmt.Fprintf(w, "    Ports:\t%s\n", valueOrNone(strings.Join(container.Ports, ", ")))
+
+		printContainerState(w, "State", container.State)
+		printContainerState(w, "Last State", container.LastState)
+
+		fmt.Fprintf(w, "    Ready:\t%t\n", container.Ready)
+		fmt.Fprintf(w, "    Restart Count:\t%d\n", container.RestartCount)
+		fmt.Fprintf(w, "    Requests:\t%s\n", formatMap(container.Requests, ", "))
+		fmt.Fprintf(w, "    Limits:\t%s\n", formatMap(container.Limits, ", "))
+
+		if container.LivenessProbe != "" {
+			fmt.Fprintf(w, "    Liveness:\t%s\n", container.LivenessProbe)
+		}
+
+		if container.ReadinessProbe != "" {
+			fmt.Fprintf(w, "    Readiness:\t%s\n", container.ReadinessProbe)
+		}
+
+		if container.StartupProbe != "" {
+			fmt.Fprintf(w, "    Startup:\t%s\n", container.StartupProbe)
+		}
+
+		fmt.Fprintf(w, "    Environment:\t%s\n", valueOrNone(strings.Join(container.Env, ", ")))
+	}
+}
+
+func printContainerState(w *tabwriter.Writer, name string, state *models.ContainerState) {
+	if state == nil {
+		return
+	}
+
+	fmt.Fprintf(w, "    %s:\t%s\n", name, strings.Title(state.State))
+
+	if state.Reason != "" {
+		fmt.Fprintf(w, "      Reason:\t%s\n", state.Reason)
+	}
+
+	if state.Message != "" {
+		fmt.Fprintf(w, "      Message:\t%s\n", truncate(state.Message, 120))
+	}
+
+	if state.State == "terminated" {
+		fmt.Fprintf(w, "      Exit Code:\t%d\n", state.ExitCode)
+	}
+
+	if state.StartedAt != 0 {
+		fmt.Fprintf(w, "      Started:\t%s ago\n", age(state.StartedAt))
+	}
+
+	if state.FinishedAt != 0 {
+		fmt.Fprintf(w, "      Finished:\t%s ago\n", age(state.FinishedAt))
+	}
+}
+
+func printConditions(w *tabwriter.Writer, conditions []*models.Condition) {
+	if len(conditions) == 0 {
+		fmt.Fprintln(w, "  <none>")
+		return
+	}
+
+	printRow(w, "  TYPE", "STATUS", "REASON", "MESSAGE")
+
+	for _, condition := range conditions {
+		printRow(w, "  "+condition.Type, condition.Status, valueOrNone(condition.Reason), truncate(condition.Message, 80))
+	}
+}
+
+// formatMap formats a map as sorted key=value pairs
+func formatMap(m map[string]string, sep string) string {
+	pairs := make([]string, 0, len(m))
+
+	for key, value := range m {
+		pairs = append(pairs, key+"="+value)
+	}
+
+	sort.Strings(pairs)
+
+	return valueOrNone(strings.Join(pairs, sep))
+}
@@ -0,0 +1,266 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/redact"
+	"github.com/porter-dev/porter-agent/pkg/tracing"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// headers separating the logs of the previous and the current run of a
+// container when both are captured
+const (
+	previousLogsHeader = "--- logs of the previous container ---\n"
+	currentLogsHeader  = "--- logs of the current container ---\n"
+)
+
+var (
+	// maximum size of the logs captured for a container
+	maxLogBytes int64
+
+	// number of lines captured instead of MAX_TAIL_LINES when the captured
+	// lines are mostly a stack trace, which is then likely cut off
+	stackTraceTailLines int64
+)
+
+// lines which are part of the stack traces of Go, Python, Node.js and Java
+var stackTraceLineRegexp = regexp.MustCompile(
+	`^(goroutine \d+ \[|panic: |\t.+\.go:\d+|\S.*\(0x[0-9a-f]+.*\)$|created by |` +
+		`Traceback \(most recent call last\)|\s+File ".+", line \d+|` +
+		`\s+at .+|Caused by: |\s+\.\.\. \d+ more|Exception in thread |[\w.$]*(Exception|Error)(: |$))`,
+)
+
+func init() {
+	viper.SetDefault("LOG_CAPTURE_MAX_BYTES", int64(256*1024))
+	viper.SetDefault("LOG_CAPTURE_STACK_TRACE_TAIL_LINES", int64(1000))
+	viper.AutomaticEnv()
+
+	maxLogBytes = viper.GetInt64("LOG_CAPTURE_MAX_BYTES")
+	stackTraceTailLines = viper.GetInt64("LOG_CAPTURE_STACK_TRACE_TAIL_LINES")
+}
+
+// captureLogs returns the logs of a container: the logs of its previous run
+// when it was restarted, and the logs of its current run when it has started.
+// Both start at the time their run started, and are limited to
+// LOG_CAPTURE_MAX_BYTES in total, of which the previous run gets the first
+// share as it is usually the one which crashed.
+func (r *PodReconciler) captureLogs(ctx context.Context, pod *corev1.Pod, containerName string) (string, string, error) {
+	status := getContainerStatus(pod, containerName)
+	if status == nil {
+		return "", "", nil
+	}
+
+	var previousLogs, currentLogs string
+	var err error
+
+	remaining := maxLogBytes
+
+	if terminated := status.LastTerminationState.Terminated; terminated != nil {
+		previousLogs, err = r.fetchLogs(ctx, pod, containerName, true, terminated.StartedAt, remaining)
+		if err != nil {
+			return "", "", err
+		}
+
+		remaining -= int64(len(previousLogs))
+	}
+
+	var startedAt metav1.Time
+
+	if status.State.Running != nil {
+		startedAt = status.State.Running.StartedAt
+	} else if status.State.Terminated != nil {
+		startedAt = status.State.Terminated.StartedAt
+	}
+
+	// waiting containers have no logs of their current run
+	if status.State.Waiting == nil && remaining > 0 {
+		currentLogs, err = r.fetchLogs(ctx, pod, containerName, false, startedAt, remaining)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	return previousLogs, currentLogs, nil
+}
+
+// joinLogs returns the logs of the previous run of a container followed by
+// the logs of its current run, separated by headers when there are both
+func joinLogs(previousLogs, currentLogs string) string {
+	if previousLogs != "" && currentLogs != "" {
+		return previousLogsHeader + withTrailingNewline(previousLogs) + currentLogsHeader + currentLogs
+	}
+
+	return previousLogs + currentLogs
+}
+
+// fetchLogs reads the last lines of one run of a container, reading more lines
+// when the last ones are mostly a stack trace. The logs of previous runs which
+// are no longer kept are empty.
+func (r *PodReconciler) fetchLogs(
+	ctx context.Context,
+	pod *corev1.Pod,
+	containerName string,
+	previous bool,
+	since metav1.Time,
+	limitBytes int64,
+) (string, error) {
+	logs, err := r.streamLogs(ctx, pod, containerName, previous, since, maxTailLines, limitBytes)
+	if err != nil {
+		if previous && apierrors.IsBadRequest(err) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	if stackTraceTailLines > maxTailLines && countLines(logs) >= maxTailLines && isMostlyStackTrace(logs) {
+		r.logger.Info("widening log capture for stack trace", "container", containerName, "previous", previous)
+
+		return r.streamLogs(ctx, pod, containerName, previous, since, stackTraceTailLines, limitBytes)
+	}
+
+	return logs, nil
+}
+
+func (r *PodReconciler) streamLogs(
+	ctx context.Context,
+	pod *corev1.Pod,
+	containerName string,
+	previous bool,
+	since metav1.Time,
+	tailLines, limitBytes int64,
+) (string, error) {
+	ctx, span := tracing.StartSpan(ctx, "PodReconciler.streamLogs", trace.WithAttributes(
+		attribute.String("container", containerName),
+		attribute.Bool("previous", previous),
+		attribute.Int64("tail_lines", tailLines),
+	))
+
+	logOptions := &corev1.PodLogOptions{
+		Container:  containerName,
+		Previous:   previous,
+		TailLines:  &tailLines,
+		LimitBytes: &limitBytes,
+	}
+
+	if !since.IsZero() {
+		logOptions.SinceTime = &since
+	}
+
+	podLogs, err := r.KubeClient.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, logOptions).Stream(ctx)
+	if err != nil {
+		tracing.EndSpan(span, err)
+		return "", err
+	}
+
+	defer podLogs.Close()
+
+	// the API server enforces the limit, which is checked here as well so
+	// that the reconciler never holds more than the limit in memory
+	logs := new(bytes.Buffer)
+	_, err = io.Copy(logs, io.LimitReader(podLogs, limitBytes))
+
+	span.SetAttributes(attribute.Int("bytes", logs.Len()))
+	tracing.EndSpan(span, err)
+
+	if err != nil {
+		return "", fmt.Errorf("error reading logs of container %s. Error: %w", containerName, err)
+	}
+
+	return logs.String(), nil
+}
+
+// redactEvent redacts the messages of an event and of its containers, which
+// can hold the termination message or the last lines of the logs
+func (r *PodReconciler) redactEvent(event *models.PodEvent) redact.Counts {
+	var counts redact.Counts
+
+	event.Message, counts = r.redactor.Redact(event.Message)
+
+	for _, containerEvent := range event.ContainerEvents {
+		var containerCounts redact.Counts
+
+		containerEvent.Message, containerCounts = r.redactor.Redact(containerEvent.Message)
+		containerEvent.Redactions = containerCounts.Total()
+
+		counts.Add(containerCounts)
+	}
+
+	return counts
+}
+
+// setStackTrace sets the crash of the event to the one of the first container
+// with one. The reasons of the event and its containers are left as they are,
+// since they are indexed and used as metric labels, which the summary of the
+// crash is available through the stack trace for.
+func setStackTrace(event *models.PodEvent) {
+	containerNames := make([]string, 0, len(event.ContainerEvents))
+
+	for containerName := range event.ContainerEvents {
+		containerNames = append(containerNames, containerName)
+	}
+
+	sort.Strings(containerNames)
+
+	for _, containerName := range containerNames {
+		if stackTrace := event.ContainerEvents[containerName].StackTrace; stackTrace != nil {
+			event.StackTrace = stackTrace
+			return
+		}
+	}
+}
+
+func getContainerStatus(pod *corev1.Pod, containerName string) *corev1.ContainerStatus {
+	for i := range pod.Status.ContainerStatuses {
+		if pod.Status.ContainerStatuses[i].Name == containerName {
+			return &pod.Status.ContainerStatuses[i]
+		}
+	}
+
+	return nil
+}
+
+// isMostlyStackTrace returns true if at least half of the non-empty lines of
+// the logs belong to a stack trace
+func isMostlyStackTrace(logs string) bool {
+	var lines, stackTraceLines int
+
+	for _, line := range strings.Split(logs, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		lines++
+
+		if stackTraceLineRegexp.MatchString(line) {
+			stackTraceLines++
+		}
+	}
+
+	return lines > 0 && stackTraceLines*2 >= lines
+}
+
+func countLines(logs string) int64 {
+	return int64(strings.Count(strings.TrimSuffix(logs, "\n"), "\n") + 1)
+}
+
+func withTrailingNewline(s string) string {
+	if strings.HasSuffix(s, "\n") {
+		return s
+	}
+
+	return s + "\n"
+}
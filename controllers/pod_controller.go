@@ -1,19 +1,23 @@
 package controllers
 
 import (
-	"bytes"
 	"context"
 	"fmt"
-	"io"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/porter-dev/porter-agent/pkg/metrics"
 	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/redact"
 	"github.com/porter-dev/porter-agent/pkg/redis"
+	"github.com/porter-dev/porter-agent/pkg/stacktrace"
+	"github.com/porter-dev/porter-agent/pkg/tracing"
 	"github.com/porter-dev/porter-agent/pkg/utils"
 	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -29,20 +33,14 @@ import (
 )
 
 var (
-	redisHost        string
-	redisPort        string
 	maxTailLines     int64
 	containerSignals map[int32]string
 )
 
 func init() {
-	viper.SetDefault("REDIS_HOST", "porter-redis-master")
-	viper.SetDefault("REDIS_PORT", "6379")
 	viper.SetDefault("MAX_TAIL_LINES", int64(100))
 	viper.AutomaticEnv()
 
-	redisHost = viper.GetString("REDIS_HOST")
-	redisPort = viper.GetString("REDIS_PORT")
 	maxTailLines = viper.GetInt64("MAX_TAIL_LINES")
 
 	// refer: https://www.man7.org/linux/man-pages/man7/signal.7.html
@@ -64,6 +62,7 @@ type PodReconciler struct {
 	Scheme *runtime.Scheme
 
 	redisClient *redis.Client
+	redactor    *redact.Redactor
 	KubeClient  *kubernetes.Clientset
 	PodFilter   utils.PodFilter
 
@@ -84,12 +83,30 @@ type PodReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.8.3/pkg/reconcile
 func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	r.logger = log.FromContext(ctx)
+	ctx, span := tracing.StartSpan(ctx, "PodReconciler.Reconcile", trace.WithAttributes(
+		attribute.String("namespace", req.Namespace),
+		attribute.String("pod", req.Name),
+	))
+
+	outcome := metrics.ReconcileIgnored
 
-	if r.redisClient == nil {
-		r.redisClient = redis.NewClient(redisHost, redisPort, "", "", redis.PODSTORE, maxTailLines)
+	res, err := r.reconcile(ctx, req, &outcome)
+	if err != nil {
+		outcome = metrics.ReconcileError
 	}
 
+	metrics.ReconcileOutcomes.WithLabelValues(outcome).Inc()
+
+	span.SetAttributes(attribute.String("outcome", outcome))
+	tracing.EndSpan(span, err)
+
+	return res, err
+}
+
+// reconcile sets the outcome when the pod is not ignored
+func (r *PodReconciler) reconcile(ctx context.Context, req ctrl.Request, outcome *string) (ctrl.Result, error) {
+	r.logger = log.FromContext(ctx)
+
 	instance := &corev1.Pod{}
 	err := r.Get(ctx, req.NamespacedName, instance)
 	if err != nil {
@@ -147,6 +164,7 @@ func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 				incidentID, err := r.redisClient.GetActiveIncident(ctx, porterReleaseName, instance.Namespace)
 				if err == nil {
 					r.redisClient.SetPodResolved(ctx, instance.Name, incidentID) // FIXME: make use of the error
+					*outcome = metrics.ReconcileResolved
 				}
 
 				// remove the finalizer
@@ -199,7 +217,7 @@ func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 
 	r.logger.Info("creating container events")
 
-	filteredMsgRes := r.PodFilter.Filter(instance, ownerKind == "Job")
+	filteredMsgRes := r.PodFilter.Filter(ctx, instance, ownerKind == "Job")
 
 	if filteredMsgRes == nil {
 		incidentID, err := r.redisClient.GetActiveIncident(ctx, porterReleaseName, instance.Namespace)
@@ -207,6 +225,7 @@ func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 			if ownerKind == "Job" {
 				// since a job has one running pod at a time and here we know that it has run successfully
 				r.redisClient.SetJobIncidentResolved(ctx, incidentID) // FIXME: make use of the error
+				*outcome = metrics.ReconcileResolved
 			} else {
 				allRunning := true
 
@@ -221,6 +240,7 @@ func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 					startedAt, valid := r.getLatestRunningStartedAt(instance)
 					if valid && time.Now().After(startedAt.Add(10*time.Minute)) {
 						r.redisClient.SetPodResolved(ctx, instance.Name, incidentID) // FIXME: make use of the error
+						*outcome = metrics.ReconcileResolved
 						return ctrl.Result{}, nil
 					}
 				}
@@ -234,6 +254,7 @@ func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 
 				if ignore {
 					r.redisClient.SetJobIncidentResolved(ctx, incidentID)
+					*outcome = metrics.ReconcileResolved
 				}
 			}
 		}
@@ -245,9 +266,12 @@ func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 
 	for _, filteredContainerRes := range filteredMsgRes.ContainerStatuses {
 		containerEvents[filteredContainerRes.ContainerName] = &models.ContainerEvent{
-			Name:    filteredContainerRes.ContainerName,
-			Reason:  filteredContainerRes.Summary,
-			Message: filteredContainerRes.Details,
+			Name:         filteredContainerRes.ContainerName,
+			Image:        r.getContainerImage(instance, filteredContainerRes.ContainerName),
+			Reason:       filteredContainerRes.Summary,
+			Message:      filteredContainerRes.Details,
+			FilterReason: filteredContainerRes.Reason,
+			Severity:     filteredContainerRes.Severity,
 		}
 	}
 
@@ -294,6 +318,8 @@ func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 	event := &models.PodEvent{
 		ChartName:       chartName,
 		PodName:         instance.Name,
+		NodeName:        instance.Spec.NodeName,
+		Labels:          instance.Labels,
 		Namespace:       instance.Namespace,
 		OwnerName:       porterReleaseName,
 		OwnerType:       ownerKind,
@@ -302,8 +328,21 @@ func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 		ContainerEvents: containerEvents,
 		Reason:          filteredMsgRes.PodSummary,
 		Message:         filteredMsgRes.PodDetails,
+		FilterReason:    filteredMsgRes.PodReason,
+		Severity:        filteredMsgRes.PodSeverity,
 	}
 
+	revision, rs := r.getRevision(ctx, instance, chartName)
+	event.Revision = revision
+
+	if newIncident {
+		event.Rollout = r.getRollout(ctx, rs)
+	}
+
+	// messages can hold the last lines of the logs, so they are redacted
+	// before being compared with the ones of the latest event
+	redactions := r.redactEvent(event)
+
 	r.logger.Info("checking for incident existence")
 	if exists, err := r.redisClient.IncidentExists(ctx, incidentID); err != nil {
 		return ctrl.Result{Requeue: true}, err
@@ -320,6 +359,7 @@ func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 			if event.Reason == "Error while pulling image from container registry" &&
 				latestEvent.Reason == event.Reason {
 				// FIXME: a better way to check for this succession of events, perhaps?
+				*outcome = metrics.ReconcileDuplicate
 				return ctrl.Result{}, nil
 			}
 
@@ -356,6 +396,7 @@ func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 
 				if !newEvent {
 					r.logger.Info("duplicate event")
+					*outcome = metrics.ReconcileDuplicate
 					return ctrl.Result{}, nil
 				}
 			}
@@ -364,61 +405,71 @@ func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 
 	r.logger.Info("fetching logs for containers")
 	for containerName, containerEvent := range event.ContainerEvents {
-		logOptions := &corev1.PodLogOptions{
-			TailLines: &maxTailLines,
-			Previous:  r.hasLastTerminatedState(instance, containerName),
-			Container: containerName,
+		// logs are best-effort, so the event is recorded with the logs of the
+		// containers which could be read
+		previousLogs, currentLogs, err := r.captureLogs(ctx, instance, containerName)
+		if err != nil {
+			r.logger.Error(err, "unable to capture logs", "container", containerName)
+			continue
 		}
 
-		req := r.KubeClient.
-			CoreV1().
-			Pods(instance.Namespace).
-			GetLogs(instance.Name, logOptions)
+		strLogs := joinLogs(previousLogs, currentLogs)
 
-		podLogs, err := req.Stream(ctx)
+		if strLogs == "" || strings.Contains(strLogs, "unable to retrieve container logs") {
+			// let us not add this unhelpful log message
+			continue
+		}
+
+		previousLogs, previousRedactions := r.redactor.Redact(previousLogs)
+		currentLogs, currentRedactions := r.redactor.Redact(currentLogs)
+		strLogs = joinLogs(previousLogs, currentLogs)
+
+		for _, logRedactions := range []redact.Counts{previousRedactions, currentRedactions} {
+			containerEvent.Redactions += logRedactions.Total()
+			redactions.Add(logRedactions)
+		}
+
+		containerEvent.StackTrace = stacktrace.Extract(strLogs)
+
+		r.logger.Info("checking for duplicate logs", "incidentID", incidentID, "container", containerName)
+
+		// the logs of a container which did not change since they were last
+		// captured for the incident are referenced instead of stored again
+		logID, err := r.redisClient.GetDuplicateContainerLogs(ctx, incidentID, containerName, previousLogs, currentLogs)
 		if err != nil {
-			r.logger.Error(err, "error streaming logs")
+			r.logger.Error(err, "unable to check for duplicate logs")
 			return ctrl.Result{Requeue: true}, err
 		}
-		defer podLogs.Close()
 
-		logs := new(bytes.Buffer)
-		_, err = io.Copy(logs, podLogs)
+		if logID != "" {
+			r.logger.Info("found duplicate logs", "incidentID", incidentID, "container", containerName)
+			containerEvent.LogID = logID
+			continue
+		}
+
+		logID, err = r.redisClient.AddLogs(ctx, incidentID, containerName, strLogs)
 		if err != nil {
-			r.logger.Error(err, "unable to read logs")
+			r.logger.Error(err, "error adding new logs")
 			return ctrl.Result{Requeue: true}, err
 		}
 
-		strLogs := logs.String()
-
-		if strLogs != "" { // logs can be empty
-			if strings.Contains(strLogs, "unable to retrieve container logs") {
-				// let us not add this unhelpful log message and completely ignore this event
-				return ctrl.Result{}, nil
-			}
+		if err := r.redisClient.SetContainerLogs(ctx, incidentID, containerName, previousLogs, currentLogs,
+			logID); err != nil {
+			r.logger.Error(err, "error recording logs of container", "container", containerName)
+		}
 
-			r.logger.Info("checking for duplicate logs", "incidentID", incidentID)
+		containerEvent.LogID = logID
 
-			duplicateLogs, err := r.redisClient.DuplicateLogs(ctx, incidentID, strLogs)
-			if err != nil {
-				r.logger.Error(err, "unable to check for duplicate logs")
-				return ctrl.Result{Requeue: true}, err
-			}
+		metrics.LogsCaptured.Inc()
+		metrics.LogsCapturedBytes.Add(float64(len(strLogs)))
+	}
 
-			if duplicateLogs {
-				r.logger.Info("found duplicate logs", "incidentID", incidentID)
-				return ctrl.Result{}, nil
-			}
+	setStackTrace(event)
 
-			logID, err := r.redisClient.AddLogs(ctx, incidentID, strLogs)
-			if err != nil {
-				r.logger.Error(err, "error adding new logs")
-				return ctrl.Result{Requeue: true}, err
-			}
+	snapshot, snapshotRedactions := r.snapshotPod(ctx, instance)
+	redactions.Add(snapshotRedactions)
 
-			containerEvent.LogID = logID
-		}
-	}
+	event.Redactions = redactions.Total()
 
 	r.logger.Info("adding event to incident")
 	err = r.redisClient.AddEventToIncident(ctx, incidentID, event, newIncident)
@@ -430,6 +481,25 @@ func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 		return ctrl.Result{Requeue: true}, err
 	}
 
+	metrics.EventsRecorded.WithLabelValues(string(event.Severity)).Inc()
+
+	// the event is recorded even if its snapshot cannot be stored
+	snapshot.EventID = event.EventID
+
+	if err := r.redisClient.AddSnapshot(ctx, incidentID, snapshot); err != nil {
+		r.logger.Error(err, "error adding snapshot of pod", "eventID", event.EventID)
+	}
+
+	for detector, count := range redactions {
+		metrics.LogRedactions.WithLabelValues(detector).Add(float64(count))
+	}
+
+	if newIncident {
+		*outcome = metrics.ReconcileNewIncident
+	} else {
+		*outcome = metrics.ReconcileEventAdded
+	}
+
 	return ctrl.Result{}, nil
 }
 
@@ -447,19 +517,14 @@ func (r *PodReconciler) getLatestRunningStartedAt(pod *corev1.Pod) (time.Time, b
 	return tm, count > 0
 }
 
-func (r *PodReconciler) hasLastTerminatedState(pod *corev1.Pod, containerName string) bool {
-	for i := len(pod.Status.ContainerStatuses) - 1; i >= 0; i-- {
-		if containerName == pod.Status.ContainerStatuses[i].Name {
-			if pod.Status.ContainerStatuses[i].LastTerminationState.Waiting != nil ||
-				pod.Status.ContainerStatuses[i].LastTerminationState.Terminated != nil {
-				return true
-			}
-
-			return false
+func (r *PodReconciler) getContainerImage(pod *corev1.Pod, containerName string) string {
+	for _, container := range pod.Spec.Containers {
+		if container.Name == containerName {
+			return container.Image
 		}
 	}
 
-	return false
+	return ""
 }
 
 func (r *PodReconciler) fetchReplicaSetOwner(ctx context.Context, req ctrl.Request) (*metav1.OwnerReference, error) {
@@ -585,6 +650,20 @@ func (r *PodReconciler) canIgnoreMultipodDeployment(ctx context.Context, req rec
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *PodReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	redisClient, err := redis.NewClientFromEnv(maxTailLines)
+	if err != nil {
+		return err
+	}
+
+	r.redisClient = redisClient
+
+	redactor, err := redact.NewRedactorFromEnv()
+	if err != nil {
+		return err
+	}
+
+	r.redactor = redactor
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&corev1.Pod{}).
 		Complete(r)
@@ -0,0 +1,248 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/utils"
+	"github.com/spf13/viper"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	revisionAnnotation = utils.RevisionAnnotation
+	chartLabel         = "helm.sh/chart"
+)
+
+// incidents starting within this long of the rollout of the revision of their
+// pod are correlated with the rollout
+var rolloutWindow time.Duration
+
+func init() {
+	viper.SetDefault("ROLLOUT_CORRELATION_WINDOW", "15m")
+	viper.AutomaticEnv()
+
+	rolloutWindow = viper.GetDuration("ROLLOUT_CORRELATION_WINDOW")
+}
+
+//+kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch
+
+// getRevision returns the revision of the workload a pod is running, which is
+// only partly set if the ReplicaSet of the pod cannot be read
+func (r *PodReconciler) getRevision(ctx context.Context, pod *corev1.Pod, chartName string) (*models.Revision, *appsv1.ReplicaSet) {
+	revision := &models.Revision{
+		Images:          make(map[string]string),
+		PodTemplateHash: pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey],
+		Chart:           pod.Labels[chartLabel],
+	}
+
+	if revision.Chart == "" {
+		revision.Chart = chartName
+	}
+
+	for _, container := range pod.Spec.Containers {
+		revision.Images[container.Name] = container.Image
+	}
+
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil || owner.Kind != "ReplicaSet" {
+		return revision, nil
+	}
+
+	rs := &appsv1.ReplicaSet{}
+
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: pod.Namespace, Name: owner.Name}, rs); err != nil {
+		r.logger.Error(err, "cannot fetch replicaset of pod for revision", "replicaset", owner.Name)
+		return revision, nil
+	}
+
+	revision.ReplicaSet = rs.Name
+	revision.Revision = rs.Annotations[revisionAnnotation]
+	revision.RolledOutAt = rs.CreationTimestamp.Unix()
+
+	return revision, rs
+}
+
+// getRollout returns the rollout of the ReplicaSet of a pod if it happened
+// within ROLLOUT_CORRELATION_WINDOW, along with the changes from the previous
+// revision of its deployment. Rollbacks reuse the ReplicaSet of the revision
+// they roll back to, so they are not correlated.
+func (r *PodReconciler) getRollout(ctx context.Context, rs *appsv1.ReplicaSet) *models.Rollout {
+	if rs == nil || rolloutWindow <= 0 || time.Since(rs.CreationTimestamp.Time) > rolloutWindow {
+		return nil
+	}
+
+	deployment := metav1.GetControllerOf(rs)
+	if deployment == nil || deployment.Kind != "Deployment" {
+		return nil
+	}
+
+	rollout := &models.Rollout{
+		Revision:    rs.Annotations[revisionAnnotation],
+		RolledOutAt: rs.CreationTimestamp.Unix(),
+	}
+
+	previous, err := utils.GetPreviousReplicaSet(ctx, r.Client, rs, deployment.UID)
+	if err != nil {
+		r.logger.Error(err, "cannot fetch previous replicaset for rollout", "replicaset", rs.Name)
+	}
+
+	if previous != nil {
+		rollout.PreviousRevision = previous.Annotations[revisionAnnotation]
+		rollout.Changes = diffPodTemplates(&previous.Spec.Template, &rs.Spec.Template)
+	}
+
+	return rollout
+}
+
+// diffPodTemplates returns the changes of the chart, containers, images, env
+// var names and resources between two pod templates
+func diffPodTemplates(previous, current *corev1.PodTemplateSpec) []*models.RevisionChange {
+	changes := make([]*models.RevisionChange, 0)
+
+	if previousChart, currentChart := previous.Labels[chartLabel], current.Labels[chartLabel]; previousChart != currentChart {
+		changes = append(changes, &models.RevisionChange{
+			Field:    "chart",
+			Previous: previousChart,
+			Current:  currentChart,
+		})
+	}
+
+	previousContainers := make(map[string]*corev1.Container)
+
+	for i := range previous.Spec.Containers {
+		previousContainers[previous.Spec.Containers[i].Name] = &previous.Spec.Containers[i]
+	}
+
+	for i := range current.Spec.Containers {
+		container := &current.Spec.Containers[i]
+
+		previousContainer, ok := previousContainers[container.Name]
+		if !ok {
+			changes = append(changes, &models.RevisionChange{
+				Container: container.Name,
+				Field:     "container",
+				Current:   container.Image,
+			})
+
+			continue
+		}
+
+		delete(previousContainers, container.Name)
+
+		changes = append(changes, diffContainers(previousContainer, container)...)
+	}
+
+	for i := range previous.Spec.Containers {
+		if container, ok := previousContainers[previous.Spec.Containers[i].Name]; ok {
+			changes = append(changes, &models.RevisionChange{
+				Container: container.Name,
+				Field:     "container",
+				Previous:  container.Image,
+			})
+		}
+	}
+
+	return changes
+}
+
+func diffContainers(previous, current *corev1.Container) []*models.RevisionChange {
+	var changes []*models.RevisionChange
+
+	if previous.Image != current.Image {
+		changes = append(changes, &models.RevisionChange{
+			Container: current.Name,
+			Field:     "image",
+			Previous:  previous.Image,
+			Current:   current.Image,
+		})
+	}
+
+	previousEnv := make(map[string]bool)
+
+	for _, env := range previous.Env {
+		previousEnv[env.Name] = true
+	}
+
+	for _, env := range current.Env {
+		if previousEnv[env.Name] {
+			delete(previousEnv, env.Name)
+			continue
+		}
+
+		changes = append(changes, &models.RevisionChange{
+			Container: current.Name,
+			Field:     "env",
+			Current:   env.Name,
+		})
+	}
+
+	for _, env := range previous.Env {
+		if previousEnv[env.Name] {
+			changes = append(changes, &models.RevisionChange{
+				Container: current.Name,
+				Field:     "env",
+				Previous:  env.Name,
+			})
+		}
+	}
+
+	changes = append(changes, diffResources(current.Name, "requests", previous.Resources.Requests, current.Resources.Requests)...)
+	changes = append(changes, diffResources(current.Name, "limits", previous.Resources.Limits, current.Resources.Limits)...)
+
+	return changes
+}
+
+func diffResources(containerName, field string, previous, current corev1.ResourceList) []*models.RevisionChange {
+	names := make(map[corev1.ResourceName]bool)
+
+	for name := range previous {
+		names[name] = true
+	}
+
+	for name := range current {
+		names[name] = true
+	}
+
+	sortedNames := make([]string, 0, len(names))
+
+	for name := range names {
+		sortedNames = append(sortedNames, string(name))
+	}
+
+	sort.Strings(sortedNames)
+
+	var changes []*models.RevisionChange
+
+	for _, name := range sortedNames {
+		previousQuantity, hadPrevious := previous[corev1.ResourceName(name)]
+		currentQuantity, hasCurrent := current[corev1.ResourceName(name)]
+
+		if hadPrevious && hasCurrent && previousQuantity.Cmp(currentQuantity) == 0 {
+			continue
+		}
+
+		change := &models.RevisionChange{
+			Container: containerName,
+			Field:     field + "." + name,
+		}
+
+		if hadPrevious {
+			change.Previous = previousQuantity.String()
+		}
+
+		if hasCurrent {
+			change.Current = currentQuantity.String()
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes
+}
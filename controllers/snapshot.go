@@ -0,0 +1,293 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/redact"
+	"github.com/porter-dev/porter-agent/pkg/tracing"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+// number of the latest Kubernetes events of a pod kept in its snapshot
+const maxSnapshotEvents = 20
+
+//+kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+
+// snapshotPod returns the diagnostic snapshot of a pod, along the lines of
+// kubectl describe. The Kubernetes events of the pod and its node are
+// best-effort, so the snapshot is taken without them if they cannot be read.
+func (r *PodReconciler) snapshotPod(ctx context.Context, pod *corev1.Pod) (*models.PodSnapshot, redact.Counts) {
+	ctx, span := tracing.StartSpan(ctx, "PodReconciler.snapshotPod")
+	defer span.End()
+
+	counts := redact.Counts{}
+
+	redactText := func(text string) string {
+		redacted, textCounts := r.redactor.Redact(text)
+		counts.Add(textCounts)
+
+		return redacted
+	}
+
+	snapshot := &models.PodSnapshot{
+		CapturedAt: time.Now().Unix(),
+		PodName:    pod.Name,
+		Namespace:  pod.Namespace,
+		Labels:     pod.Labels,
+		NodeName:   pod.Spec.NodeName,
+		Phase:      string(pod.Status.Phase),
+		QOSClass:   string(pod.Status.QOSClass),
+		Reason:     pod.Status.Reason,
+		Message:    redactText(pod.Status.Message),
+		StartTime:  unixTime(pod.Status.StartTime),
+	}
+
+	for _, condition := range pod.Status.Conditions {
+		snapshot.Conditions = append(snapshot.Conditions, &models.Condition{
+			Type:               string(condition.Type),
+			Status:             string(condition.Status),
+			Reason:             condition.Reason,
+			Message:            redactText(condition.Message),
+			LastTransitionTime: unixTime(&condition.LastTransitionTime),
+		})
+	}
+
+	for _, container := range pod.Spec.InitContainers {
+		snapshot.InitContainers = append(snapshot.InitContainers,
+			snapshotContainer(container, pod.Status.InitContainerStatuses, redactText))
+	}
+
+	for _, container := range pod.Spec.Containers {
+		snapshot.Containers = append(snapshot.Containers,
+			snapshotContainer(container, pod.Status.ContainerStatuses, redactText))
+	}
+
+	events, err := r.getPodEvents(ctx, pod)
+	if err != nil {
+		r.logger.Error(err, "unable to get events for pod snapshot")
+	}
+
+	for _, event := range events {
+		event.Message = redactText(event.Message)
+	}
+
+	snapshot.Events = events
+
+	if pod.Spec.NodeName != "" {
+		node, err := r.KubeClient.CoreV1().Nodes().Get(ctx, pod.Spec.NodeName, metav1.GetOptions{})
+		if err != nil {
+			r.logger.Error(err, "unable to get node for pod snapshot", "node", pod.Spec.NodeName)
+		} else {
+			snapshot.Node = snapshotNode(node, redactText)
+		}
+	}
+
+	return snapshot, counts
+}
+
+// getPodEvents returns the latest Kubernetes events of a pod, oldest first
+func (r *PodReconciler) getPodEvents(ctx context.Context, pod *corev1.Pod) ([]*models.KubernetesEvent, error) {
+	selector := fields.AndSelectors(
+		fields.OneTermEqualSelector("involvedObject.kind", "Pod"),
+		fields.OneTermEqualSelector("involvedObject.name", pod.Name),
+		fields.OneTermEqualSelector("involvedObject.uid", string(pod.UID)),
+	)
+
+	eventList, err := r.KubeClient.CoreV1().Events(pod.Namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: selector.String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing events of pod %s. Error: %w", pod.Name, err)
+	}
+
+	events := make([]*models.KubernetesEvent, 0, len(eventList.Items))
+
+	for _, item := range eventList.Items {
+		event := &models.KubernetesEvent{
+			Type:           item.Type,
+			Reason:         item.Reason,
+			Message:        item.Message,
+			Source:         item.Source.Component,
+			Count:          item.Count,
+			FirstTimestamp: unixTime(&item.FirstTimestamp),
+			LastTimestamp:  unixTime(&item.LastTimestamp),
+		}
+
+		// events recorded through the events.k8s.io API only have an event
+		// time
+		if item.LastTimestamp.IsZero() && !item.EventTime.IsZero() {
+			event.FirstTimestamp = item.EventTime.Unix()
+			event.LastTimestamp = item.EventTime.Unix()
+		}
+
+		if event.Source == "" {
+			event.Source = item.ReportingController
+		}
+
+		if event.Count == 0 {
+			event.Count = 1
+		}
+
+		events = append(events, event)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].LastTimestamp < events[j].LastTimestamp
+	})
+
+	if len(events) > maxSnapshotEvents {
+		events = events[len(events)-maxSnapshotEvents:]
+	}
+
+	return events, nil
+}
+
+func snapshotContainer(
+	container corev1.Container,
+	statuses []corev1.ContainerStatus,
+	redactText func(string) string,
+) *models.ContainerSnapshot {
+	res := &models.ContainerSnapshot{
+		Name:           container.Name,
+		Image:          container.Image,
+		Requests:       resourceListToMap(container.Resources.Requests),
+		Limits:         resourceListToMap(container.Resources.Limits),
+		LivenessProbe:  redactText(describeProbe(container.LivenessProbe)),
+		ReadinessProbe: redactText(describeProbe(container.ReadinessProbe)),
+		StartupProbe:   redactText(describeProbe(container.StartupProbe)),
+	}
+
+	for _, port := range container.Ports {
+		res.Ports = append(res.Ports, fmt.Sprintf("%d/%s", port.ContainerPort, port.Protocol))
+	}
+
+	for _, env := range container.Env {
+		res.Env = append(res.Env, env.Name)
+	}
+
+	for _, status := range statuses {
+		if status.Name != container.Name {
+			continue
+		}
+
+		res.ImageID = status.ImageID
+		res.Ready = status.Ready
+		res.RestartCount = status.RestartCount
+		res.State = snapshotContainerState(status.State, redactText)
+		res.LastState = snapshotContainerState(status.LastTerminationState, redactText)
+	}
+
+	return res
+}
+
+func snapshotContainerState(state corev1.ContainerState, redactText func(string) string) *models.ContainerState {
+	switch {
+	case state.Waiting != nil:
+		return &models.ContainerState{
+			State:   "waiting",
+			Reason:  state.Waiting.Reason,
+			Message: redactText(state.Waiting.Message),
+		}
+	case state.Running != nil:
+		return &models.ContainerState{
+			State:     "running",
+			StartedAt: unixTime(&state.Running.StartedAt),
+		}
+	case state.Terminated != nil:
+		return &models.ContainerState{
+			State:      "terminated",
+			Reason:     state.Terminated.Reason,
+			Message:    redactText(state.Terminated.Message),
+			ExitCode:   state.Terminated.ExitCode,
+			Signal:     state.Terminated.Signal,
+			StartedAt:  unixTime(&state.Terminated.StartedAt),
+			FinishedAt: unixTime(&state.Terminated.FinishedAt),
+		}
+	}
+
+	return nil
+}
+
+func snapshotNode(node *corev1.Node, redactText func(string) string) *models.NodeSnapshot {
+	res := &models.NodeSnapshot{
+		Name:          node.Name,
+		Unschedulable: node.Spec.Unschedulable,
+		Capacity:      resourceListToMap(node.Status.Capacity),
+		Allocatable:   resourceListToMap(node.Status.Allocatable),
+	}
+
+	for _, taint := range node.Spec.Taints {
+		res.Taints = append(res.Taints, taint.ToString())
+	}
+
+	for _, condition := range node.Status.Conditions {
+		res.Conditions = append(res.Conditions, &models.Condition{
+			Type:               string(condition.Type),
+			Status:             string(condition.Status),
+			Reason:             condition.Reason,
+			Message:            redactText(condition.Message),
+			LastTransitionTime: unixTime(&condition.LastTransitionTime),
+		})
+	}
+
+	return res
+}
+
+// describeProbe describes a probe like kubectl describe does
+func describeProbe(probe *corev1.Probe) string {
+	if probe == nil {
+		return ""
+	}
+
+	var handler string
+
+	switch {
+	case probe.Exec != nil:
+		handler = fmt.Sprintf("exec %v", probe.Exec.Command)
+	case probe.HTTPGet != nil:
+		scheme := strings.ToLower(string(probe.HTTPGet.Scheme))
+		if scheme == "" {
+			scheme = "http"
+		}
+
+		handler = fmt.Sprintf("http-get %s://%s:%s%s", scheme, probe.HTTPGet.Host, probe.HTTPGet.Port.String(), probe.HTTPGet.Path)
+	case probe.TCPSocket != nil:
+		handler = fmt.Sprintf("tcp-socket %s:%s", probe.TCPSocket.Host, probe.TCPSocket.Port.String())
+	default:
+		handler = "unknown"
+	}
+
+	return fmt.Sprintf("%s delay=%ds timeout=%ds period=%ds #success=%d #failure=%d", handler,
+		probe.InitialDelaySeconds, probe.TimeoutSeconds, probe.PeriodSeconds, probe.SuccessThreshold, probe.FailureThreshold)
+}
+
+func resourceListToMap(resources corev1.ResourceList) map[string]string {
+	if len(resources) == 0 {
+		return nil
+	}
+
+	res := make(map[string]string, len(resources))
+
+	for name, quantity := range resources {
+		res[string(name)] = quantity.String()
+	}
+
+	return res
+}
+
+// unixTime returns the unix timestamp of t, or 0 if it is not set
+func unixTime(t *metav1.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.Unix()
+}
@@ -3,15 +3,26 @@ module github.com/porter-dev/porter-agent
 go 1.16
 
 require (
+	github.com/alicebob/miniredis/v2 v2.23.0
+	github.com/aws/aws-sdk-go v1.44.0
 	github.com/gin-gonic/gin v1.7.4
 	github.com/go-logr/logr v0.3.0
 	github.com/go-redis/redis/v8 v8.11.1
-	github.com/google/uuid v1.3.0 // indirect
+	github.com/google/uuid v1.3.0
+	github.com/gorilla/websocket v1.4.2
 	github.com/onsi/ginkgo v1.15.0
 	github.com/onsi/gomega v1.10.5
+	github.com/prometheus/client_golang v1.7.1
+	github.com/robfig/cron/v3 v3.0.1
+	github.com/spf13/cobra v1.1.1
 	github.com/spf13/viper v1.7.0
+	go.opentelemetry.io/otel v1.2.0
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp v1.2.0
+	go.opentelemetry.io/otel/sdk v1.2.0
+	go.opentelemetry.io/otel/trace v1.2.0
 	k8s.io/api v0.20.2
 	k8s.io/apimachinery v0.20.2
 	k8s.io/client-go v0.20.2
 	sigs.k8s.io/controller-runtime v0.8.3
+	sigs.k8s.io/yaml v1.2.0
 )
@@ -12,8 +12,6 @@ import (
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
-	corev1 "k8s.io/api/core/v1"
-	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -22,10 +20,19 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	"github.com/gin-gonic/gin"
+	agentv1alpha1 "github.com/porter-dev/porter-agent/api/v1alpha1"
 	"github.com/porter-dev/porter-agent/controllers"
+	"github.com/porter-dev/porter-agent/pkg/archive"
 	"github.com/porter-dev/porter-agent/pkg/consumer"
+	"github.com/porter-dev/porter-agent/pkg/metrics"
+	"github.com/porter-dev/porter-agent/pkg/redis"
+	"github.com/porter-dev/porter-agent/pkg/remediation"
+	"github.com/porter-dev/porter-agent/pkg/server/middleware"
 	"github.com/porter-dev/porter-agent/pkg/server/routes"
+	"github.com/porter-dev/porter-agent/pkg/silence"
+	"github.com/porter-dev/porter-agent/pkg/tracing"
 	"github.com/porter-dev/porter-agent/pkg/utils"
+	"github.com/porter-dev/porter-agent/pkg/writeback"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -39,6 +46,7 @@ var (
 
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(agentv1alpha1.AddToScheme(scheme))
 
 	//+kubebuilder:scaffold:scheme
 }
@@ -60,6 +68,18 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	shutdownTracing, err := tracing.Init(context.Background())
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			setupLog.Error(err, "error flushing traces")
+		}
+	}()
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
@@ -73,33 +93,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	// first check if the redis server is running and wait for it if needed
 	kubeClient := kubernetes.NewForConfigOrDie(mgr.GetConfig())
-	for {
-		pods, err := kubeClient.CoreV1().Pods("porter-agent-system").List(
-			context.Background(), v1.ListOptions{
-				LabelSelector: "app.kubernetes.io/name=redis",
-			},
-		)
-
-		if err == nil && len(pods.Items) > 0 {
-			running := false
-
-			for _, pod := range pods.Items {
-				if pod.Status.Phase == corev1.PodRunning {
-					running = true
-					break
-				}
-			}
-
-			if running {
-				break
-			}
-		}
-
-		setupLog.Info("waiting for redis ...")
-		time.Sleep(time.Second * 2)
-	}
 
 	if err = (&controllers.PodReconciler{
 		Client:     mgr.GetClient(),
@@ -112,25 +106,130 @@ func main() {
 	}
 	//+kubebuilder:scaffold:builder
 
-	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
-		setupLog.Error(err, "unable to set up health check")
+	silenceStore := silence.NewStore(mgr.GetAPIReader(), mgr.GetClient())
+
+	archiveStore, err := archive.NewStoreFromEnv()
+	if err != nil {
+		setupLog.Error(err, "unable to set up archive")
+		os.Exit(1)
+	}
+
+	// the compactor is run by the manager, so that only the leader archives
+	// and prunes the log index
+	compactorRedisClient, err := redis.NewClientFromEnv(0)
+	if err != nil {
+		setupLog.Error(err, "unable to create redis client for compactor")
 		os.Exit(1)
 	}
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
-		setupLog.Error(err, "unable to set up ready check")
+
+	compactor, err := archive.NewCompactor(compactorRedisClient, archiveStore)
+	if err != nil {
+		setupLog.Error(err, "unable to set up compactor")
 		os.Exit(1)
 	}
 
+	if err := mgr.Add(compactor); err != nil {
+		setupLog.Error(err, "unable to set up compactor")
+		os.Exit(1)
+	}
+
+	// incidents are written back by the manager as well, so that only the
+	// leader emits events and annotates workloads
+	if writeback.Enabled() {
+		redisClient, err := redis.NewClientFromEnv(0)
+		if err != nil {
+			setupLog.Error(err, "unable to create redis client for write-back")
+			os.Exit(1)
+		}
+
+		writer := writeback.NewWriter(redisClient, mgr.GetClient(), mgr.GetEventRecorderFor("porter-agent"))
+
+		if err := mgr.Add(writer); err != nil {
+			setupLog.Error(err, "unable to set up write-back")
+			os.Exit(1)
+		}
+	}
+
+	// remediation is opt-in, and only the leader takes actions
+	if remediation.Enabled() {
+		policy, err := remediation.LoadPolicy()
+		if err != nil {
+			setupLog.Error(err, "unable to load remediation policy")
+			os.Exit(1)
+		}
+
+		redisClient, err := redis.NewClientFromEnv(0)
+		if err != nil {
+			setupLog.Error(err, "unable to create redis client for remediation")
+			os.Exit(1)
+		}
+
+		if err := mgr.Add(remediation.NewRemediator(redisClient, mgr.GetClient(), policy)); err != nil {
+			setupLog.Error(err, "unable to set up remediation")
+			os.Exit(1)
+		}
+	}
+
 	// create the event consumer
 	setupLog.Info("creating event consumer")
-	eventConsumer = consumer.NewEventConsumer(50, time.Millisecond, context.TODO())
+	eventConsumer, err = consumer.NewEventConsumer(50, time.Millisecond, context.TODO(), silenceStore)
+	if err != nil {
+		setupLog.Error(err, "unable to create event consumer")
+		os.Exit(1)
+	}
+
+	// the notification backlog is a metric rather than a ready check, so that
+	// a failing sink does not take the agent out of service
+	if err := metrics.RegisterStoreCollector(eventConsumer.PendingNotificationAge); err != nil {
+		setupLog.Error(err, "unable to register incident store metrics")
+		os.Exit(1)
+	}
+
+	// the agent is only restarted if it is stuck, while it is not ready as
+	// long as one of its dependencies is unavailable
+	healthChecks := map[string]healthz.Checker{
+		"ping":     healthz.Ping,
+		"consumer": eventConsumer.LivenessCheck,
+	}
+
+	readyChecks := map[string]healthz.Checker{
+		"redis":    eventConsumer.StoreCheck,
+		"consumer": eventConsumer.ReadinessCheck,
+		"http":     routes.ServerCheck,
+	}
+
+	for name, check := range healthChecks {
+		if err := mgr.AddHealthzCheck(name, check); err != nil {
+			setupLog.Error(err, "unable to set up health check", "check", name)
+			os.Exit(1)
+		}
+	}
+
+	for name, check := range readyChecks {
+		if err := mgr.AddReadyzCheck(name, check); err != nil {
+			setupLog.Error(err, "unable to set up ready check", "check", name)
+			os.Exit(1)
+		}
+	}
 
 	setupLog.Info("starting event consumer")
 	go eventConsumer.Start()
 
+	auth, err := middleware.NewAuth(kubeClient)
+	if err != nil {
+		setupLog.Error(err, "unable to set up HTTP authentication")
+		os.Exit(1)
+	}
+
 	setupLog.Info("starting HTTP server")
-	httpServer = routes.NewRouter()
-	go httpServer.Run(":10001")
+	httpServer = routes.NewRouter(silenceStore, archiveStore, auth)
+
+	go func() {
+		if err := routes.Run(httpServer, ":10001"); err != nil {
+			setupLog.Error(err, "problem running HTTP server")
+			os.Exit(1)
+		}
+	}()
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
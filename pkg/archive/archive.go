@@ -0,0 +1,352 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/redis"
+	"github.com/porter-dev/porter-agent/pkg/tracing"
+	"github.com/porter-dev/porter-agent/pkg/utils"
+	"github.com/spf13/viper"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var archiveLog = ctrl.Log.WithName("archive")
+
+// archive files are grouped by the day they were written, which is always
+// after the incidents and logs in them were created
+const (
+	incidentsPrefix = "incidents/"
+	logsPrefix      = "logs/"
+	dayLayout       = "2006/01/02"
+)
+
+var (
+	// URL of the archive, which is disabled when empty
+	archiveURL string
+
+	// how often the store is checked for expiring incidents and logs
+	interval time.Duration
+
+	// how long before they expire incidents and logs are archived
+	leadTime time.Duration
+)
+
+func init() {
+	viper.SetDefault("ARCHIVE_INTERVAL", "10m")
+	viper.SetDefault("ARCHIVE_LEAD_TIME", "1h")
+	viper.AutomaticEnv()
+
+	archiveURL = viper.GetString("ARCHIVE_URL")
+	interval = viper.GetDuration("ARCHIVE_INTERVAL")
+	leadTime = viper.GetDuration("ARCHIVE_LEAD_TIME")
+}
+
+// NewStoreFromEnv returns the store at ARCHIVE_URL, or nil if archiving is
+// disabled
+func NewStoreFromEnv() (Store, error) {
+	if archiveURL == "" {
+		return nil, nil
+	}
+
+	return NewStore(archiveURL)
+}
+
+// Compactor archives incidents and logs before they expire from the store, if
+// there is an archive, and removes the expired logs from the search index
+type Compactor struct {
+	redisClient *redis.Client
+	store       Store
+}
+
+func NewCompactor(redisClient *redis.Client, store Store) (*Compactor, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("ARCHIVE_INTERVAL must be positive")
+	} else if store != nil && leadTime <= interval {
+		// otherwise items could expire between two runs
+		return nil, fmt.Errorf("ARCHIVE_LEAD_TIME must be longer than ARCHIVE_INTERVAL")
+	}
+
+	return &Compactor{
+		redisClient: redisClient,
+		store:       store,
+	}, nil
+}
+
+// Start compacts the store every interval until the context is done. It is
+// run by the manager, so that only the leader compacts.
+func (c *Compactor) Start(ctx context.Context) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.Compact(ctx); err != nil {
+			archiveLog.Error(err, "error compacting incidents and logs")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Compact archives the incidents and logs which expire within the lead time,
+// then prunes the log index. Items are marked as archived once the archive
+// file is written, so a failed run is retried by the next one.
+func (c *Compactor) Compact(ctx context.Context) error {
+	ctx, span := tracing.StartSpan(ctx, "archive.Compact")
+	defer span.End()
+
+	if c.store != nil {
+		if err := c.archive(ctx); err != nil {
+			return err
+		}
+	}
+
+	return c.redisClient.PruneLogIndex(ctx)
+}
+
+func (c *Compactor) archive(ctx context.Context) error {
+	incidentIDs, err := c.redisClient.GetExpiringIncidents(ctx, leadTime)
+	if err != nil {
+		return err
+	}
+
+	var incidents []interface{}
+	var archivedIncidentIDs []string
+
+	for _, incidentID := range incidentIDs {
+		incident, err := c.redisClient.ExportIncident(ctx, incidentID)
+		if redis.IsExpiredError(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		incidents = append(incidents, incident)
+		archivedIncidentIDs = append(archivedIncidentIDs, incidentID)
+	}
+
+	if err := c.write(ctx, incidentsPrefix, incidents, archivedIncidentIDs); err != nil {
+		return err
+	}
+
+	logIDs, err := c.redisClient.GetExpiringLogs(ctx, leadTime)
+	if err != nil {
+		return err
+	}
+
+	var logs []interface{}
+	var archivedLogIDs []string
+
+	for _, logID := range logIDs {
+		archived, err := c.redisClient.ExportLogs(ctx, logID)
+		if redis.IsExpiredError(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		logs = append(logs, archived)
+		archivedLogIDs = append(archivedLogIDs, logID)
+	}
+
+	return c.write(ctx, logsPrefix, logs, archivedLogIDs)
+}
+
+func (c *Compactor) write(ctx context.Context, prefix string, records []interface{}, ids []string) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			return fmt.Errorf("error encoding archive record. Error: %w", err)
+		}
+	}
+
+	now := time.Now().UTC()
+	key := fmt.Sprintf("%s%s/%d.ndjson", prefix, now.Format(dayLayout), now.UnixNano())
+
+	if err := c.store.Put(ctx, key, buf.Bytes()); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := c.redisClient.MarkArchived(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	archiveLog.Info("archived expiring items", "file", key, "count", len(records))
+
+	return nil
+}
+
+// Restore imports the archived incidents and logs selected by the request
+// into the store. Items in namespaces which are not allowed are skipped.
+func Restore(
+	ctx context.Context,
+	store Store,
+	redisClient *redis.Client,
+	req *models.RestoreArchiveRequest,
+	allowed func(namespace string) (bool, error),
+) (*models.RestoreArchiveResponse, error) {
+	ctx, span := tracing.StartSpan(ctx, "archive.Restore")
+	defer span.End()
+
+	res := &models.RestoreArchiveResponse{}
+
+	err := readRecords(ctx, store, incidentsPrefix, req.Since, func(line []byte) error {
+		archived := &models.ArchivedIncident{}
+
+		if err := json.Unmarshal(line, archived); err != nil {
+			return fmt.Errorf("error decoding archived incident. Error: %w", err)
+		} else if archived.Incident == nil {
+			return nil
+		}
+
+		incident := archived.Incident
+
+		if !matches(req, incident.Namespace, incident.CreatedAt) {
+			return nil
+		}
+
+		if ok, err := allowed(incident.Namespace); err != nil {
+			return err
+		} else if !ok {
+			res.Skipped++
+			return nil
+		}
+
+		restored, err := redisClient.RestoreIncident(ctx, archived)
+		if err != nil {
+			return err
+		}
+
+		if restored {
+			res.Incidents++
+		} else {
+			res.Skipped++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readRecords(ctx, store, logsPrefix, req.Since, func(line []byte) error {
+		archived := &models.ArchivedLog{}
+
+		if err := json.Unmarshal(line, archived); err != nil {
+			return fmt.Errorf("error decoding archived log. Error: %w", err)
+		}
+
+		// logs are authorized by the namespace of their incident
+		incidentObj, err := utils.NewIncidentFromString(archived.IncidentID)
+		if err != nil {
+			return fmt.Errorf("error getting incident object for archived log ID: %s. Error: %w", archived.LogID, err)
+		}
+
+		namespace := incidentObj.GetNamespace()
+
+		if !matches(req, namespace, archived.CapturedAt) {
+			return nil
+		}
+
+		if ok, err := allowed(namespace); err != nil {
+			return err
+		} else if !ok {
+			res.Skipped++
+			return nil
+		}
+
+		restored, err := redisClient.RestoreLogs(ctx, archived)
+		if err != nil {
+			return err
+		}
+
+		if restored {
+			res.Logs++
+		} else {
+			res.Skipped++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func matches(req *models.RestoreArchiveRequest, namespace string, timestamp int64) bool {
+	if req.Namespace != "" && req.Namespace != namespace {
+		return false
+	}
+
+	if req.Since != 0 && timestamp < req.Since {
+		return false
+	}
+
+	if req.Until != 0 && timestamp > req.Until {
+		return false
+	}
+
+	return true
+}
+
+// readRecords calls fn with every line of the archive files under the prefix,
+// skipping the files written before since, which cannot hold newer items
+func readRecords(ctx context.Context, store Store, prefix string, since int64, fn func(line []byte) error) error {
+	keys, err := store.List(ctx, prefix)
+	if err != nil {
+		return err
+	}
+
+	var sinceDay string
+	if since != 0 {
+		sinceDay = prefix + time.Unix(since, 0).UTC().Format(dayLayout)
+	}
+
+	for _, key := range keys {
+		if sinceDay != "" && len(key) >= len(sinceDay) && key[:len(sinceDay)] < sinceDay {
+			continue
+		}
+
+		data, err := store.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+
+		for scanner.Scan() {
+			if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+				if err := fn(line); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("error reading archive file %s. Error: %w", key, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,350 @@
+package archive
+
+import (
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/redis"
+)
+
+func newTestRedisClient(t *testing.T) (*redis.Client, *miniredis.Miniredis) {
+	t.Helper()
+
+	m := miniredis.RunT(t)
+
+	config, err := redis.ConfigFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error reading redis config: %v", err)
+	}
+
+	config.Options.Addrs = []string{m.Addr()}
+
+	return redis.NewClient(config, 100), m
+}
+
+// fakeS3 is an S3 stand-in serving the objects of a single bucket by path,
+// enough for the store to put, get and list objects
+type fakeS3 struct {
+	bucket string
+
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+type listBucketResult struct {
+	XMLName     xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+	Name        string   `xml:"Name"`
+	Prefix      string   `xml:"Prefix"`
+	KeyCount    int      `xml:"KeyCount"`
+	IsTruncated bool     `xml:"IsTruncated"`
+	Contents    []struct {
+		Key  string `xml:"Key"`
+		Size int    `xml:"Size"`
+	} `xml:"Contents"`
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := strings.TrimPrefix(r.URL.Path, "/"+s.bucket)
+	if key == r.URL.Path {
+		http.Error(w, "no such bucket", http.StatusNotFound)
+		return
+	}
+
+	key = strings.TrimPrefix(key, "/")
+
+	switch {
+	case r.Method == http.MethodPut && key != "":
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		s.objects[key] = data
+	case r.Method == http.MethodGet && key != "":
+		data, ok := s.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("<Error><Code>NoSuchKey</Code><Message>not found</Message></Error>"))
+
+			return
+		}
+
+		w.Write(data)
+	case r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+		res := &listBucketResult{
+			Name:   s.bucket,
+			Prefix: r.URL.Query().Get("prefix"),
+		}
+
+		for key, data := range s.objects {
+			if strings.HasPrefix(key, res.Prefix) {
+				res.Contents = append(res.Contents, struct {
+					Key  string `xml:"Key"`
+					Size int    `xml:"Size"`
+				}{key, len(data)})
+			}
+		}
+
+		sort.Slice(res.Contents, func(i, j int) bool { return res.Contents[i].Key < res.Contents[j].Key })
+
+		res.KeyCount = len(res.Contents)
+
+		w.Header().Set("Content-Type", "application/xml")
+		xml.NewEncoder(w).Encode(res)
+	default:
+		http.Error(w, "unsupported request", http.StatusBadRequest)
+	}
+}
+
+func newTestS3Store(t *testing.T) (Store, *fakeS3) {
+	t.Helper()
+
+	for key, value := range map[string]string{
+		"AWS_ACCESS_KEY_ID":     "test",
+		"AWS_SECRET_ACCESS_KEY": "test",
+	} {
+		previous, ok := os.LookupEnv(key)
+		os.Setenv(key, value)
+
+		t.Cleanup(func() {
+			if ok {
+				os.Setenv(key, previous)
+			} else {
+				os.Unsetenv(key)
+			}
+		})
+	}
+
+	s3 := &fakeS3{bucket: "archive", objects: make(map[string][]byte)}
+
+	server := httptest.NewServer(s3)
+	t.Cleanup(server.Close)
+
+	store, err := NewStore("s3://archive/porter-agent?region=us-east-1&endpoint=" + server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error creating S3 store: %v", err)
+	}
+
+	return store, s3
+}
+
+func TestStores(t *testing.T) {
+	s3Store, s3 := newTestS3Store(t)
+
+	stores := map[string]Store{
+		"local": &LocalStore{Dir: t.TempDir()},
+		"s3":    s3Store,
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			for _, key := range []string{"logs/2024/01/02/2.ndjson", "incidents/2024/01/02/1.ndjson",
+				"incidents/2024/01/01/1.ndjson"} {
+				if err := store.Put(ctx, key, []byte(key)); err != nil {
+					t.Fatalf("unexpected error putting %s: %v", key, err)
+				}
+			}
+
+			keys, err := store.List(ctx, "incidents/")
+			if err != nil {
+				t.Fatalf("unexpected error listing keys: %v", err)
+			}
+
+			want := []string{"incidents/2024/01/01/1.ndjson", "incidents/2024/01/02/1.ndjson"}
+			if strings.Join(keys, ",") != strings.Join(want, ",") {
+				t.Errorf("expected keys %v, got %v", want, keys)
+			}
+
+			data, err := store.Get(ctx, "logs/2024/01/02/2.ndjson")
+			if err != nil || string(data) != "logs/2024/01/02/2.ndjson" {
+				t.Errorf("expected to get the stored data, got %q, %v", data, err)
+			}
+
+			if _, err := store.Get(ctx, "logs/missing.ndjson"); err == nil {
+				t.Errorf("expected an error getting a missing key")
+			}
+		})
+	}
+
+	// objects are kept under the prefix of the URL
+	if _, ok := s3.objects["porter-agent/logs/2024/01/02/2.ndjson"]; !ok {
+		t.Errorf("expected objects to be stored under the prefix of the archive URL")
+	}
+}
+
+func TestArchiveAndRestore(t *testing.T) {
+	defer func(previous time.Duration) { leadTime = previous }(leadTime)
+
+	// everything which is stored expires within the lead time
+	leadTime = 365 * 24 * time.Hour
+
+	s3Store, _ := newTestS3Store(t)
+
+	stores := map[string]Store{
+		"local": &LocalStore{Dir: t.TempDir()},
+		"s3":    s3Store,
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			redisClient, m := newTestRedisClient(t)
+
+			incidentIDs := make(map[string]string)
+			logIDs := make(map[string]string)
+
+			for _, namespace := range []string{"prod", "staging"} {
+				incidentID, err := redisClient.CreateActiveIncident(ctx, "web", namespace)
+				if err != nil {
+					t.Fatalf("unexpected error creating incident: %v", err)
+				}
+
+				event := &models.PodEvent{
+					PodName:   "web-1",
+					Namespace: namespace,
+					OwnerName: "web",
+					OwnerType: "Deployment",
+					Timestamp: time.Now().Unix(),
+					Reason:    "CrashLoopBackOff",
+				}
+
+				if err := redisClient.AddEventToIncident(ctx, incidentID, event, true); err != nil {
+					t.Fatalf("unexpected error adding event: %v", err)
+				}
+
+				logID, err := redisClient.AddLogs(ctx, incidentID, "web", "panic: "+namespace)
+				if err != nil {
+					t.Fatalf("unexpected error adding logs: %v", err)
+				}
+
+				incidentIDs[namespace] = incidentID
+				logIDs[namespace] = logID
+			}
+
+			compactor, err := NewCompactor(redisClient, store)
+			if err != nil {
+				t.Fatalf("unexpected error creating compactor: %v", err)
+			}
+
+			if err := compactor.Compact(ctx); err != nil {
+				t.Fatalf("unexpected error archiving: %v", err)
+			}
+
+			for _, prefix := range []string{incidentsPrefix, logsPrefix} {
+				if keys, err := store.List(ctx, prefix); err != nil || len(keys) != 1 {
+					t.Fatalf("expected a single archive file under %s, got %v, %v", prefix, keys, err)
+				}
+			}
+
+			// archived items are not archived again
+			if err := compactor.Compact(ctx); err != nil {
+				t.Fatalf("unexpected error archiving: %v", err)
+			}
+
+			if keys, _ := store.List(ctx, incidentsPrefix); len(keys) != 1 {
+				t.Errorf("expected archived incidents not to be archived again, got %v", keys)
+			}
+
+			// restoring items which are still stored skips them
+			res, err := Restore(ctx, store, redisClient, &models.RestoreArchiveRequest{}, allowAll)
+			if err != nil {
+				t.Fatalf("unexpected error restoring: %v", err)
+			}
+
+			if res.Incidents != 0 || res.Logs != 0 || res.Skipped != 4 {
+				t.Errorf("expected stored items to be skipped, got %+v", res)
+			}
+
+			m.FlushAll()
+
+			// only the allowed namespace of the request is restored
+			res, err = Restore(ctx, store, redisClient, &models.RestoreArchiveRequest{Namespace: "prod"},
+				func(namespace string) (bool, error) { return namespace == "prod", nil })
+			if err != nil {
+				t.Fatalf("unexpected error restoring: %v", err)
+			}
+
+			if res.Incidents != 1 || res.Logs != 1 || res.Skipped != 0 {
+				t.Errorf("expected the incident and logs of prod to be restored, got %+v", res)
+			}
+
+			events, err := redisClient.GetIncidentEventsByID(ctx, incidentIDs["prod"])
+			if err != nil || len(events) != 1 || events[0].Reason != "CrashLoopBackOff" {
+				t.Fatalf("expected the events of the incident to be restored, got %v, %v", events, err)
+			}
+
+			if exists, _ := redisClient.IncidentExists(ctx, incidentIDs["staging"]); exists {
+				t.Errorf("expected the incident of staging not to be restored")
+			}
+
+			if logs, err := redisClient.GetLogs(ctx, logIDs["prod"]); err != nil || logs != "panic: prod" {
+				t.Errorf("expected the logs of the incident to be restored, got %q, %v", logs, err)
+			}
+
+			if _, err := redisClient.GetLogs(ctx, logIDs["staging"]); err == nil {
+				t.Errorf("expected the logs of staging not to be restored")
+			}
+
+			// namespaces which are not allowed are skipped
+			res, err = Restore(ctx, store, redisClient, &models.RestoreArchiveRequest{},
+				func(namespace string) (bool, error) { return namespace == "prod", nil })
+			if err != nil {
+				t.Fatalf("unexpected error restoring: %v", err)
+			}
+
+			if res.Incidents != 0 || res.Logs != 0 || res.Skipped != 4 {
+				t.Errorf("expected restored and disallowed items to be skipped, got %+v", res)
+			}
+		})
+	}
+}
+
+func allowAll(string) (bool, error) {
+	return true, nil
+}
+
+func TestCompactWithoutArchive(t *testing.T) {
+	ctx := context.Background()
+	redisClient, _ := newTestRedisClient(t)
+
+	incidentID, err := redisClient.CreateActiveIncident(ctx, "web", "prod")
+	if err != nil {
+		t.Fatalf("unexpected error creating incident: %v", err)
+	}
+
+	if _, err := redisClient.AddLogs(ctx, incidentID, "web", "panic: prod"); err != nil {
+		t.Fatalf("unexpected error adding logs: %v", err)
+	}
+
+	compactor, err := NewCompactor(redisClient, nil)
+	if err != nil {
+		t.Fatalf("unexpected error creating compactor: %v", err)
+	}
+
+	// the log index is pruned without archiving
+	if err := compactor.Compact(ctx); err != nil {
+		t.Fatalf("unexpected error compacting: %v", err)
+	}
+
+	logIDs, _, err := redisClient.SearchLogs(ctx, []string{"panic"}, nil, 0)
+	if err != nil || len(logIDs) != 1 {
+		t.Errorf("expected the logs which have not expired to stay in the index, got %v, %v", logIDs, err)
+	}
+}
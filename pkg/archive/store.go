@@ -0,0 +1,240 @@
+// Package archive writes incidents and logs to an archive before they expire
+// from the store, and restores them from it. Archives are NDJSON files kept
+// in a local directory or in an S3-compatible bucket.
+package archive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// Store holds the archive files. Keys are slash-separated paths relative to
+// the root of the archive.
+type Store interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// List returns the keys starting with the prefix in lexical order
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// NewStore returns the store at the archive URL, which is one of:
+//
+//	file:///var/lib/porter-agent/archive
+//	s3://bucket[/prefix][?endpoint=http://minio:9000&region=us-east-1]
+//
+// S3 credentials are read from the environment, the shared credentials file
+// or the instance role. A custom endpoint is addressed by path, so that
+// S3-compatible stores such as MinIO can be used.
+func NewStore(rawURL string) (Store, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive URL. Error: %w", err)
+	}
+
+	switch u.Scheme {
+	case "file":
+		if u.Host != "" && u.Host != "localhost" {
+			return nil, fmt.Errorf("archive file URLs must not have a host")
+		} else if u.Path == "" {
+			return nil, fmt.Errorf("archive file URL has no path")
+		}
+
+		return &LocalStore{Dir: filepath.FromSlash(u.Path)}, nil
+	case "s3":
+		if u.Host == "" {
+			return nil, fmt.Errorf("archive S3 URL has no bucket")
+		}
+
+		query := u.Query()
+
+		config := aws.NewConfig()
+
+		if region := query.Get("region"); region != "" {
+			config = config.WithRegion(region)
+		} else if os.Getenv("AWS_REGION") == "" && os.Getenv("AWS_DEFAULT_REGION") == "" {
+			config = config.WithRegion("us-east-1")
+		}
+
+		if endpoint := query.Get("endpoint"); endpoint != "" {
+			config = config.WithEndpoint(endpoint).WithS3ForcePathStyle(true)
+		}
+
+		sess, err := session.NewSessionWithOptions(session.Options{
+			Config:            *config,
+			SharedConfigState: session.SharedConfigEnable,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error creating S3 session. Error: %w", err)
+		}
+
+		return &S3Store{
+			Client: s3.New(sess),
+			Bucket: u.Host,
+			Prefix: strings.Trim(u.Path, "/"),
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid archive URL scheme: %s", u.Scheme)
+	}
+}
+
+// LocalStore keeps the archive in a directory
+type LocalStore struct {
+	Dir string
+}
+
+func (s *LocalStore) Put(ctx context.Context, key string, data []byte) error {
+	filename := filepath.Join(s.Dir, filepath.FromSlash(key))
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return fmt.Errorf("error creating archive directory. Error: %w", err)
+	}
+
+	// files are written under a temporary name first, so that restores never
+	// read partial files
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), ".tmp-")
+	if err != nil {
+		return fmt.Errorf("error creating archive file. Error: %w", err)
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing archive file %s. Error: %w", key, err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing archive file %s. Error: %w", key, err)
+	}
+
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return fmt.Errorf("error writing archive file %s. Error: %w", key, err)
+	}
+
+	return nil
+}
+
+func (s *LocalStore) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := ioutil.ReadFile(filepath.Join(s.Dir, filepath.FromSlash(key)))
+	if err != nil {
+		return nil, fmt.Errorf("error reading archive file %s. Error: %w", key, err)
+	}
+
+	return data, nil
+}
+
+func (s *LocalStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+
+	err := filepath.Walk(s.Dir, func(filename string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && filename == s.Dir {
+				return filepath.SkipDir
+			}
+
+			return err
+		}
+
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".tmp-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.Dir, filename)
+		if err != nil {
+			return err
+		}
+
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing archive files. Error: %w", err)
+	}
+
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+// S3Store keeps the archive in an S3 bucket, under an optional prefix
+type S3Store struct {
+	Client *s3.S3
+	Bucket string
+	Prefix string
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, data []byte) error {
+	_, err := s.Client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.Bucket),
+		Key:         aws.String(path.Join(s.Prefix, key)),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/x-ndjson"),
+	})
+	if err != nil {
+		return fmt.Errorf("error uploading archive file %s. Error: %w", key, err)
+	}
+
+	return nil
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	out, err := s.Client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(path.Join(s.Prefix, key)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error downloading archive file %s. Error: %w", key, err)
+	}
+
+	defer out.Body.Close()
+
+	data, err := ioutil.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading archive file %s. Error: %w", key, err)
+	}
+
+	return data, nil
+}
+
+func (s *S3Store) List(ctx context.Context, prefix string) ([]string, error) {
+	root := ""
+	if s.Prefix != "" {
+		root = s.Prefix + "/"
+	}
+
+	var keys []string
+
+	err := s.Client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.Bucket),
+		Prefix: aws.String(root + prefix),
+	}, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		for _, obj := range page.Contents {
+			keys = append(keys, strings.TrimPrefix(aws.StringValue(obj.Key), root))
+		}
+
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing archive files. Error: %w", err)
+	}
+
+	sort.Strings(keys)
+
+	return keys, nil
+}
@@ -0,0 +1,18 @@
+package client
+
+import (
+	"context"
+
+	"github.com/porter-dev/porter-agent/pkg/models"
+)
+
+// RestoreArchive imports archived incidents and logs back into the agent
+func (c *Client) RestoreArchive(ctx context.Context, req *models.RestoreArchiveRequest) (*models.RestoreArchiveResponse, error) {
+	res := &models.RestoreArchiveResponse{}
+
+	if err := c.post(ctx, "/archive/restore", req, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
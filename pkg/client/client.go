@@ -0,0 +1,191 @@
+// Package client is a Go client for the agent API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/porter-dev/porter-agent/pkg/models"
+)
+
+// the version of the API used by the client, whose errors are envelopes
+const apiPrefix = "/v2"
+
+type Client struct {
+	client  *http.Client
+	baseURL string
+	token   string
+}
+
+// NewClient returns a client for the /v2 agent API at baseURL, such as
+// "http://porter-agent-controller-manager.porter-agent-system:10001". The
+// token is sent as a bearer token if it is non-empty.
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+	}
+}
+
+// WithHTTPClient replaces the HTTP client used for requests, for example to
+// present a client certificate
+func (c *Client) WithHTTPClient(httpClient *http.Client) *Client {
+	c.client = httpClient
+	return c
+}
+
+// APIError is returned for the error responses of the API. Code and
+// RequestID are empty for agents which do not serve the /v2 API.
+type APIError struct {
+	StatusCode int
+	Code       models.ErrorCode
+	Message    string
+	Details    map[string]interface{}
+	RequestID  string
+}
+
+func (e *APIError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("agent API returned status %d: %s (request ID: %s)", e.StatusCode, e.Message, e.RequestID)
+	}
+
+	return fmt.Sprintf("agent API returned status %d: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound returns true if the error is a 404 response of the API
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// IsConflict returns true if the error is a 409 response of the API
+func IsConflict(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict
+}
+
+// IsUnavailable returns true if the error is a 503 response of the API,
+// which can be retried
+func IsUnavailable(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusServiceUnavailable
+}
+
+func (c *Client) get(ctx context.Context, path string, query url.Values, res interface{}) error {
+	return c.do(ctx, http.MethodGet, path, query, nil, res)
+}
+
+func (c *Client) post(ctx context.Context, path string, body, res interface{}) error {
+	return c.do(ctx, http.MethodPost, path, nil, body, res)
+}
+
+func (c *Client) delete(ctx context.Context, path string) error {
+	return c.do(ctx, http.MethodDelete, path, nil, nil, nil)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, res interface{}) error {
+	req, err := c.newRequest(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return newAPIError(resp)
+	}
+
+	if res == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return fmt.Errorf("error decoding response of %s %s. Error: %w", method, path, err)
+	}
+
+	return nil
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Request, error) {
+	reqURL := c.baseURL + apiPrefix + path
+
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+
+	var reqBody io.Reader
+
+	if body != nil {
+		jsonBody, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("error marshalling request body. Error: %w", err)
+		}
+
+		reqBody = bytes.NewReader(jsonBody)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if c.token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
+	}
+
+	return req, nil
+}
+
+// newAPIError reads an error envelope from the response, or the
+// {"error": message} body of the unversioned API
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get("X-Request-ID"),
+	}
+
+	errRes := &struct {
+		Error json.RawMessage `json:"error"`
+	}{}
+
+	if err := json.NewDecoder(resp.Body).Decode(errRes); err == nil && len(errRes.Error) > 0 {
+		envelope := &models.APIError{}
+
+		if err := json.Unmarshal(errRes.Error, envelope); err == nil {
+			apiErr.Code = envelope.Code
+			apiErr.Message = envelope.Message
+			apiErr.Details = envelope.Details
+
+			if envelope.RequestID != "" {
+				apiErr.RequestID = envelope.RequestID
+			}
+		} else {
+			json.Unmarshal(errRes.Error, &apiErr.Message)
+		}
+	}
+
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+
+	return apiErr
+}
@@ -0,0 +1,267 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/porter-dev/porter-agent/pkg/models"
+)
+
+// defaultPageSize is the page size used to list all incidents
+const defaultPageSize = 50
+
+// ListIncidentsOptions filters, sorts and paginates incident lists. Each
+// filter matches any of its values, and zero values are ignored.
+type ListIncidentsOptions struct {
+	States     []string
+	Severities []models.Severity
+	Namespaces []string
+	Releases   []string
+	OwnerKinds []string
+	Reasons    []string
+	ChartNames []string
+
+	// Fingerprints select the incidents whose latest crash has one of the
+	// stack trace fingerprints
+	Fingerprints []string
+
+	// AfterRollout only selects the incidents which started shortly after a
+	// rollout
+	AfterRollout bool
+
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
+
+	// SortBy is either created_at or updated_at
+	SortBy    string
+	Ascending bool
+
+	Limit  int
+	Offset int
+}
+
+func (o *ListIncidentsOptions) values() url.Values {
+	values := url.Values{}
+
+	if o == nil {
+		return values
+	}
+
+	var severities []string
+
+	for _, severity := range o.Severities {
+		severities = append(severities, string(severity))
+	}
+
+	for param, filter := range map[string][]string{
+		"state":       o.States,
+		"severity":    severities,
+		"namespace":   o.Namespaces,
+		"release":     o.Releases,
+		"owner_kind":  o.OwnerKinds,
+		"reason":      o.Reasons,
+		"chart_name":  o.ChartNames,
+		"fingerprint": o.Fingerprints,
+	} {
+		if len(filter) > 0 {
+			values.Set(param, strings.Join(filter, ","))
+		}
+	}
+
+	for param, t := range map[string]time.Time{
+		"created_after":  o.CreatedAfter,
+		"created_before": o.CreatedBefore,
+		"updated_after":  o.UpdatedAfter,
+		"updated_before": o.UpdatedBefore,
+	} {
+		if !t.IsZero() {
+			values.Set(param, strconv.FormatInt(t.Unix(), 10))
+		}
+	}
+
+	if o.AfterRollout {
+		values.Set("after_rollout", "true")
+	}
+
+	if o.SortBy != "" {
+		values.Set("sort", o.SortBy)
+	}
+
+	if o.Ascending {
+		values.Set("order", "asc")
+	}
+
+	if o.Limit > 0 {
+		values.Set("limit", strconv.Itoa(o.Limit))
+	}
+
+	if o.Offset > 0 {
+		values.Set("offset", strconv.Itoa(o.Offset))
+	}
+
+	return values
+}
+
+// ListIncidents returns a page of the incidents matching the options
+func (c *Client) ListIncidents(ctx context.Context, opts *ListIncidentsOptions) (*models.ListIncidentsResponse, error) {
+	res := &models.ListIncidentsResponse{}
+
+	if err := c.get(ctx, "/incidents", opts.values(), res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ListReleaseIncidents returns a page of the incidents of a release matching
+// the options. The namespace and release filters of the options are ignored.
+func (c *Client) ListReleaseIncidents(ctx context.Context, namespace, releaseName string, opts *ListIncidentsOptions) (*models.ListIncidentsResponse, error) {
+	res := &models.ListIncidentsResponse{}
+
+	path := fmt.Sprintf("/incidents/namespaces/%s/releases/%s", url.PathEscape(namespace), url.PathEscape(releaseName))
+
+	if err := c.get(ctx, path, opts.values(), res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ListIncidentsPages calls fn with each page of the incidents matching the
+// options, starting at the offset of the options, until fn returns false or
+// an error or there are no more pages
+func (c *Client) ListIncidentsPages(ctx context.Context, opts *ListIncidentsOptions,
+	fn func(page *models.ListIncidentsResponse) (bool, error)) error {
+	pageOpts := ListIncidentsOptions{}
+
+	if opts != nil {
+		pageOpts = *opts
+	}
+
+	if pageOpts.Limit == 0 {
+		pageOpts.Limit = defaultPageSize
+	}
+
+	for {
+		page, err := c.ListIncidents(ctx, &pageOpts)
+		if err != nil {
+			return err
+		}
+
+		if cont, err := fn(page); err != nil {
+			return err
+		} else if !cont || page.NextOffset == nil {
+			return nil
+		}
+
+		pageOpts.Offset = *page.NextOffset
+	}
+}
+
+// ListAllIncidents returns all the incidents matching the options, fetching
+// them a page at a time
+func (c *Client) ListAllIncidents(ctx context.Context, opts *ListIncidentsOptions) ([]*models.Incident, error) {
+	var incidents []*models.Incident
+
+	err := c.ListIncidentsPages(ctx, opts, func(page *models.ListIncidentsResponse) (bool, error) {
+		incidents = append(incidents, page.Incidents...)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return incidents, nil
+}
+
+// GetIncident returns an incident along with its events
+func (c *Client) GetIncident(ctx context.Context, incidentID string) (*models.IncidentEventsResponse, error) {
+	res := &models.IncidentEventsResponse{}
+
+	if err := c.get(ctx, "/incidents/"+url.PathEscape(incidentID), nil, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// AcknowledgeIncident acknowledges an ongoing incident. The user is only used
+// when authenticating with a static API token.
+func (c *Client) AcknowledgeIncident(ctx context.Context, incidentID, user string) (*models.Incident, error) {
+	return c.incidentAction(ctx, incidentID, "acknowledge", user)
+}
+
+// ResolveIncident manually resolves an ongoing incident
+func (c *Client) ResolveIncident(ctx context.Context, incidentID, user string) (*models.Incident, error) {
+	return c.incidentAction(ctx, incidentID, "resolve", user)
+}
+
+// ReopenIncident reopens a resolved incident
+func (c *Client) ReopenIncident(ctx context.Context, incidentID, user string) (*models.Incident, error) {
+	return c.incidentAction(ctx, incidentID, "reopen", user)
+}
+
+func (c *Client) incidentAction(ctx context.Context, incidentID, action, user string) (*models.Incident, error) {
+	res := &models.Incident{}
+
+	path := fmt.Sprintf("/incidents/%s/%s", url.PathEscape(incidentID), action)
+
+	if err := c.post(ctx, path, &models.IncidentActionRequest{User: user}, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ListIncidentComments returns the comments of an incident, oldest first
+func (c *Client) ListIncidentComments(ctx context.Context, incidentID string) ([]*models.IncidentComment, error) {
+	res := &models.ListCommentsResponse{}
+
+	if err := c.get(ctx, fmt.Sprintf("/incidents/%s/comments", url.PathEscape(incidentID)), nil, res); err != nil {
+		return nil, err
+	}
+
+	return res.Comments, nil
+}
+
+func (c *Client) AddIncidentComment(ctx context.Context, incidentID string, req *models.AddCommentRequest) (*models.IncidentComment, error) {
+	res := &models.IncidentComment{}
+
+	if err := c.post(ctx, fmt.Sprintf("/incidents/%s/comments", url.PathEscape(incidentID)), req, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// GetEventSnapshot returns the snapshot of the pod of an event of an incident,
+// taken when the event was recorded
+func (c *Client) GetEventSnapshot(ctx context.Context, incidentID, eventID string) (*models.PodSnapshot, error) {
+	res := &models.PodSnapshot{}
+
+	path := fmt.Sprintf("/incidents/%s/events/%s/snapshot", url.PathEscape(incidentID), url.PathEscape(eventID))
+
+	if err := c.get(ctx, path, nil, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ListIncidentRemediations returns the actions taken to remediate an incident,
+// oldest first
+func (c *Client) ListIncidentRemediations(ctx context.Context, incidentID string) ([]*models.Remediation, error) {
+	res := &models.ListRemediationsResponse{}
+
+	if err := c.get(ctx, fmt.Sprintf("/incidents/%s/remediations", url.PathEscape(incidentID)), nil, res); err != nil {
+		return nil, err
+	}
+
+	return res.Remediations, nil
+}
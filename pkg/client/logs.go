@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/porter-dev/porter-agent/pkg/models"
+)
+
+// GetLogs returns the contents of captured logs
+func (c *Client) GetLogs(ctx context.Context, logID string) (string, error) {
+	res := &models.LogsResponse{}
+
+	if err := c.get(ctx, "/incidents/logs/"+url.PathEscape(logID), nil, res); err != nil {
+		return "", err
+	}
+
+	return res.Contents, nil
+}
+
+type SearchLogsOptions struct {
+	// Regex makes the query a regular expression instead of words
+	Regex     bool
+	Namespace string
+	Since     time.Time
+	Limit     int
+}
+
+// SearchLogs returns the captured logs matching the query, newest first
+func (c *Client) SearchLogs(ctx context.Context, q string, opts *SearchLogsOptions) (*models.SearchLogsResponse, error) {
+	values := url.Values{}
+	values.Set("q", q)
+
+	if opts != nil {
+		if opts.Regex {
+			values.Set("regex", "true")
+		}
+
+		if opts.Namespace != "" {
+			values.Set("namespace", opts.Namespace)
+		}
+
+		if !opts.Since.IsZero() {
+			values.Set("since", strconv.FormatInt(opts.Since.Unix(), 10))
+		}
+
+		if opts.Limit > 0 {
+			values.Set("limit", strconv.Itoa(opts.Limit))
+		}
+	}
+
+	res := &models.SearchLogsResponse{}
+
+	if err := c.get(ctx, "/logs/search", values, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,42 @@
+package client
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/porter-dev/porter-agent/pkg/models"
+)
+
+func (c *Client) ListSilences(ctx context.Context) ([]*models.SilenceResponse, error) {
+	res := &models.ListSilencesResponse{}
+
+	if err := c.get(ctx, "/silences", nil, res); err != nil {
+		return nil, err
+	}
+
+	return res.Silences, nil
+}
+
+func (c *Client) GetSilence(ctx context.Context, name string) (*models.SilenceResponse, error) {
+	res := &models.SilenceResponse{}
+
+	if err := c.get(ctx, "/silences/"+url.PathEscape(name), nil, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *Client) CreateSilence(ctx context.Context, req *models.CreateSilenceRequest) (*models.SilenceResponse, error) {
+	res := &models.SilenceResponse{}
+
+	if err := c.post(ctx, "/silences", req, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *Client) DeleteSilence(ctx context.Context, name string) error {
+	return c.delete(ctx, "/silences/"+url.PathEscape(name))
+}
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/porter-dev/porter-agent/pkg/models"
+)
+
+type StreamIncidentsOptions struct {
+	Namespace string
+	Release   string
+
+	// LastEventID resumes the stream after this change. Without it, the
+	// stream starts with the next change.
+	LastEventID string
+}
+
+// StreamIncidentChanges reads the change stream of incidents, calling fn with
+// each change, until the context is done, fn returns an error or the stream
+// is closed. The ID of the last change can be used to resume the stream.
+func (c *Client) StreamIncidentChanges(ctx context.Context, opts *StreamIncidentsOptions,
+	fn func(change *models.IncidentChange) error) error {
+	values := url.Values{}
+
+	if opts != nil {
+		if opts.Namespace != "" {
+			values.Set("namespace", opts.Namespace)
+		}
+
+		if opts.Release != "" {
+			values.Set("release", opts.Release)
+		}
+	}
+
+	req, err := c.newRequest(ctx, http.MethodGet, "/incidents/stream", values, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "text/event-stream")
+
+	if opts != nil && opts.LastEventID != "" {
+		req.Header.Set("Last-Event-ID", opts.LastEventID)
+	}
+
+	// the stream stays open, so the timeout of the client cannot apply
+	streamClient := *c.client
+	streamClient.Timeout = 0
+
+	resp, err := streamClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return newAPIError(resp)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var data strings.Builder
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case line == "":
+			// a blank line ends an event
+			if data.Len() == 0 {
+				continue
+			}
+
+			change := &models.IncidentChange{}
+
+			if err := json.Unmarshal([]byte(data.String()), change); err != nil {
+				return fmt.Errorf("error decoding incident change. Error: %w", err)
+			}
+
+			data.Reset()
+
+			if err := fn(change); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteString("\n")
+			}
+
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+
+		// comments, such as heartbeats, and the id and event fields, which
+		// are also part of the change, are ignored
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return scanner.Err()
+}
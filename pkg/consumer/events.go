@@ -11,15 +11,21 @@ import (
 	"github.com/go-logr/logr"
 	porterErrors "github.com/porter-dev/porter-agent/pkg/errors"
 	"github.com/porter-dev/porter-agent/pkg/httpclient"
+	"github.com/porter-dev/porter-agent/pkg/metrics"
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/notify"
 	"github.com/porter-dev/porter-agent/pkg/pulsar"
 	"github.com/porter-dev/porter-agent/pkg/redis"
+	"github.com/porter-dev/porter-agent/pkg/signature"
+	"github.com/porter-dev/porter-agent/pkg/silence"
+	"github.com/porter-dev/porter-agent/pkg/tracing"
 	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
 var (
-	redisHost    string
-	redisPort    string
 	maxTailLines int64
 	porterHost   string
 	porterPort   string
@@ -27,18 +33,31 @@ var (
 	clusterID    string
 	projectID    string
 
+	// comma-separated list of secrets used to sign outbound notifications,
+	// the first one being the current secret and the rest kept for rotation
+	signingSecrets []string
+
+	// path to the YAML file holding the notification routing rules
+	notifyRoutesFile string
+
+	// failed deliveries are retried after a backoff doubling from
+	// retryBackoff up to retryMaxBackoff, and are moved to the dead-letter
+	// queue after retryMaxAttempts failures
+	retryMaxAttempts int
+	retryBackoff     time.Duration
+	retryMaxBackoff  time.Duration
+
 	consumerLog = ctrl.Log.WithName("event-consumer")
 )
 
 func init() {
-	viper.SetDefault("REDIS_HOST", "porter-redis-master")
-	viper.SetDefault("REDIS_PORT", "6379")
 	viper.SetDefault("MAX_TAIL_LINES", int64(100))
 	viper.SetDefault("PORTER_PORT", "80")
+	viper.SetDefault("NOTIFY_RETRY_MAX_ATTEMPTS", 10)
+	viper.SetDefault("NOTIFY_RETRY_BACKOFF", "10s")
+	viper.SetDefault("NOTIFY_RETRY_MAX_BACKOFF", "1h")
 	viper.AutomaticEnv()
 
-	redisHost = viper.GetString("REDIS_HOST")
-	redisPort = viper.GetString("REDIS_PORT")
 	maxTailLines = viper.GetInt64("MAX_TAIL_LINES")
 
 	porterPort = viper.GetString("PORTER_PORT")
@@ -47,14 +66,31 @@ func init() {
 	clusterID = getStringOrDie("CLUSTER_ID")
 	projectID = getStringOrDie("PROJECT_ID")
 
+	signingSecrets = strings.Split(viper.GetString("NOTIFY_SIGNING_SECRETS"), ",")
+	notifyRoutesFile = viper.GetString("NOTIFY_ROUTES_FILE")
+
+	retryMaxAttempts = viper.GetInt("NOTIFY_RETRY_MAX_ATTEMPTS")
+	retryBackoff = viper.GetDuration("NOTIFY_RETRY_BACKOFF")
+	retryMaxBackoff = viper.GetDuration("NOTIFY_RETRY_MAX_BACKOFF")
 }
 
+// The reasons for moving notifications to the dead-letter queue
+const (
+	deadLetterInvalid        = "invalid"
+	deadLetterUnknownSink    = "unknown_sink"
+	deadLetterPermanentError = "permanent_error"
+	deadLetterMaxAttempts    = "max_attempts"
+)
+
 type EventConsumer struct {
 	redisClient *redis.Client
-	httpClient  *httpclient.Client
+	router      *notify.Router
+	dispatcher  *notify.Dispatcher
+	silences    *silence.Store
 	pulsar      *pulsar.Pulsar
 	context     context.Context
 	consumerLog logr.Logger
+	health      *health
 }
 
 func getStringOrDie(key string) string {
@@ -69,20 +105,57 @@ func getStringOrDie(key string) string {
 	return value
 }
 
-func NewEventConsumer(timePeriod int, timeUnit time.Duration, ctx context.Context) *EventConsumer {
-	return &EventConsumer{
-		redisClient: redis.NewClient(redisHost, redisPort, "", "", redis.PODSTORE, maxTailLines),
-		httpClient:  httpclient.NewClient(fmt.Sprintf("%s:%s", porterHost, porterPort), porterToken),
+func NewEventConsumer(timePeriod int, timeUnit time.Duration, ctx context.Context, silenceStore *silence.Store) (*EventConsumer, error) {
+	signer := signature.NewSigner(signingSecrets...)
+
+	porterSink := notify.NewPorterSink(
+		notify.PorterSinkName,
+		httpclient.NewClient(fmt.Sprintf("%s:%s", porterHost, porterPort), porterToken, signer),
+		projectID,
+		clusterID,
+	)
+
+	notifyConfig, err := notify.LoadConfig(notifyRoutesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	router, err := notify.NewRouter(notifyConfig, porterSink, signer)
+	if err != nil {
+		return nil, fmt.Errorf("invalid notification routing config. Error: %w", err)
+	}
+
+	redisClient, err := redis.NewClientFromEnv(maxTailLines)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &EventConsumer{
+		redisClient: redisClient,
+		router:      router,
+		silences:    silenceStore,
 		pulsar:      pulsar.NewPulsar(timePeriod, timeUnit),
 		context:     ctx,
 		consumerLog: consumerLog,
+		health:      newHealth(),
 	}
+
+	e.dispatcher = notify.NewDispatcher(e.onSendFailure, &pendingHoldStore{redisClient: redisClient})
+
+	return e, nil
 }
 
 func (e *EventConsumer) Start() {
 	e.consumerLog.Info("Starting event consumer")
 	for range e.pulsar.Pulsate() {
+		// send the grouped notifications which are due
+		e.dispatcher.Flush(e.context)
+
 		value, score, err := e.redisClient.GetItemFromPendingQueue(e.context)
+
+		// an empty queue still means that the store was read
+		e.health.loop(err == nil || errors.Is(err, porterErrors.NoPendingItemError))
+
 		if err != nil {
 			// log the error and continue
 			if !errors.Is(err, porterErrors.NoPendingItemError) {
@@ -91,89 +164,223 @@ func (e *EventConsumer) Start() {
 			continue
 		}
 
-		payload := string(value)
-		incidentID := ""
-		newIncident := false
+		e.processItem(value, score)
+	}
+}
+
+// processItem sends the notification of an item of the pending queue, in a
+// new trace linked to the trace which queued the item
+func (e *EventConsumer) processItem(value []byte, score float64) {
+	payload := string(value)
+
+	item, err := notify.NewQueueItemFromString(payload)
+	if err != nil {
+		// a malformed item can never be delivered
+		e.consumerLog.Error(err, "moving invalid item to dead-letter queue", "payload", payload)
+		e.deadLetter(value, "", deadLetterInvalid)
+		return
+	}
+
+	// items queued for the first time are scored with the time they were
+	// queued at, which retries and held items keep
+	if item.QueuedAt.IsZero() {
+		item.QueuedAt = time.Unix(int64(score), 0)
+	}
+
+	spanOpts := []trace.SpanStartOption{
+		trace.WithNewRoot(),
+		trace.WithAttributes(
+			attribute.String("incident.id", item.IncidentID),
+			attribute.String("notification.type", string(item.Type)),
+		),
+	}
+
+	if link, ok := tracing.LinkFromTraceParent(item.TraceParent); ok {
+		spanOpts = append(spanOpts, trace.WithLinks(link))
+	}
+
+	ctx, span := tracing.StartSpan(e.context, "consumer.ProcessNotification", spanOpts...)
+	defer span.End()
+
+	e.consumerLog.Info("sending notification", "payload", payload)
 
-		if strings.HasPrefix(payload, "new:") {
-			newIncident = true
-			incidentID = strings.TrimPrefix(payload, "new:")
-		} else if strings.HasPrefix(payload, "resolved:") {
-			incidentID = strings.TrimPrefix(payload, "resolved:")
+	incident, err := e.redisClient.GetIncidentDetails(ctx, item.IncidentID)
+	if err != nil {
+		e.consumerLog.Error(err, "error getting incident details for notification", "payload", payload)
+
+		if !errors.Is(err, porterErrors.IncidentNotFoundError) {
+			e.requeue(ctx, item, score)
 		}
 
-		e.consumerLog.Info("doing HTTP post", "payload", payload)
-
-		if newIncident {
-			if err = e.doHTTPPostNotifyNew(incidentID); err != nil {
-				// log error
-				e.consumerLog.Error(err, "error sending HTTP request to porter server for new incident", "payload", payload)
-
-				// requeue the object into the work queue
-				if !strings.Contains(err.Error(), "non-existent incident") {
-					err := e.redisClient.RequeueItemWithScore(e.context, value, score)
-					if err != nil {
-						// log error and continue
-						e.consumerLog.Error(err, "error requeuing item in store with score", "payload", payload)
-						continue
-					}
-				}
-			}
-		} else {
-			if err = e.doHTTPPostNotifyResolved(incidentID); err != nil {
-				// log error
-				e.consumerLog.Error(err, "error sending HTTP request to porter server for resolved incident", "payload", payload)
-
-				if !strings.Contains(err.Error(), "non-existent incident") {
-					// requeue the object into the work queue
-					err := e.redisClient.RequeueItemWithScore(e.context, value, score)
-					if err != nil {
-						// log error and continue
-						e.consumerLog.Error(err, "error requeuing item in store with score", "payload", payload)
-						continue
-					}
-				}
-			}
+		return
+	}
+
+	if silenced, err := e.isSilenced(ctx, item, incident); err != nil {
+		e.consumerLog.Error(err, "error checking silences for incident", "payload", payload)
+		e.requeue(ctx, item, score)
+		return
+	} else if silenced {
+		e.consumerLog.Info("notification suppressed by silence", "payload", payload, "silence", incident.SilencedBy)
+		return
+	}
+
+	notification := &notify.Notification{
+		Type:     item.Type,
+		Incident: incident,
+	}
+
+	if item.Type == notify.NotificationCommented {
+		notification.Comment, err = e.redisClient.GetIncidentComment(ctx, item.IncidentID, item.Ref)
+		if err != nil {
+			// the comment expired along with its incident
+			e.consumerLog.Error(err, "dropping notification for missing comment", "payload", payload)
+			return
 		}
 	}
-}
 
-func (e *EventConsumer) doHTTPPostNotifyNew(incidentID string) error {
-	e.consumerLog.Info("notify new", "incidentID", incidentID)
+	if item.Sink != "" {
+		// this is a retry for a single sink, or a notification which was held
+		// back by a dispatcher that did not send it, which is not held again
+		sink, ok := e.router.GetSink(item.Sink)
+		if !ok {
+			e.consumerLog.Info("moving item for unknown sink to dead-letter queue", "payload", payload)
+			e.deadLetter(value, item.Sink, deadLetterUnknownSink)
+			return
+		}
 
-	incident, err := e.redisClient.GetIncidentDetails(e.context, incidentID)
-	if err != nil {
-		e.consumerLog.Error(err, "error sending http request for new incident")
-		return err
+		e.consumerLog.Info("notify "+string(item.Type), "incidentID", item.IncidentID, "sink", sink.Name())
+
+		if err := notify.Send(ctx, sink, notification); err != nil {
+			e.onSendFailure(ctx, sink, []*notify.QueueItem{item}, err)
+		}
+
+		return
+	}
+
+	for _, target := range e.router.Route(incident) {
+		e.consumerLog.Info("notify "+string(item.Type), "incidentID", item.IncidentID, "sink", target.Sink.Name())
+		e.dispatcher.Dispatch(ctx, target, notification, item)
+	}
+}
+
+// isSilenced returns true if notifications for the incident should be suppressed.
+// Incidents are matched against the active silences when they are created, and
+// stay silenced until they are resolved.
+func (e *EventConsumer) isSilenced(ctx context.Context, item *notify.QueueItem, incident *models.Incident) (bool, error) {
+	if incident.Silenced {
+		return true, nil
 	}
 
-	_, err = e.httpClient.Post(fmt.Sprintf("/api/projects/%s/clusters/%s/incidents/notify_new", projectID, clusterID), incident)
+	if item.Type != notify.NotificationNew || e.silences == nil {
+		return false, nil
+	}
 
+	silence, err := e.silences.GetMatchingSilence(ctx, incident)
 	if err != nil {
-		// log and return error
-		e.consumerLog.Error(err, "error sending http request for new incident", "incidentID", incidentID)
-		return err
+		return false, err
+	} else if silence == nil {
+		return false, nil
+	}
+
+	if err := e.redisClient.SetIncidentSilenced(ctx, incident.ID, silence.Name); err != nil {
+		return false, err
 	}
 
-	return nil
+	incident.Silenced = true
+	incident.SilencedBy = silence.Name
+
+	return true, nil
 }
 
-func (e *EventConsumer) doHTTPPostNotifyResolved(incidentID string) error {
-	e.consumerLog.Info("notify resolved", "incidentID", incidentID)
+// onSendFailure requeues the items of a notification for the failed sink only,
+// so that the sinks which succeeded are not notified twice. Retries are
+// delayed by an exponential backoff and linked to the trace of the failed
+// delivery. Items which failed permanently, or too many times, are moved to
+// the dead-letter queue instead.
+func (e *EventConsumer) onSendFailure(ctx context.Context, sink notify.Sink, items []*notify.QueueItem, err error) {
+	e.consumerLog.Error(err, "error sending notification", "sink", sink.Name())
 
-	incident, err := e.redisClient.GetIncidentDetails(e.context, incidentID)
-	if err != nil {
-		e.consumerLog.Error(err, "error sending http request for new incident")
-		return err
+	permanent := notify.IsPermanentError(err)
+
+	for _, item := range items {
+		retry := item.ForSink(sink.Name())
+		retry.Attempts++
+		retry.TraceParent = tracing.TraceParent(ctx)
+
+		if permanent || retry.Attempts >= retryMaxAttempts {
+			reason := deadLetterMaxAttempts
+			if permanent {
+				reason = deadLetterPermanentError
+			}
+
+			e.consumerLog.Info("moving failed notification to dead-letter queue", "incidentID", item.IncidentID,
+				"sink", sink.Name(), "type", item.Type, "attempts", retry.Attempts, "reason", reason)
+
+			e.deadLetter([]byte(retry.ToString()), sink.Name(), reason)
+
+			continue
+		}
+
+		backoff := getRetryBackoff(retry.Attempts)
+
+		e.consumerLog.Info("requeuing failed notification", "incidentID", item.IncidentID, "sink", sink.Name(),
+			"type", item.Type, "attempts", retry.Attempts, "backoff", backoff)
+
+		e.requeue(ctx, retry, float64(time.Now().Add(backoff).Unix()))
+	}
+}
+
+// getRetryBackoff returns how long to wait before retrying a delivery which
+// failed the given number of times
+func getRetryBackoff(attempts int) time.Duration {
+	backoff := retryBackoff
+
+	for i := 1; i < attempts && backoff < retryMaxBackoff; i++ {
+		backoff *= 2
 	}
 
-	_, err = e.httpClient.Post(fmt.Sprintf("/api/projects/%s/clusters/%s/incidents/notify_resolved", projectID, clusterID), incident)
+	if backoff > retryMaxBackoff {
+		return retryMaxBackoff
+	}
+
+	return backoff
+}
 
+func (e *EventConsumer) requeue(ctx context.Context, item *notify.QueueItem, score float64) {
+	err := e.redisClient.RequeueItemWithScore(ctx, []byte(item.ToString()), score)
 	if err != nil {
-		// log and return error
-		e.consumerLog.Error(err, "error sending http request for resolved incident", "incidentID", incidentID)
-		return err
+		e.consumerLog.Error(err, "error requeuing item in store with score", "payload", item.ToString())
 	}
+}
 
-	return nil
+// pendingHoldStore holds the notifications held back by the dispatcher in the
+// pending queue, scored with the time until which they are held, so that they
+// are delivered on their own if the agent restarts before sending them
+type pendingHoldStore struct {
+	redisClient *redis.Client
+}
+
+func (s *pendingHoldStore) Hold(ctx context.Context, items []*notify.QueueItem, until time.Time) {
+	for _, item := range items {
+		if err := s.redisClient.RequeueItemWithScore(ctx, []byte(item.ToString()), float64(until.Unix())); err != nil {
+			consumerLog.Error(err, "error holding item in store", "payload", item.ToString())
+		}
+	}
+}
+
+func (s *pendingHoldStore) Release(ctx context.Context, items []*notify.QueueItem) {
+	for _, item := range items {
+		if err := s.redisClient.RemoveItemFromPendingQueue(ctx, []byte(item.ToString())); err != nil {
+			consumerLog.Error(err, "error releasing held item from store", "payload", item.ToString())
+		}
+	}
+}
+
+// deadLetter moves an item which can never be delivered to the dead-letter queue
+func (e *EventConsumer) deadLetter(value []byte, sink, reason string) {
+	metrics.NotificationsDeadLettered.WithLabelValues(sink, reason).Inc()
+
+	if err := e.redisClient.AddToDeadLetterQueue(e.context, value); err != nil {
+		e.consumerLog.Error(err, "error adding item to dead-letter queue", "payload", string(value))
+	}
 }
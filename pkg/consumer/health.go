@@ -0,0 +1,112 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/porter-dev/porter-agent/pkg/notify"
+	"github.com/spf13/viper"
+)
+
+// how long the consumer loop can go without running, or without reading the
+// store successfully, before it is reported as unhealthy
+var livenessTimeout time.Duration
+
+func init() {
+	viper.SetDefault("CONSUMER_LIVENESS_TIMEOUT", "1m")
+	viper.AutomaticEnv()
+
+	livenessTimeout = viper.GetDuration("CONSUMER_LIVENESS_TIMEOUT")
+}
+
+// how long a health check waits for the store
+const healthCheckTimeout = 2 * time.Second
+
+// health holds the times, in unix nanoseconds, at which the consumer loop last
+// ran and last read the pending queue without an error
+type health struct {
+	lastLoop    int64
+	lastSuccess int64
+}
+
+func newHealth() *health {
+	now := time.Now().UnixNano()
+
+	// the consumer gets a full timeout to start before it is unhealthy
+	return &health{
+		lastLoop:    now,
+		lastSuccess: now,
+	}
+}
+
+func (h *health) loop(success bool) {
+	now := time.Now().UnixNano()
+
+	atomic.StoreInt64(&h.lastLoop, now)
+
+	if success {
+		atomic.StoreInt64(&h.lastSuccess, now)
+	}
+}
+
+func since(unixNano *int64) time.Duration {
+	return time.Since(time.Unix(0, atomic.LoadInt64(unixNano)))
+}
+
+// LivenessCheck fails if the consumer loop is stuck. It does not depend on
+// the store, so that the agent is not restarted while Redis is unavailable.
+func (e *EventConsumer) LivenessCheck(_ *http.Request) error {
+	if elapsed := since(&e.health.lastLoop); elapsed > livenessTimeout {
+		return fmt.Errorf("event consumer loop has not run for %s", elapsed.Round(time.Second))
+	}
+
+	return nil
+}
+
+// ReadinessCheck fails if the consumer has not read the pending queue
+// successfully within the liveness timeout
+func (e *EventConsumer) ReadinessCheck(_ *http.Request) error {
+	if elapsed := since(&e.health.lastSuccess); elapsed > livenessTimeout {
+		return fmt.Errorf("event consumer has not read the pending queue for %s", elapsed.Round(time.Second))
+	}
+
+	return nil
+}
+
+// StoreCheck fails if the store cannot be reached
+func (e *EventConsumer) StoreCheck(req *http.Request) error {
+	ctx, cancel := context.WithTimeout(req.Context(), healthCheckTimeout)
+	defer cancel()
+
+	return e.redisClient.Ping(ctx)
+}
+
+// PendingNotificationAge returns how long ago the next pending notification
+// was first queued, or 0 if there is none. Retries and notifications held
+// back by the dispatcher stay in the pending queue with the time they were
+// first queued, so that a failing sink makes the age grow. It is reported as
+// a metric rather than a health check, so that a failing sink does not take
+// the agent out of service.
+func (e *EventConsumer) PendingNotificationAge(ctx context.Context) (time.Duration, error) {
+	payload, score, err := e.redisClient.GetNextPendingItem(ctx)
+	if err != nil || payload == "" {
+		return 0, err
+	}
+
+	// items queued for the first time are scored with the time they were
+	// queued at
+	queuedAt := time.Unix(int64(score), 0)
+
+	if item, err := notify.NewQueueItemFromString(payload); err == nil && !item.QueuedAt.IsZero() {
+		queuedAt = item.QueuedAt
+	}
+
+	if age := time.Since(queuedAt); age > 0 {
+		return age, nil
+	}
+
+	return 0, nil
+}
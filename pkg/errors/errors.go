@@ -3,3 +3,12 @@ package errors
 import "errors"
 
 var NoPendingItemError = errors.New("no pending item")
+
+var (
+	IncidentNotFoundError        = errors.New("incident not found")
+	IncidentAlreadyResolvedError = errors.New("incident is already resolved")
+	IncidentNotResolvedError     = errors.New("incident is not resolved")
+	ActiveIncidentExistsError    = errors.New("another incident is active for the release")
+	LogsNotFoundError            = errors.New("no such logs")
+	SnapshotNotFoundError        = errors.New("no snapshot for event")
+)
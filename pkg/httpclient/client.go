@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"net/http"
 	"time"
+
+	"github.com/porter-dev/porter-agent/pkg/signature"
 )
 
 type ClientOptions struct{}
@@ -14,15 +16,19 @@ type Client struct {
 	client *http.Client
 	token  string
 	host   string
+	signer *signature.Signer
 }
 
-func NewClient(host, token string) *Client {
+// NewClient returns a client for the given host. If signer is non-nil, every
+// POST request body is signed so that receivers can verify its authenticity.
+func NewClient(host, token string, signer *signature.Signer) *Client {
 	return &Client{
 		client: &http.Client{
 			Timeout: 3 * time.Second,
 		},
-		token: token,
-		host:  host,
+		token:  token,
+		host:   host,
+		signer: signer,
 	}
 }
 
@@ -43,8 +49,15 @@ func (c *Client) Post(path string, body interface{}) (*http.Response, error) {
 		return nil, err
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
+	if c.token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
+	}
+
 	req.Header.Set("Content-Type", "application/json")
 
+	if c.signer != nil {
+		c.signer.SignRequest(req, jsonBody)
+	}
+
 	return c.client.Do(req)
 }
@@ -0,0 +1,91 @@
+// Package metrics defines the Prometheus metrics of the agent, which are
+// served with the controller-runtime metrics on the metrics bind address.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "porter_agent"
+
+// The outcomes of reconciling a pod
+const (
+	ReconcileIgnored     = "ignored"
+	ReconcileDuplicate   = "duplicate"
+	ReconcileNewIncident = "new_incident"
+	ReconcileEventAdded  = "event_added"
+	ReconcileResolved    = "resolved"
+	ReconcileError       = "error"
+)
+
+var (
+	ReconcileOutcomes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reconcile_outcomes_total",
+		Help:      "Number of pod reconciles by outcome.",
+	}, []string{"outcome"})
+
+	EventsRecorded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_recorded_total",
+		Help:      "Number of events added to incidents by severity.",
+	}, []string{"severity"})
+
+	LogsCaptured = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logs_captured_total",
+		Help:      "Number of container logs captured for incidents.",
+	})
+
+	LogsCapturedBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logs_captured_bytes_total",
+		Help:      "Size of the container logs captured for incidents.",
+	})
+
+	LogRedactions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "log_redactions_total",
+		Help:      "Number of sensitive values redacted from captured logs and events by detector.",
+	}, []string{"detector"})
+
+	RemediationActions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "remediation_actions_total",
+		Help:      "Number of remediation actions by action and status, which is applied, dry_run or failed.",
+	}, []string{"action", "status"})
+
+	NotificationsSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_sent_total",
+		Help:      "Number of notifications sent by sink, type and result, which is success or failure.",
+	}, []string{"sink", "type", "result"})
+
+	NotificationsDeadLettered = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_dead_lettered_total",
+		Help:      "Number of notifications moved to the dead-letter queue by sink and reason.",
+	}, []string{"sink", "reason"})
+
+	NotificationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "notification_duration_seconds",
+		Help:      "Time taken to send notifications by sink.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"sink"})
+)
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(
+		ReconcileOutcomes,
+		EventsRecorded,
+		LogsCaptured,
+		LogsCapturedBytes,
+		LogRedactions,
+		RemediationActions,
+		NotificationsSent,
+		NotificationsDeadLettered,
+		NotificationDuration,
+	)
+}
@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/porter-dev/porter-agent/pkg/redis"
+	"github.com/prometheus/client_golang/prometheus"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var metricsLog = ctrl.Log.WithName("metrics")
+
+// how long a scrape waits for the store
+const storeCollectTimeout = 5 * time.Second
+
+var (
+	openIncidentsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "open_incidents"),
+		"Number of ongoing incidents by namespace, release, reason and severity.",
+		[]string{"namespace", "release", "reason", "severity"}, nil,
+	)
+
+	pendingNotificationsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "pending_notifications"),
+		"Number of notifications waiting to be sent.",
+		nil, nil,
+	)
+
+	deadLetterNotificationsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "dead_letter_notifications"),
+		"Number of notifications which could not be delivered and were moved to the dead-letter queue.",
+		nil, nil,
+	)
+
+	pendingNotificationAgeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "oldest_pending_notification_age_seconds"),
+		"Time since the next pending notification was first queued, including retries and notifications held back by grouping and rate limits.",
+		nil, nil,
+	)
+
+	storeUpDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "store_up"),
+		"Whether the incident store could be read during the last scrape.",
+		nil, nil,
+	)
+)
+
+// storeCollector reads the metrics which describe the state of the incident
+// store when it is scraped, so that they are correct across restarts
+type storeCollector struct {
+	redisClient *redis.Client
+	pendingAge  func(ctx context.Context) (time.Duration, error)
+}
+
+// RegisterStoreCollector registers the metrics read from the incident store.
+// pendingAge returns the age of the next pending notification.
+func RegisterStoreCollector(pendingAge func(ctx context.Context) (time.Duration, error)) error {
+	redisClient, err := redis.NewClientFromEnv(0)
+	if err != nil {
+		return err
+	}
+
+	return ctrlmetrics.Registry.Register(&storeCollector{
+		redisClient: redisClient,
+		pendingAge:  pendingAge,
+	})
+}
+
+func (s *storeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- openIncidentsDesc
+	ch <- pendingNotificationsDesc
+	ch <- pendingNotificationAgeDesc
+	ch <- deadLetterNotificationsDesc
+	ch <- storeUpDesc
+}
+
+func (s *storeCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeCollectTimeout)
+	defer cancel()
+
+	up := 1.0
+
+	if err := s.collect(ctx, ch); err != nil {
+		metricsLog.Error(err, "error collecting incident store metrics")
+		up = 0
+	}
+
+	ch <- prometheus.MustNewConstMetric(storeUpDesc, prometheus.GaugeValue, up)
+}
+
+func (s *storeCollector) collect(ctx context.Context, ch chan<- prometheus.Metric) error {
+	pending, err := s.redisClient.GetPendingQueueLength(ctx)
+	if err != nil {
+		return err
+	}
+
+	ch <- prometheus.MustNewConstMetric(pendingNotificationsDesc, prometheus.GaugeValue, float64(pending))
+
+	age, err := s.pendingAge(ctx)
+	if err != nil {
+		return err
+	}
+
+	ch <- prometheus.MustNewConstMetric(pendingNotificationAgeDesc, prometheus.GaugeValue, age.Seconds())
+
+	deadLetter, err := s.redisClient.GetDeadLetterQueueLength(ctx)
+	if err != nil {
+		return err
+	}
+
+	ch <- prometheus.MustNewConstMetric(deadLetterNotificationsDesc, prometheus.GaugeValue, float64(deadLetter))
+
+	counts, err := s.redisClient.CountOpenIncidents(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, count := range counts {
+		ch <- prometheus.MustNewConstMetric(openIncidentsDesc, prometheus.GaugeValue, float64(count.Count),
+			count.Namespace, count.Release, count.Reason, count.Severity)
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"time"
+
+	"github.com/porter-dev/porter-agent/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The request and response bodies of the agent API, which is described by
+// the OpenAPI document in pkg/server/openapi.
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type ErrorCode string
+
+const (
+	ErrorCodeBadRequest   ErrorCode = "bad_request"
+	ErrorCodeUnauthorized ErrorCode = "unauthorized"
+	ErrorCodeForbidden    ErrorCode = "forbidden"
+	ErrorCodeNotFound     ErrorCode = "not_found"
+	ErrorCodeConflict     ErrorCode = "conflict"
+	ErrorCodeInternal     ErrorCode = "internal"
+	ErrorCodeUnavailable  ErrorCode = "unavailable"
+)
+
+// ErrorEnvelope is the body of the error responses of the /v2 API
+type ErrorEnvelope struct {
+	Error *APIError `json:"error"`
+}
+
+type APIError struct {
+	Code      ErrorCode              `json:"code"`
+	Message   string                 `json:"message"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
+}
+
+// ListIncidentsResponse is a page of incidents. NextOffset is the offset of
+// the next page, and is unset on the last page.
+type ListIncidentsResponse struct {
+	Incidents  []*Incident `json:"incidents"`
+	Total      int         `json:"total"`
+	NextOffset *int        `json:"next_offset,omitempty"`
+}
+
+type IncidentEventsResponse struct {
+	IncidentID    string      `json:"incident_id"`
+	ReleaseName   string      `json:"release_name"`
+	Namespace     string      `json:"namespace"`
+	ChartName     string      `json:"chart_name"`
+	CreatedAt     int64       `json:"created_at"`
+	UpdatedAt     int64       `json:"updated_at"`
+	LatestState   string      `json:"latest_state"`
+	LatestReason  string      `json:"latest_reason"`
+	LatestMessage string      `json:"latest_message"`
+	Events        []*PodEvent `json:"events"`
+}
+
+type LogsResponse struct {
+	Contents string `json:"contents"`
+}
+
+type SearchLogsResponse struct {
+	Results []*LogSearchResult `json:"results"`
+
+	// Truncated is true if the search stopped reading logs before finding
+	// enough matches, in which case older matches may exist
+	Truncated bool `json:"truncated"`
+}
+
+// IncidentActionRequest is the body of incident actions. The user is only
+// used for callers authenticated with a static API token, the username of
+// other callers is taken from their identity.
+type IncidentActionRequest struct {
+	User string `json:"user"`
+}
+
+type AddCommentRequest struct {
+	User string `json:"user"`
+	Body string `json:"body" binding:"required"`
+}
+
+type ListCommentsResponse struct {
+	Comments []*IncidentComment `json:"comments"`
+}
+
+type ListRemediationsResponse struct {
+	Remediations []*Remediation `json:"remediations"`
+}
+
+type CreateSilenceRequest struct {
+	Name       string                `json:"name"`
+	Namespaces []string              `json:"namespaces"`
+	Releases   []string              `json:"releases"`
+	Reasons    []string              `json:"reasons"`
+	Selector   *metav1.LabelSelector `json:"selector"`
+	StartsAt   *time.Time            `json:"starts_at"`
+	EndsAt     *time.Time            `json:"ends_at"`
+	Schedule   string                `json:"schedule"`
+	Duration   string                `json:"duration"`
+	CreatedBy  string                `json:"created_by"`
+	Comment    string                `json:"comment" binding:"required"`
+}
+
+type SilenceResponse struct {
+	Name      string               `json:"name"`
+	CreatedAt int64                `json:"created_at"`
+	Active    bool                 `json:"active"`
+	Spec      v1alpha1.SilenceSpec `json:"spec"`
+}
+
+type ListSilencesResponse struct {
+	Silences []*SilenceResponse `json:"silences"`
+}
@@ -0,0 +1,42 @@
+package models
+
+// ArchivedIncident is an incident as it is written to the archive before it
+// expires, one per line of the incident archives
+type ArchivedIncident struct {
+	Incident     *Incident          `json:"incident"`
+	Events       []*PodEvent        `json:"events"`
+	Comments     []*IncidentComment `json:"comments"`
+	Snapshots    []*PodSnapshot     `json:"snapshots,omitempty"`
+	Remediations []*Remediation     `json:"remediations,omitempty"`
+	ArchivedAt   int64              `json:"archived_at"`
+}
+
+// ArchivedLog is a captured log as it is written to the archive before it
+// expires, one per line of the log archives
+type ArchivedLog struct {
+	LogID         string `json:"log_id"`
+	IncidentID    string `json:"incident_id"`
+	EventID       string `json:"event_id,omitempty"`
+	ContainerName string `json:"container_name,omitempty"`
+	CapturedAt    int64  `json:"captured_at"`
+	Contents      string `json:"contents"`
+	ArchivedAt    int64  `json:"archived_at"`
+}
+
+// RestoreArchiveRequest selects the archived incidents and logs to restore.
+// Since and Until are unix timestamps bounding the creation time of incidents
+// and the capture time of logs, and zero means unbounded.
+type RestoreArchiveRequest struct {
+	Namespace string `json:"namespace"`
+	Since     int64  `json:"since"`
+	Until     int64  `json:"until"`
+}
+
+// RestoreArchiveResponse counts the restored incidents and logs. Skipped
+// counts the ones which were still stored or which the caller has no access
+// to.
+type RestoreArchiveResponse struct {
+	Incidents int `json:"incidents"`
+	Logs      int `json:"logs"`
+	Skipped   int `json:"skipped"`
+}
@@ -0,0 +1,32 @@
+package models
+
+type IncidentChangeType string
+
+const (
+	IncidentChangeCreated      IncidentChangeType = "incident_created"
+	IncidentChangeEventAdded   IncidentChangeType = "event_added"
+	IncidentChangeStateChanged IncidentChangeType = "state_changed"
+	IncidentChangeResolved     IncidentChangeType = "resolved"
+)
+
+// IncidentChange is an entry of the incident change feed. The ID is assigned
+// by the store and increases with every change, so it can be used to resume
+// reading the feed.
+type IncidentChange struct {
+	ID          string             `json:"id"`
+	Type        IncidentChangeType `json:"type"`
+	IncidentID  string             `json:"incident_id"`
+	ReleaseName string             `json:"release_name"`
+	Namespace   string             `json:"namespace"`
+	Timestamp   int64              `json:"timestamp"`
+
+	// State is set for state_changed changes, to one of acknowledged,
+	// reopened, silenced, commented or remediated
+	State string `json:"state,omitempty"`
+
+	// User is the user who made the change, if it was made through the API
+	User string `json:"user,omitempty"`
+
+	// Event is set for incident_created and event_added changes
+	Event *PodEvent `json:"event,omitempty"`
+}
@@ -12,18 +12,50 @@ const (
 
 type EventCriticality string
 
+// Severity is the severity of an event or incident as decided by the pod filter
+type Severity string
+
+const (
+	SeverityCritical Severity = "critical"
+	SeverityWarning  Severity = "warning"
+	SeverityInfo     Severity = "info"
+)
+
+var severityRanks = map[Severity]int{
+	SeverityInfo:     1,
+	SeverityWarning:  2,
+	SeverityCritical: 3,
+}
+
+// Higher returns true if s is more severe than other
+func (s Severity) Higher(other Severity) bool {
+	return severityRanks[s] > severityRanks[other]
+}
+
 type ContainerEvent struct {
-	Name     string `json:"container_name"`
-	Reason   string `json:"reason"`
-	Message  string `json:"message"`
-	LogID    string `json:"log_id"`
-	ExitCode int32  `json:"exit_code"`
+	Name         string   `json:"container_name"`
+	Image        string   `json:"image"`
+	Reason       string   `json:"reason"`
+	Message      string   `json:"message"`
+	LogID        string   `json:"log_id"`
+	ExitCode     int32    `json:"exit_code"`
+	FilterReason string   `json:"filter_reason"`
+	Severity     Severity `json:"severity"`
+
+	// StackTrace is the crash extracted from the logs, if there is one
+	StackTrace *StackTrace `json:"stack_trace,omitempty"`
+
+	// Redactions is the number of sensitive values redacted from the logs
+	// and the message
+	Redactions int `json:"redactions,omitempty"`
 }
 
 type PodEvent struct {
 	EventID         string                     `json:"event_id"`
 	ChartName       string                     `json:"release_chart_name"`
 	PodName         string                     `json:"pod_name"`
+	NodeName        string                     `json:"node_name"`
+	Labels          map[string]string          `json:"pod_labels"`
 	Namespace       string                     `json:"namespace"`
 	Cluster         string                     `json:"cluster"`
 	OwnerName       string                     `json:"release_name"`
@@ -33,5 +65,49 @@ type PodEvent struct {
 	Status          string                     `json:"pod_status"`
 	Reason          string                     `json:"reason"`
 	Message         string                     `json:"message"`
+	FilterReason    string                     `json:"filter_reason"`
+	Severity        Severity                   `json:"severity"`
 	ContainerEvents map[string]*ContainerEvent `json:"container_events"`
+
+	// StackTrace is the crash of the first container with one
+	StackTrace *StackTrace `json:"stack_trace,omitempty"`
+
+	// Revision is the revision of the workload the pod was running
+	Revision *Revision `json:"revision,omitempty"`
+
+	// Rollout is only set on the first event of incidents which started
+	// shortly after a rollout
+	Rollout *Rollout `json:"rollout,omitempty"`
+
+	// Redactions is the number of sensitive values redacted from the event,
+	// the logs of its containers and the snapshot of its pod
+	Redactions int `json:"redactions,omitempty"`
+}
+
+// StackTrace is the error and stack trace of a crash, extracted from the logs
+// of a container
+type StackTrace struct {
+	// Runtime is one of go, python, node or java
+	Runtime string `json:"runtime"`
+
+	// Type is the type of the error, such as panic or ValueError
+	Type    string `json:"type"`
+	Message string `json:"message"`
+
+	// Frames are the frames of the crashing thread, innermost first
+	Frames []*StackFrame `json:"frames"`
+
+	// Summary is the error on a single line, such as "panic: runtime error:
+	// index out of range [5] with length 3"
+	Summary string `json:"summary"`
+
+	// Fingerprint is the same for crashes of the same error type in the same
+	// functions, whatever their message and line numbers
+	Fingerprint string `json:"fingerprint"`
+}
+
+type StackFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
 }
@@ -1,12 +1,44 @@
 package models
 
 type Incident struct {
-	ID            string `json:"id" form:"required"`
-	ReleaseName   string `json:"release_name" form:"required"`
-	ChartName     string `json:"chart_name"`
-	CreatedAt     int64  `json:"created_at" form:"required"`
-	UpdatedAt     int64  `json:"updated_at" form:"required"`
-	LatestState   string `json:"latest_state" form:"required"`
-	LatestReason  string `json:"latest_reason" form:"required"`
-	LatestMessage string `json:"latest_message" form:"required"`
+	ID                 string            `json:"id" form:"required"`
+	ReleaseName        string            `json:"release_name" form:"required"`
+	ChartName          string            `json:"chart_name"`
+	Namespace          string            `json:"namespace"`
+	OwnerKind          string            `json:"release_type"`
+	Severity           Severity          `json:"severity"`
+	LatestFilterReason string            `json:"latest_filter_reason"`
+	NodeName           string            `json:"node_name"`
+	Images             []string          `json:"images"`
+	Labels             map[string]string `json:"labels"`
+	Silenced           bool              `json:"silenced"`
+	SilencedBy         string            `json:"silenced_by,omitempty"`
+	Acknowledged       bool              `json:"acknowledged"`
+	AcknowledgedBy     string            `json:"acknowledged_by,omitempty"`
+	AcknowledgedAt     int64             `json:"acknowledged_at,omitempty"`
+	ResolvedBy         string            `json:"resolved_by,omitempty"`
+	CreatedAt          int64             `json:"created_at" form:"required"`
+	UpdatedAt          int64             `json:"updated_at" form:"required"`
+	LatestState        string            `json:"latest_state" form:"required"`
+	LatestReason       string            `json:"latest_reason" form:"required"`
+	LatestMessage      string            `json:"latest_message" form:"required"`
+	Fingerprint        string            `json:"fingerprint,omitempty"`
+
+	// AfterRollout is true if the incident started shortly after a rollout,
+	// which is described by its first event
+	AfterRollout bool `json:"after_rollout"`
+}
+
+// IncidentAcknowledgement records who acknowledged an incident and when
+type IncidentAcknowledgement struct {
+	User      string `json:"user"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+type IncidentComment struct {
+	ID         string `json:"id"`
+	IncidentID string `json:"incident_id"`
+	User       string `json:"user"`
+	Body       string `json:"body"`
+	Timestamp  int64  `json:"timestamp"`
 }
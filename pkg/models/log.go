@@ -0,0 +1,22 @@
+package models
+
+// LogSearchResult is a captured log which matches a search, with the lines
+// that matched
+type LogSearchResult struct {
+	LogID       string          `json:"log_id"`
+	IncidentID  string          `json:"incident_id"`
+	EventID     string          `json:"event_id,omitempty"`
+	Container   string          `json:"container_name,omitempty"`
+	ReleaseName string          `json:"release_name"`
+	Namespace   string          `json:"namespace"`
+	Timestamp   int64           `json:"timestamp"`
+	Matches     []*LogLineMatch `json:"matches"`
+}
+
+// LogLineMatch is a matching line of a log. Highlighted is the HTML-escaped
+// line with the matches wrapped in <mark> tags.
+type LogLineMatch struct {
+	LineNumber  int    `json:"line_number"`
+	Line        string `json:"line"`
+	Highlighted string `json:"highlighted"`
+}
@@ -0,0 +1,39 @@
+package models
+
+type RemediationStatus string
+
+const (
+	RemediationApplied RemediationStatus = "applied"
+	RemediationDryRun  RemediationStatus = "dry_run"
+	RemediationFailed  RemediationStatus = "failed"
+)
+
+// Remediation is an action which the agent took, or would have taken in
+// dry-run mode, to remediate an incident
+type Remediation struct {
+	ID         string `json:"id"`
+	IncidentID string `json:"incident_id"`
+	EventID    string `json:"event_id"`
+
+	// Action is one of rollback, increase-memory or delete-stuck-pod
+	Action string `json:"action"`
+
+	// Target is the object the action was taken on, as deployment/<name> or
+	// pod/<name>, and Container is set for actions on a single container
+	Target    string `json:"target"`
+	Container string `json:"container,omitempty"`
+
+	Status RemediationStatus `json:"status"`
+
+	// Reason is why the action was taken
+	Reason string `json:"reason"`
+
+	// Changes are the changes made to the target, such as the revision a
+	// deployment was rolled back to or the new memory limit of a container
+	Changes []*RevisionChange `json:"changes,omitempty"`
+
+	// Error is set when the action failed
+	Error string `json:"error,omitempty"`
+
+	Timestamp int64 `json:"timestamp"`
+}
@@ -0,0 +1,44 @@
+package models
+
+// Revision is the revision of the workload which a pod was running when an
+// event was recorded
+type Revision struct {
+	// Revision is the deployment.kubernetes.io/revision of the ReplicaSet of
+	// the pod, and is only set for pods of deployments
+	Revision        string `json:"revision,omitempty"`
+	ReplicaSet      string `json:"replica_set,omitempty"`
+	PodTemplateHash string `json:"pod_template_hash,omitempty"`
+
+	// Images are the images of the containers of the pod by container name
+	Images map[string]string `json:"images"`
+
+	// Chart is the helm.sh/chart label of the pod or of its owner
+	Chart string `json:"chart,omitempty"`
+
+	// RolledOutAt is when the ReplicaSet of the pod was created
+	RolledOutAt int64 `json:"rolled_out_at,omitempty"`
+}
+
+// Rollout is the rollout an incident started shortly after, along with what
+// changed from the previous revision
+type Rollout struct {
+	Revision         string `json:"revision"`
+	PreviousRevision string `json:"previous_revision,omitempty"`
+	RolledOutAt      int64  `json:"rolled_out_at"`
+
+	// Changes are the differences between the pod templates of the previous
+	// and the failing revision, which are empty if there is no previous
+	// revision
+	Changes []*RevisionChange `json:"changes"`
+}
+
+// RevisionChange is a difference between the pod templates of two revisions.
+// Field is one of chart, container, image, env, or requests.<resource> and
+// limits.<resource>. Added containers and env vars only have a current value,
+// and removed ones only a previous value. Env vars are compared by name only.
+type RevisionChange struct {
+	Container string `json:"container,omitempty"`
+	Field     string `json:"field"`
+	Previous  string `json:"previous,omitempty"`
+	Current   string `json:"current,omitempty"`
+}
@@ -0,0 +1,91 @@
+package models
+
+// PodSnapshot is the state of a pod and of its node when an event was
+// recorded, kept so that the pod can be looked into after it is gone. Env
+// values, secrets and the commands of containers are left out, and messages
+// are redacted.
+type PodSnapshot struct {
+	EventID        string               `json:"event_id"`
+	CapturedAt     int64                `json:"captured_at"`
+	PodName        string               `json:"pod_name"`
+	Namespace      string               `json:"namespace"`
+	Labels         map[string]string    `json:"labels"`
+	NodeName       string               `json:"node_name"`
+	Phase          string               `json:"phase"`
+	QOSClass       string               `json:"qos_class"`
+	Reason         string               `json:"reason,omitempty"`
+	Message        string               `json:"message,omitempty"`
+	StartTime      int64                `json:"start_time,omitempty"`
+	Conditions     []*Condition         `json:"conditions"`
+	InitContainers []*ContainerSnapshot `json:"init_containers,omitempty"`
+	Containers     []*ContainerSnapshot `json:"containers"`
+
+	// Events are the latest Kubernetes events of the pod, oldest first
+	Events []*KubernetesEvent `json:"events"`
+
+	// Node is unset if the pod was not scheduled or the node could not be
+	// read
+	Node *NodeSnapshot `json:"node,omitempty"`
+}
+
+type ContainerSnapshot struct {
+	Name     string            `json:"name"`
+	Image    string            `json:"image"`
+	ImageID  string            `json:"image_id,omitempty"`
+	Ports    []string          `json:"ports,omitempty"`
+	Requests map[string]string `json:"requests,omitempty"`
+	Limits   map[string]string `json:"limits,omitempty"`
+
+	// probes are described like kubectl describe does, such as
+	// "http-get http://:8080/healthz delay=0s timeout=1s period=10s #success=1 #failure=3"
+	LivenessProbe  string `json:"liveness_probe,omitempty"`
+	ReadinessProbe string `json:"readiness_probe,omitempty"`
+	StartupProbe   string `json:"startup_probe,omitempty"`
+
+	// Env holds the names of the environment variables, without their values
+	Env []string `json:"env,omitempty"`
+
+	State        *ContainerState `json:"state,omitempty"`
+	LastState    *ContainerState `json:"last_state,omitempty"`
+	Ready        bool            `json:"ready"`
+	RestartCount int32           `json:"restart_count"`
+}
+
+type ContainerState struct {
+	// State is one of waiting, running or terminated
+	State      string `json:"state"`
+	Reason     string `json:"reason,omitempty"`
+	Message    string `json:"message,omitempty"`
+	ExitCode   int32  `json:"exit_code,omitempty"`
+	Signal     int32  `json:"signal,omitempty"`
+	StartedAt  int64  `json:"started_at,omitempty"`
+	FinishedAt int64  `json:"finished_at,omitempty"`
+}
+
+type Condition struct {
+	Type               string `json:"type"`
+	Status             string `json:"status"`
+	Reason             string `json:"reason,omitempty"`
+	Message            string `json:"message,omitempty"`
+	LastTransitionTime int64  `json:"last_transition_time,omitempty"`
+}
+
+// KubernetesEvent is an event recorded by Kubernetes for a pod
+type KubernetesEvent struct {
+	Type           string `json:"type"`
+	Reason         string `json:"reason"`
+	Message        string `json:"message"`
+	Source         string `json:"source"`
+	Count          int32  `json:"count"`
+	FirstTimestamp int64  `json:"first_timestamp"`
+	LastTimestamp  int64  `json:"last_timestamp"`
+}
+
+type NodeSnapshot struct {
+	Name          string            `json:"name"`
+	Unschedulable bool              `json:"unschedulable"`
+	Taints        []string          `json:"taints,omitempty"`
+	Capacity      map[string]string `json:"capacity,omitempty"`
+	Allocatable   map[string]string `json:"allocatable,omitempty"`
+	Conditions    []*Condition      `json:"conditions"`
+}
@@ -0,0 +1,148 @@
+package notify
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+
+	"github.com/porter-dev/porter-agent/pkg/models"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+var sinkNameRegex = regexp.MustCompile("^[a-zA-Z0-9_-]+$")
+
+// Config is the notification routing configuration. An example:
+//
+//	receivers:
+//	  - name: staging-webhook
+//	    type: webhook
+//	    url: https://hooks.example.com/staging
+//	  - name: pagerduty
+//	    type: pagerduty
+//	    routing_key: <integration key>
+//	routes:
+//	  - match:
+//	      namespaces: ["staging-*"]
+//	    receivers: [staging-webhook]
+//	  - match:
+//	      namespaces: ["prod-*"]
+//	      reasons: [OOMKilled]
+//	    receivers: [pagerduty]
+//	    continue: true
+//	default:
+//	  receivers: [porter]
+//	  grouping:
+//	    by: namespace
+//	    window: 30s
+//	    bypass_severities: [critical]
+//	  rate_limit:
+//	    max: 10
+//	    interval: 1m
+//
+// The "porter" receiver always exists and points to the Porter API.
+type Config struct {
+	Receivers []*ReceiverConfig `json:"receivers"`
+	Routes    []*Route          `json:"routes"`
+	Default   *Route            `json:"default"`
+}
+
+type ReceiverConfig struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+
+	// URL is the target of webhook receivers
+	URL string `json:"url"`
+
+	// SigningSecrets override the global signing secrets for webhook receivers
+	SigningSecrets []string `json:"signing_secrets"`
+
+	// RoutingKey is the integration key of pagerduty receivers
+	RoutingKey string `json:"routing_key"`
+}
+
+// Route sends the incidents matched by Match to each of its receivers. Routes
+// are evaluated in order and evaluation stops at the first matching route,
+// unless Continue is set on it.
+type Route struct {
+	Match     Matcher          `json:"match"`
+	Receivers []string         `json:"receivers"`
+	Continue  bool             `json:"continue"`
+	Grouping  *GroupingConfig  `json:"grouping"`
+	RateLimit *RateLimitConfig `json:"rate_limit"`
+}
+
+const (
+	GroupByNamespace = "namespace"
+	GroupByImage     = "image"
+	GroupByNode      = "node"
+)
+
+// GroupingConfig holds back the notifications of a route for Window and sends
+// the ones sharing the same namespace, image or node as a single digest.
+// Notifications with one of the BypassSeverities are sent right away.
+type GroupingConfig struct {
+	By               string            `json:"by"`
+	Window           metav1.Duration   `json:"window"`
+	BypassSeverities []models.Severity `json:"bypass_severities"`
+}
+
+// RateLimitConfig allows at most Max deliveries per Interval for each receiver
+// of a route. Notifications exceeding the limit are not dropped, they are held
+// back and sent as a digest once the limit allows it.
+type RateLimitConfig struct {
+	Max      int             `json:"max"`
+	Interval metav1.Duration `json:"interval"`
+}
+
+func (r *Route) validate() error {
+	if r.Grouping != nil {
+		switch r.Grouping.By {
+		case GroupByNamespace, GroupByImage, GroupByNode:
+		default:
+			return fmt.Errorf("invalid grouping %q, must be one of namespace, image or node", r.Grouping.By)
+		}
+
+		if r.Grouping.Window.Duration <= 0 {
+			return fmt.Errorf("grouping window must be positive")
+		}
+	}
+
+	if r.RateLimit != nil && (r.RateLimit.Max <= 0 || r.RateLimit.Interval.Duration <= 0) {
+		return fmt.Errorf("rate limit max and interval must be positive")
+	}
+
+	return nil
+}
+
+// Matcher matches incidents on each of its non-empty fields. Namespaces and
+// releases accept glob patterns such as "prod-*".
+type Matcher struct {
+	Namespaces []string          `json:"namespaces"`
+	Releases   []string          `json:"releases"`
+	OwnerKinds []string          `json:"owner_kinds"`
+	Severities []models.Severity `json:"severities"`
+	Reasons    []string          `json:"reasons"`
+}
+
+// LoadConfig reads the routing configuration from a YAML or JSON file. An
+// empty path returns an empty configuration which routes everything to the
+// Porter API.
+func LoadConfig(path string) (*Config, error) {
+	config := &Config{}
+
+	if path == "" {
+		return config, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading notification config file %s. Error: %w", path, err)
+	}
+
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("error parsing notification config file %s. Error: %w", path, err)
+	}
+
+	return config, nil
+}
@@ -0,0 +1,479 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Dispatcher delivers notifications to their targets while applying the
+// grouping and rate limits of the route which selected each target. Held back
+// notifications are only sent by Flush, which should be called periodically.
+type Dispatcher struct {
+	// onFailure is called with the queue items of every notification that a
+	// sink failed to accept, along with the context of the failed delivery
+	onFailure func(ctx context.Context, sink Sink, items []*QueueItem, err error)
+	holds     HoldStore
+	now       func() time.Time
+
+	mu       sync.Mutex
+	groups   map[groupKey]*group
+	limiters map[limiterKey]*rateLimiter
+}
+
+type groupKey struct {
+	route    *Route
+	sink     string
+	notifTyp NotificationType
+	by       string
+	value    string
+}
+
+// HoldStore keeps the notifications held back by a dispatcher until they are
+// sent, so that they are not lost along with the dispatcher. Held items are
+// for a single sink, and should be delivered on their own once they are no
+// longer held, which only happens when the dispatcher did not send them.
+type HoldStore interface {
+	Hold(ctx context.Context, items []*QueueItem, until time.Time)
+	Release(ctx context.Context, items []*QueueItem)
+}
+
+// held notifications are kept for this long after they should have been sent,
+// so that a running dispatcher always sends them first
+const holdGrace = time.Minute
+
+type group struct {
+	key       groupKey
+	target    *Target
+	incidents []*models.Incident
+	flushAt   time.Time
+
+	// the queue items of the incidents, and until when they are held
+	items     []*QueueItem
+	heldUntil time.Time
+
+	// links to the spans which dispatched the notifications of the group
+	links []trace.Link
+}
+
+type limiterKey struct {
+	route *Route
+	sink  string
+}
+
+// groupByOverflow is used for the notifications held back by a rate limit
+const groupByOverflow = "overflow"
+
+// heldItems are queue items to hold until a time
+type heldItems struct {
+	items []*QueueItem
+	until time.Time
+}
+
+func NewDispatcher(
+	onFailure func(ctx context.Context, sink Sink, items []*QueueItem, err error),
+	holds HoldStore,
+) *Dispatcher {
+	return &Dispatcher{
+		onFailure: onFailure,
+		holds:     holds,
+		now:       time.Now,
+		groups:    make(map[groupKey]*group),
+		limiters:  make(map[limiterKey]*rateLimiter),
+	}
+}
+
+// Dispatch sends the notification of a queue item to the target right away,
+// or holds it back until the next Flush after its group window ends or its
+// rate limit allows it.
+func (d *Dispatcher) Dispatch(ctx context.Context, target *Target, notification *Notification, item *QueueItem) {
+	route := target.Route
+	incident := notification.Incident
+	item = item.ForSink(target.Sink.Name())
+
+	// only new and resolved incidents are grouped, other state changes are
+	// the result of a user action and are sent right away
+	groupable := notification.Type == NotificationNew || notification.Type == NotificationResolved
+
+	if !groupable || (route.Grouping == nil && route.RateLimit == nil) {
+		d.send(ctx, target.Sink, notification, []*QueueItem{item})
+		return
+	}
+
+	if route.Grouping != nil {
+		for _, severity := range route.Grouping.BypassSeverities {
+			if severity == incident.Severity {
+				d.send(ctx, target.Sink, notification, []*QueueItem{item})
+				return
+			}
+		}
+	}
+
+	key := groupKey{
+		route:    route,
+		sink:     target.Sink.Name(),
+		notifTyp: notification.Type,
+	}
+
+	window := time.Duration(0)
+
+	if route.Grouping != nil {
+		key.by = route.Grouping.By
+		key.value = getGroupValue(route.Grouping.By, incident)
+		window = route.Grouping.Window.Duration
+	}
+
+	if key.value == "" {
+		// the incident cannot be grouped with others so it gets its own group,
+		// which is still subject to the grouping window and rate limit
+		key.by = ""
+		key.value = incident.ID
+	}
+
+	d.mu.Lock()
+
+	g, ok := d.groups[key]
+	if !ok {
+		g = &group{
+			key:     key,
+			target:  target,
+			flushAt: d.now().Add(window),
+		}
+
+		g.heldUntil = g.flushAt
+		d.groups[key] = g
+	}
+
+	added := g.add(incident, item)
+	heldUntil := g.heldUntil
+
+	if link, ok := tracing.LinkFromContext(ctx); ok {
+		g.links = append(g.links, link)
+	}
+
+	d.mu.Unlock()
+
+	if added {
+		d.hold(ctx, &heldItems{items: []*QueueItem{item}, until: heldUntil})
+	}
+}
+
+// Flush sends the groups whose window has ended, as long as their rate limit
+// allows it. Every notification sent counts against the limit, so a group sent
+// to a sink without digests takes one per incident. Groups blocked by a rate
+// limit are merged into a single digest which is sent once the limit allows it
+// again.
+func (d *Dispatcher) Flush(ctx context.Context) {
+	now := d.now()
+
+	var due []*group
+	var holds []*heldItems
+
+	d.mu.Lock()
+
+	// flush overflow groups first so that the oldest notifications go out first
+	keys := make([]groupKey, 0, len(d.groups))
+	for key := range d.groups {
+		keys = append(keys, key)
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].by == groupByOverflow && keys[j].by != groupByOverflow
+	})
+
+	for _, key := range keys {
+		g, ok := d.groups[key]
+		if !ok || now.Before(g.flushAt) {
+			continue
+		}
+
+		if limit := g.target.Route.RateLimit; limit != nil {
+			lk := limiterKey{route: key.route, sink: key.sink}
+
+			limiter, ok := d.limiters[lk]
+			if !ok {
+				limiter = &rateLimiter{max: limit.Max, interval: limit.Interval.Duration}
+				d.limiters[lk] = limiter
+			}
+
+			// sinks without digests are sent a notification per incident of
+			// the group, and each of them counts against the limit
+			sends := g.sends()
+
+			allowed := limiter.take(now, sends)
+			if allowed == 0 {
+				holds = append(holds, d.holdBack(g, now, limiter.releaseAt())...)
+				continue
+			}
+
+			if allowed < sends {
+				rest := g.split(allowed)
+
+				delete(d.groups, key)
+				due = append(due, g)
+
+				if key.by == groupByOverflow {
+					d.groups[key] = rest
+				}
+
+				holds = append(holds, d.holdBack(rest, now, limiter.releaseAt())...)
+
+				continue
+			}
+		}
+
+		delete(d.groups, key)
+		due = append(due, g)
+	}
+
+	d.mu.Unlock()
+
+	for _, held := range holds {
+		d.hold(ctx, held)
+	}
+
+	for _, g := range due {
+		d.sendGroup(ctx, g)
+
+		if d.holds != nil {
+			d.holds.Release(ctx, g.items)
+		}
+	}
+}
+
+// holdBack moves a group blocked by its rate limit to the overflow group of its
+// route, and returns the items which should be held until the limit releases
+func (d *Dispatcher) holdBack(g *group, now, releaseAt time.Time) []*heldItems {
+	var holds []*heldItems
+
+	overflow := g
+
+	if g.key.by != groupByOverflow {
+		overflow = d.moveToOverflow(g, now)
+
+		if !overflow.heldUntil.Before(releaseAt) {
+			return []*heldItems{{items: g.items, until: overflow.heldUntil}}
+		}
+	}
+
+	if overflow.heldUntil.Before(releaseAt) {
+		overflow.heldUntil = releaseAt
+		holds = append(holds, &heldItems{items: append([]*QueueItem{}, overflow.items...), until: releaseAt})
+	}
+
+	return holds
+}
+
+func (d *Dispatcher) moveToOverflow(g *group, now time.Time) *group {
+	delete(d.groups, g.key)
+
+	key := groupKey{
+		route:    g.key.route,
+		sink:     g.key.sink,
+		notifTyp: g.key.notifTyp,
+		by:       groupByOverflow,
+	}
+
+	overflow, ok := d.groups[key]
+	if !ok {
+		overflow = &group{
+			key:     key,
+			target:  g.target,
+			flushAt: now,
+		}
+
+		d.groups[key] = overflow
+	}
+
+	for i, incident := range g.incidents {
+		overflow.add(incident, g.items[i])
+	}
+
+	overflow.links = append(overflow.links, g.links...)
+
+	return overflow
+}
+
+func (d *Dispatcher) sendGroup(ctx context.Context, g *group) {
+	// the group is sent in a new trace, linked to the traces which
+	// dispatched its notifications
+	ctx, span := tracing.StartSpan(ctx, "notify.SendGroup",
+		trace.WithNewRoot(),
+		trace.WithLinks(g.links...),
+		trace.WithAttributes(
+			attribute.String("sink", g.key.sink),
+			attribute.String("notification.type", string(g.key.notifTyp)),
+			attribute.Int("incidents", len(g.incidents)),
+		),
+	)
+	defer span.End()
+
+	if len(g.incidents) == 1 {
+		d.send(ctx, g.target.Sink, &Notification{
+			Type:     g.key.notifTyp,
+			Incident: g.incidents[0],
+		}, g.items)
+
+		return
+	}
+
+	if g.digest() {
+		d.send(ctx, g.target.Sink, &Notification{
+			Type:      g.key.notifTyp,
+			Digest:    true,
+			Summary:   g.summary(),
+			Incidents: g.incidents,
+		}, g.items)
+
+		return
+	}
+
+	for i, incident := range g.incidents {
+		d.send(ctx, g.target.Sink, &Notification{
+			Type:     g.key.notifTyp,
+			Incident: incident,
+		}, g.items[i:i+1])
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, sink Sink, notification *Notification, items []*QueueItem) {
+	if err := Send(ctx, sink, notification); err != nil && d.onFailure != nil {
+		d.onFailure(ctx, sink, items, err)
+	}
+}
+
+func (d *Dispatcher) hold(ctx context.Context, held *heldItems) {
+	if d.holds != nil {
+		d.holds.Hold(ctx, held.items, held.until.Add(holdGrace))
+	}
+}
+
+// add adds an incident to the group along with its queue item, and returns
+// false if the incident was already in the group
+func (g *group) add(incident *models.Incident, item *QueueItem) bool {
+	for _, existing := range g.incidents {
+		if existing.ID == incident.ID {
+			return false
+		}
+	}
+
+	g.incidents = append(g.incidents, incident)
+	g.items = append(g.items, item)
+
+	return true
+}
+
+// digest returns whether the incidents of the group are sent as a digest
+func (g *group) digest() bool {
+	digestSink, ok := g.target.Sink.(DigestSink)
+	return ok && digestSink.SupportsDigest()
+}
+
+// sends returns the number of notifications sending the group takes
+func (g *group) sends() int {
+	if len(g.incidents) == 1 || g.digest() {
+		return 1
+	}
+
+	return len(g.incidents)
+}
+
+// split keeps the first n incidents in the group, and returns a group with the
+// same key holding the rest of them
+func (g *group) split(n int) *group {
+	rest := &group{
+		key:       g.key,
+		target:    g.target,
+		incidents: g.incidents[n:],
+		flushAt:   g.flushAt,
+		items:     g.items[n:],
+		heldUntil: g.heldUntil,
+		links:     append([]trace.Link{}, g.links...),
+	}
+
+	g.incidents = g.incidents[:n:n]
+	g.items = g.items[:n:n]
+
+	return rest
+}
+
+// summary returns a human readable summary such as "12 new incidents in namespace prod"
+func (g *group) summary() string {
+	var summary string
+
+	if g.key.notifTyp == NotificationResolved {
+		summary = fmt.Sprintf("%d incidents resolved", len(g.incidents))
+	} else {
+		summary = fmt.Sprintf("%d new incidents", len(g.incidents))
+	}
+
+	switch g.key.by {
+	case GroupByNamespace:
+		summary += fmt.Sprintf(" in namespace %s", g.key.value)
+	case GroupByImage:
+		summary += fmt.Sprintf(" with image %s", g.key.value)
+	case GroupByNode:
+		summary += fmt.Sprintf(" on node %s", g.key.value)
+	}
+
+	return summary
+}
+
+func getGroupValue(by string, incident *models.Incident) string {
+	switch by {
+	case GroupByNamespace:
+		return incident.Namespace
+	case GroupByImage:
+		if len(incident.Images) > 0 {
+			return incident.Images[0]
+		}
+	case GroupByNode:
+		return incident.NodeName
+	}
+
+	return ""
+}
+
+// rateLimiter is a sliding window limiter allowing max events per interval
+type rateLimiter struct {
+	max      int
+	interval time.Duration
+	sent     []time.Time
+}
+
+func (l *rateLimiter) allow(now time.Time) bool {
+	return l.take(now, 1) == 1
+}
+
+// take takes up to n events from the limiter, and returns how many it allowed
+func (l *rateLimiter) take(now time.Time, n int) int {
+	cutoff := now.Add(-l.interval)
+
+	i := 0
+	for i < len(l.sent) && !l.sent[i].After(cutoff) {
+		i++
+	}
+
+	l.sent = l.sent[i:]
+
+	allowed := 0
+	for allowed < n && len(l.sent) < l.max {
+		l.sent = append(l.sent, now)
+		allowed++
+	}
+
+	return allowed
+}
+
+// releaseAt returns when a limiter which did not allow an event allows the
+// next one
+func (l *rateLimiter) releaseAt() time.Time {
+	return l.sent[0].Add(l.interval)
+}
@@ -0,0 +1,354 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/porter-dev/porter-agent/pkg/models"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type fakeSink struct {
+	name          string
+	digest        bool
+	err           error
+	notifications []*Notification
+}
+
+func (s *fakeSink) Name() string {
+	return s.name
+}
+
+func (s *fakeSink) Send(notification *Notification) error {
+	s.notifications = append(s.notifications, notification)
+	return s.err
+}
+
+func (s *fakeSink) SupportsDigest() bool {
+	return s.digest
+}
+
+// sent returns the incident IDs of each notification sent to the sink
+func (s *fakeSink) sent() [][]string {
+	var sent [][]string
+
+	for _, notification := range s.notifications {
+		var ids []string
+
+		if notification.Digest {
+			for _, incident := range notification.Incidents {
+				ids = append(ids, incident.ID)
+			}
+		} else {
+			ids = append(ids, notification.Incident.ID)
+		}
+
+		sent = append(sent, ids)
+	}
+
+	return sent
+}
+
+type fakeHoldStore struct {
+	held     map[string]time.Time
+	released []string
+}
+
+func (s *fakeHoldStore) Hold(_ context.Context, items []*QueueItem, until time.Time) {
+	for _, item := range items {
+		s.held[item.ToString()] = until
+	}
+}
+
+func (s *fakeHoldStore) Release(_ context.Context, items []*QueueItem) {
+	for _, item := range items {
+		delete(s.held, item.ToString())
+		s.released = append(s.released, item.ToString())
+	}
+}
+
+type testDispatcher struct {
+	*Dispatcher
+	now   time.Time
+	holds *fakeHoldStore
+}
+
+func newTestDispatcher(onFailure func(ctx context.Context, sink Sink, items []*QueueItem, err error)) *testDispatcher {
+	holds := &fakeHoldStore{held: make(map[string]time.Time)}
+
+	d := &testDispatcher{
+		Dispatcher: NewDispatcher(onFailure, holds),
+		now:        time.Unix(1700000000, 0),
+		holds:      holds,
+	}
+
+	d.Dispatcher.now = func() time.Time { return d.now }
+
+	return d
+}
+
+func (d *testDispatcher) dispatch(target *Target, typ NotificationType, incident *models.Incident) {
+	d.Dispatch(context.Background(), target, &Notification{Type: typ, Incident: incident}, &QueueItem{
+		Type:       typ,
+		IncidentID: incident.ID,
+	})
+}
+
+func (d *testDispatcher) flushAt(offset time.Duration) {
+	d.now = time.Unix(1700000000, 0).Add(offset)
+	d.Flush(context.Background())
+}
+
+func TestDispatcherGrouping(t *testing.T) {
+	sink := &fakeSink{name: "hook", digest: true}
+	target := &Target{
+		Sink: sink,
+		Route: &Route{Grouping: &GroupingConfig{
+			By:               GroupByNamespace,
+			Window:           metav1.Duration{Duration: 30 * time.Second},
+			BypassSeverities: []models.Severity{models.SeverityCritical},
+		}},
+	}
+
+	d := newTestDispatcher(nil)
+	start := d.now
+
+	d.dispatch(target, NotificationNew, &models.Incident{ID: "a", Namespace: "prod"})
+	d.dispatch(target, NotificationNew, &models.Incident{ID: "b", Namespace: "prod"})
+	d.dispatch(target, NotificationNew, &models.Incident{ID: "b", Namespace: "prod"})
+	d.dispatch(target, NotificationNew, &models.Incident{ID: "c", Namespace: "staging"})
+	d.dispatch(target, NotificationResolved, &models.Incident{ID: "d", Namespace: "prod"})
+
+	// critical incidents and other state changes are not held back
+	d.dispatch(target, NotificationNew, &models.Incident{ID: "e", Namespace: "prod", Severity: models.SeverityCritical})
+	d.dispatch(target, NotificationAcknowledged, &models.Incident{ID: "f", Namespace: "prod"})
+
+	if want := [][]string{{"e"}, {"f"}}; !reflect.DeepEqual(sink.sent(), want) {
+		t.Fatalf("expected %v to be sent right away, got %v", want, sink.sent())
+	}
+
+	wantHeld := map[string]time.Time{
+		"hook@new:a":      start.Add(30*time.Second + holdGrace),
+		"hook@new:b":      start.Add(30*time.Second + holdGrace),
+		"hook@new:c":      start.Add(30*time.Second + holdGrace),
+		"hook@resolved:d": start.Add(30*time.Second + holdGrace),
+	}
+
+	if !reflect.DeepEqual(d.holds.held, wantHeld) {
+		t.Errorf("expected held items %v, got %v", wantHeld, d.holds.held)
+	}
+
+	d.flushAt(29 * time.Second)
+
+	if len(sink.notifications) != 2 {
+		t.Fatalf("expected nothing to be sent before the end of the window, got %v", sink.sent())
+	}
+
+	d.flushAt(30 * time.Second)
+
+	sent := sink.sent()[2:]
+	if len(sent) != 3 {
+		t.Fatalf("expected 3 notifications at the end of the window, got %v", sent)
+	}
+
+	for _, want := range [][]string{{"a", "b"}, {"c"}, {"d"}} {
+		found := false
+
+		for _, ids := range sent {
+			found = found || reflect.DeepEqual(ids, want)
+		}
+
+		if !found {
+			t.Errorf("expected %v to be sent together, got %v", want, sent)
+		}
+	}
+
+	for _, notification := range sink.notifications[2:] {
+		if notification.Digest && notification.Summary != "2 new incidents in namespace prod" {
+			t.Errorf("unexpected digest summary %q", notification.Summary)
+		}
+	}
+
+	if len(d.holds.held) != 0 || len(d.holds.released) != 4 {
+		t.Errorf("expected every held item to be released, %d are still held", len(d.holds.held))
+	}
+}
+
+func TestDispatcherGroupingWithoutDigests(t *testing.T) {
+	sink := &fakeSink{name: "porter"}
+	target := &Target{
+		Sink: sink,
+		Route: &Route{Grouping: &GroupingConfig{
+			By:     GroupByNode,
+			Window: metav1.Duration{Duration: time.Minute},
+		}},
+	}
+
+	d := newTestDispatcher(nil)
+
+	d.dispatch(target, NotificationNew, &models.Incident{ID: "a", NodeName: "node-1"})
+	d.dispatch(target, NotificationNew, &models.Incident{ID: "b", NodeName: "node-1"})
+	d.flushAt(time.Minute)
+
+	if want := [][]string{{"a"}, {"b"}}; !reflect.DeepEqual(sink.sent(), want) {
+		t.Errorf("expected %v to be sent one at a time, got %v", want, sink.sent())
+	}
+}
+
+func TestDispatcherRateLimit(t *testing.T) {
+	sink := &fakeSink{name: "hook", digest: true}
+	target := &Target{
+		Sink: sink,
+		Route: &Route{RateLimit: &RateLimitConfig{
+			Max:      2,
+			Interval: metav1.Duration{Duration: time.Minute},
+		}},
+	}
+
+	d := newTestDispatcher(nil)
+	start := d.now
+
+	for _, id := range []string{"a", "b", "c", "d"} {
+		d.dispatch(target, NotificationNew, &models.Incident{ID: id})
+		d.flushAt(0)
+	}
+
+	if len(sink.notifications) != 2 {
+		t.Fatalf("expected 2 notifications within the rate limit, got %v", sink.sent())
+	}
+
+	// the other notifications are held until the limit releases
+	for _, item := range []string{"hook@new:c", "hook@new:d"} {
+		if until := d.holds.held[item]; !until.Equal(start.Add(time.Minute + holdGrace)) {
+			t.Errorf("expected %s to be held until the limit releases, got %s", item, until)
+		}
+	}
+
+	d.dispatch(target, NotificationNew, &models.Incident{ID: "e"})
+	d.flushAt(30 * time.Second)
+
+	if len(sink.notifications) != 2 {
+		t.Fatalf("expected nothing to be sent before the limit releases, got %v", sink.sent())
+	}
+
+	d.flushAt(time.Minute)
+
+	if want := []string{"c", "d", "e"}; len(sink.notifications) != 3 || !reflect.DeepEqual(sink.sent()[2], want) {
+		t.Fatalf("expected %v to be sent as a digest once the limit releases, got %v", want, sink.sent())
+	}
+
+	if len(d.holds.held) != 0 {
+		t.Errorf("expected every held item to be released, got %v", d.holds.held)
+	}
+}
+
+func TestDispatcherRateLimitWithoutDigests(t *testing.T) {
+	sink := &fakeSink{name: "porter"}
+	target := &Target{
+		Sink: sink,
+		Route: &Route{
+			Grouping: &GroupingConfig{
+				By:     GroupByNamespace,
+				Window: metav1.Duration{Duration: 30 * time.Second},
+			},
+			RateLimit: &RateLimitConfig{
+				Max:      2,
+				Interval: metav1.Duration{Duration: time.Minute},
+			},
+		},
+	}
+
+	d := newTestDispatcher(nil)
+	start := d.now
+
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		d.dispatch(target, NotificationNew, &models.Incident{ID: id, Namespace: "prod"})
+	}
+
+	d.flushAt(30 * time.Second)
+
+	// each incident is its own notification, so only 2 of them are sent
+	if want := [][]string{{"a"}, {"b"}}; !reflect.DeepEqual(sink.sent(), want) {
+		t.Fatalf("expected %v to be sent within the rate limit, got %v", want, sink.sent())
+	}
+
+	for _, item := range []string{"porter@new:c", "porter@new:d", "porter@new:e"} {
+		if until := d.holds.held[item]; !until.Equal(start.Add(90*time.Second + holdGrace)) {
+			t.Errorf("expected %s to be held until the limit releases, got %s", item, until)
+		}
+	}
+
+	d.flushAt(89 * time.Second)
+
+	if len(sink.notifications) != 2 {
+		t.Fatalf("expected nothing to be sent before the limit releases, got %v", sink.sent())
+	}
+
+	d.flushAt(90 * time.Second)
+
+	if want := [][]string{{"a"}, {"b"}, {"c"}, {"d"}}; !reflect.DeepEqual(sink.sent(), want) {
+		t.Fatalf("expected %v to be sent once the limit releases, got %v", want, sink.sent())
+	}
+
+	d.flushAt(150 * time.Second)
+
+	if want := [][]string{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}}; !reflect.DeepEqual(sink.sent(), want) {
+		t.Fatalf("expected %v to be sent once the limit releases again, got %v", want, sink.sent())
+	}
+
+	if len(d.holds.held) != 0 {
+		t.Errorf("expected every held item to be released, got %v", d.holds.held)
+	}
+}
+
+func TestDispatcherFailure(t *testing.T) {
+	sink := &fakeSink{name: "hook", err: errors.New("unavailable")}
+
+	var failed []string
+
+	d := newTestDispatcher(func(_ context.Context, s Sink, items []*QueueItem, err error) {
+		for _, item := range items {
+			failed = append(failed, item.ToString())
+		}
+	})
+
+	d.dispatch(&Target{Sink: sink, Route: &Route{}}, NotificationNew, &models.Incident{ID: "a"})
+
+	if want := []string{"hook@new:a"}; !reflect.DeepEqual(failed, want) {
+		t.Errorf("expected failed items %v, got %v", want, failed)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	limiter := &rateLimiter{max: 2, interval: time.Minute}
+
+	tests := []struct {
+		offset    time.Duration
+		allowed   bool
+		releaseAt time.Duration
+	}{
+		{offset: 0, allowed: true},
+		{offset: 10 * time.Second, allowed: true},
+		{offset: 20 * time.Second, allowed: false, releaseAt: time.Minute},
+		{offset: time.Minute, allowed: true},
+		{offset: time.Minute + 5*time.Second, allowed: false, releaseAt: time.Minute + 10*time.Second},
+		{offset: time.Minute + 10*time.Second, allowed: true},
+	}
+
+	for _, test := range tests {
+		if allowed := limiter.allow(start.Add(test.offset)); allowed != test.allowed {
+			t.Fatalf("expected allowed to be %t at %s, got %t", test.allowed, test.offset, allowed)
+		}
+
+		if !test.allowed {
+			if releaseAt := limiter.releaseAt(); !releaseAt.Equal(start.Add(test.releaseAt)) {
+				t.Errorf("expected limit at %s to release at %s, got %s", test.offset, test.releaseAt,
+					releaseAt.Sub(start))
+			}
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/porter-dev/porter-agent/pkg/metrics"
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type NotificationType string
+
+const (
+	NotificationNew          NotificationType = "new"
+	NotificationResolved     NotificationType = "resolved"
+	NotificationAcknowledged NotificationType = "acknowledged"
+	NotificationReopened     NotificationType = "reopened"
+	NotificationCommented    NotificationType = "commented"
+)
+
+var notificationTypes = map[NotificationType]bool{
+	NotificationNew:          true,
+	NotificationResolved:     true,
+	NotificationAcknowledged: true,
+	NotificationReopened:     true,
+	NotificationCommented:    true,
+}
+
+// Notification is a single state change of an incident that should be
+// delivered to one or more sinks. Digests group several incidents sharing
+// the same type into one notification, in which case Incidents is set
+// instead of Incident.
+type Notification struct {
+	Type     NotificationType `json:"type"`
+	Incident *models.Incident `json:"incident,omitempty"`
+
+	// Comment is set for notifications of type commented
+	Comment *models.IncidentComment `json:"comment,omitempty"`
+
+	Digest    bool               `json:"digest"`
+	Summary   string             `json:"summary,omitempty"`
+	Incidents []*models.Incident `json:"incidents,omitempty"`
+}
+
+// Sink is a destination for notifications, such as the Porter API or a generic webhook
+type Sink interface {
+	Name() string
+	Send(notification *Notification) error
+}
+
+// Send sends the notification to the sink and records the result and the
+// time taken in the metrics of the sink and in a span
+func Send(ctx context.Context, sink Sink, notification *Notification) (err error) {
+	_, span := tracing.StartSpan(ctx, "notify.Send", trace.WithAttributes(
+		attribute.String("sink", sink.Name()),
+		attribute.String("notification.type", string(notification.Type)),
+		attribute.Bool("notification.digest", notification.Digest),
+	))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	if notification.Incident != nil {
+		span.SetAttributes(attribute.String("incident.id", notification.Incident.ID))
+	}
+
+	start := time.Now()
+
+	err = sink.Send(notification)
+
+	metrics.NotificationDuration.WithLabelValues(sink.Name()).Observe(time.Since(start).Seconds())
+
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+
+	metrics.NotificationsSent.WithLabelValues(sink.Name(), string(notification.Type), result).Inc()
+
+	return err
+}
+
+// DigestSink is implemented by sinks which accept digest notifications. Digests
+// for other sinks are delivered as one notification per incident.
+type DigestSink interface {
+	Sink
+	SupportsDigest() bool
+}
+
+// QueueItem is an item of the pending notification queue. Items are of the
+// form "<type>:<incident_id>", or "<sink>@<type>:<incident_id>" when the
+// delivery is being retried or held back for a single sink. Items referring to
+// an object of the incident, such as a comment, end with "#<ref>". Items which
+// are queued again end with "~<queued_at>", the unix time at which their
+// notification was first queued, followed by ",<attempts>" with the number of
+// failed deliveries for retries. When tracing is enabled, items end with
+// "|<traceparent>" of the span which queued them.
+type QueueItem struct {
+	Sink        string
+	Type        NotificationType
+	IncidentID  string
+	Ref         string
+	QueuedAt    time.Time
+	Attempts    int
+	TraceParent string
+}
+
+func NewQueueItemFromString(payload string) (*QueueItem, error) {
+	item := &QueueItem{}
+
+	if i := strings.LastIndex(payload, "|"); i >= 0 {
+		item.TraceParent = payload[i+1:]
+		payload = payload[:i]
+	}
+
+	if i := strings.LastIndex(payload, "~"); i >= 0 {
+		segments := strings.SplitN(payload[i+1:], ",", 2)
+
+		queuedAt, err := strconv.ParseInt(segments[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid queue time in queue item: %s", payload)
+		}
+
+		item.QueuedAt = time.Unix(queuedAt, 0)
+
+		if len(segments) == 2 {
+			if item.Attempts, err = strconv.Atoi(segments[1]); err != nil {
+				return nil, fmt.Errorf("invalid attempts in queue item: %s", payload)
+			}
+		}
+
+		payload = payload[:i]
+	}
+
+	if segments := strings.SplitN(payload, "@", 2); len(segments) == 2 {
+		item.Sink = segments[0]
+		payload = segments[1]
+	}
+
+	segments := strings.SplitN(payload, ":", 2)
+	if len(segments) != 2 {
+		return nil, fmt.Errorf("invalid queue item of the form: %s", payload)
+	}
+
+	item.Type = NotificationType(segments[0])
+	item.IncidentID = segments[1]
+
+	if refSegments := strings.SplitN(item.IncidentID, "#", 2); len(refSegments) == 2 {
+		item.IncidentID = refSegments[0]
+		item.Ref = refSegments[1]
+	}
+
+	if !notificationTypes[item.Type] {
+		return nil, fmt.Errorf("invalid notification type %s in queue item: %s", item.Type, payload)
+	}
+
+	return item, nil
+}
+
+// ForSink returns a copy of the item for the delivery to a single sink
+func (i *QueueItem) ForSink(sink string) *QueueItem {
+	item := *i
+	item.Sink = sink
+
+	return &item
+}
+
+func (i *QueueItem) ToString() string {
+	payload := fmt.Sprintf("%s:%s", i.Type, i.IncidentID)
+
+	if i.Ref != "" {
+		payload += "#" + i.Ref
+	}
+
+	if i.Sink != "" {
+		payload = i.Sink + "@" + payload
+	}
+
+	if !i.QueuedAt.IsZero() {
+		payload += "~" + strconv.FormatInt(i.QueuedAt.Unix(), 10)
+
+		if i.Attempts > 0 {
+			payload += "," + strconv.Itoa(i.Attempts)
+		}
+	}
+
+	if i.TraceParent != "" {
+		payload += "|" + i.TraceParent
+	}
+
+	return payload
+}
@@ -0,0 +1,64 @@
+package notify
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestQueueItem(t *testing.T) {
+	tests := []struct {
+		payload string
+		want    *QueueItem
+	}{
+		{
+			payload: "new:incident:web:default:1700000000",
+			want:    &QueueItem{Type: NotificationNew, IncidentID: "incident:web:default:1700000000"},
+		},
+		{
+			payload: "hook@commented:incident:web:default:1700000000#42",
+			want: &QueueItem{Sink: "hook", Type: NotificationCommented,
+				IncidentID: "incident:web:default:1700000000", Ref: "42"},
+		},
+		{
+			payload: "hook@resolved:incident:web:default:1700000000~1700000100",
+			want: &QueueItem{Sink: "hook", Type: NotificationResolved,
+				IncidentID: "incident:web:default:1700000000", QueuedAt: time.Unix(1700000100, 0)},
+		},
+		{
+			payload: "hook@new:incident:web:default:1700000000~1700000100,3|00-trace-span-01",
+			want: &QueueItem{Sink: "hook", Type: NotificationNew, IncidentID: "incident:web:default:1700000000",
+				QueuedAt: time.Unix(1700000100, 0), Attempts: 3, TraceParent: "00-trace-span-01"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.payload, func(t *testing.T) {
+			item, err := NewQueueItemFromString(test.payload)
+			if err != nil {
+				t.Fatalf("unexpected error parsing queue item: %v", err)
+			}
+
+			if !reflect.DeepEqual(item, test.want) {
+				t.Errorf("expected %+v, got %+v", test.want, item)
+			}
+
+			if got := item.ToString(); got != test.payload {
+				t.Errorf("expected %s to round-trip, got %s", test.payload, got)
+			}
+		})
+	}
+}
+
+func TestInvalidQueueItem(t *testing.T) {
+	for _, payload := range []string{
+		"incident",
+		"unknown:incident:web:default:1700000000",
+		"new:incident:web:default:1700000000~yesterday",
+		"new:incident:web:default:1700000000~1700000100,many",
+	} {
+		if _, err := NewQueueItemFromString(payload); err == nil {
+			t.Errorf("expected %s to be rejected", payload)
+		}
+	}
+}
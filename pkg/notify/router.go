@@ -0,0 +1,189 @@
+package notify
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/porter-dev/porter-agent/pkg/httpclient"
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/signature"
+)
+
+const PorterSinkName = "porter"
+
+// Router picks the sinks an incident notification should be delivered to
+type Router struct {
+	sinks        map[string]Sink
+	routes       []*Route
+	defaultRoute *Route
+}
+
+// NewRouter builds the sinks of the configuration and validates its routes.
+// The porter sink is always registered under the name "porter" and is the
+// default route unless the configuration says otherwise.
+func NewRouter(config *Config, porterSink Sink, signer *signature.Signer) (*Router, error) {
+	r := &Router{
+		sinks: map[string]Sink{
+			PorterSinkName: porterSink,
+		},
+		routes:       config.Routes,
+		defaultRoute: config.Default,
+	}
+
+	if r.defaultRoute == nil {
+		r.defaultRoute = &Route{
+			Receivers: []string{PorterSinkName},
+		}
+	}
+
+	for _, receiver := range config.Receivers {
+		if !sinkNameRegex.MatchString(receiver.Name) {
+			return nil, fmt.Errorf("invalid receiver name: %q", receiver.Name)
+		}
+
+		if _, ok := r.sinks[receiver.Name]; ok {
+			return nil, fmt.Errorf("duplicate receiver name: %s", receiver.Name)
+		}
+
+		switch receiver.Type {
+		case SinkTypeWebhook:
+			if receiver.URL == "" {
+				return nil, fmt.Errorf("webhook receiver %s must have a url", receiver.Name)
+			}
+
+			receiverSigner := signer
+			if len(receiver.SigningSecrets) > 0 {
+				receiverSigner = signature.NewSigner(receiver.SigningSecrets...)
+			}
+
+			r.sinks[receiver.Name] = NewWebhookSink(receiver.Name, httpclient.NewClient(receiver.URL, "", receiverSigner))
+		case SinkTypePagerDuty:
+			if receiver.RoutingKey == "" {
+				return nil, fmt.Errorf("pagerduty receiver %s must have a routing_key", receiver.Name)
+			}
+
+			r.sinks[receiver.Name] = NewPagerDutySink(receiver.Name,
+				httpclient.NewClient(pagerDutyEventsHost, "", nil), receiver.RoutingKey)
+		default:
+			return nil, fmt.Errorf("unknown type %q for receiver %s", receiver.Type, receiver.Name)
+		}
+	}
+
+	for _, route := range append(r.routes, r.defaultRoute) {
+		if err := route.validate(); err != nil {
+			return nil, err
+		}
+
+		for _, name := range route.Receivers {
+			if _, ok := r.sinks[name]; !ok {
+				return nil, fmt.Errorf("route refers to unknown receiver: %s", name)
+			}
+		}
+	}
+
+	return r, nil
+}
+
+// GetSink returns the sink registered with the given name
+func (r *Router) GetSink(name string) (Sink, bool) {
+	sink, ok := r.sinks[name]
+	return sink, ok
+}
+
+// Target is a sink selected for delivery along with the route which selected it
+type Target struct {
+	Sink  Sink
+	Route *Route
+}
+
+// Route returns the targets matching the incident, without duplicate sinks.
+// The default route is used when no other route matches.
+func (r *Router) Route(incident *models.Incident) []*Target {
+	var matched []*Route
+
+	for _, route := range r.routes {
+		if route.Match.Matches(incident) {
+			matched = append(matched, route)
+
+			if !route.Continue {
+				break
+			}
+		}
+	}
+
+	if len(matched) == 0 {
+		matched = append(matched, r.defaultRoute)
+	}
+
+	var targets []*Target
+	seen := make(map[string]bool)
+
+	for _, route := range matched {
+		for _, name := range route.Receivers {
+			if !seen[name] {
+				seen[name] = true
+				targets = append(targets, &Target{
+					Sink:  r.sinks[name],
+					Route: route,
+				})
+			}
+		}
+	}
+
+	return targets
+}
+
+func (m *Matcher) Matches(incident *models.Incident) bool {
+	if len(m.Namespaces) > 0 && !matchesGlob(m.Namespaces, incident.Namespace) {
+		return false
+	}
+
+	if len(m.Releases) > 0 && !matchesGlob(m.Releases, incident.ReleaseName) {
+		return false
+	}
+
+	if len(m.OwnerKinds) > 0 && !contains(m.OwnerKinds, incident.OwnerKind) {
+		return false
+	}
+
+	if len(m.Severities) > 0 {
+		found := false
+
+		for _, severity := range m.Severities {
+			if severity == incident.Severity {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if len(m.Reasons) > 0 && !contains(m.Reasons, incident.LatestFilterReason) {
+		return false
+	}
+
+	return true
+}
+
+func matchesGlob(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, value); err == nil && ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
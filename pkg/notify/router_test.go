@@ -0,0 +1,170 @@
+package notify
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/porter-dev/porter-agent/pkg/models"
+)
+
+func TestRoute(t *testing.T) {
+	config := &Config{
+		Receivers: []*ReceiverConfig{
+			{Name: "staging", Type: SinkTypeWebhook, URL: "http://staging.example.com"},
+			{Name: "prod", Type: SinkTypeWebhook, URL: "http://prod.example.com"},
+			{Name: "oom", Type: SinkTypeWebhook, URL: "http://oom.example.com"},
+			{Name: "critical", Type: SinkTypeWebhook, URL: "http://critical.example.com"},
+		},
+		Routes: []*Route{
+			{
+				Match:     Matcher{Namespaces: []string{"staging-*"}},
+				Receivers: []string{"staging"},
+			},
+			{
+				Match:     Matcher{Namespaces: []string{"prod-*"}, Reasons: []string{"OOMKilled"}},
+				Receivers: []string{"oom"},
+				Continue:  true,
+			},
+			{
+				Match:     Matcher{Severities: []models.Severity{models.SeverityCritical}},
+				Receivers: []string{"critical", "oom"},
+				Continue:  true,
+			},
+			{
+				Match:     Matcher{Namespaces: []string{"prod-*"}, Releases: []string{"api-?"}},
+				Receivers: []string{"prod"},
+			},
+			{
+				Match:     Matcher{Namespaces: []string{"prod-*"}},
+				Receivers: []string{"porter"},
+			},
+		},
+	}
+
+	router, err := NewRouter(config, &fakeSink{name: PorterSinkName}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error creating router: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		incident *models.Incident
+		want     []string
+	}{
+		{
+			name:     "glob on namespace",
+			incident: &models.Incident{Namespace: "staging-eu", ReleaseName: "api-1"},
+			want:     []string{"staging"},
+		},
+		{
+			name:     "first matching route only",
+			incident: &models.Incident{Namespace: "prod-eu", ReleaseName: "api-1"},
+			want:     []string{"prod"},
+		},
+		{
+			name:     "glob on release",
+			incident: &models.Incident{Namespace: "prod-eu", ReleaseName: "worker"},
+			want:     []string{"porter"},
+		},
+		{
+			name: "continue to the next matching routes without duplicates",
+			incident: &models.Incident{Namespace: "prod-eu", ReleaseName: "api-1", LatestFilterReason: "OOMKilled",
+				Severity: models.SeverityCritical},
+			want: []string{"oom", "critical", "prod"},
+		},
+		{
+			name:     "continue when the last matching route",
+			incident: &models.Incident{Namespace: "dev", Severity: models.SeverityCritical},
+			want:     []string{"critical", "oom"},
+		},
+		{
+			name:     "default route",
+			incident: &models.Incident{Namespace: "dev"},
+			want:     []string{"porter"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+
+			for _, target := range router.Route(test.incident) {
+				got = append(got, target.Sink.Name())
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("expected targets %v, got %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestRouteCustomDefault(t *testing.T) {
+	config := &Config{
+		Receivers: []*ReceiverConfig{
+			{Name: "fallback", Type: SinkTypeWebhook, URL: "http://fallback.example.com"},
+		},
+		Default: &Route{Receivers: []string{"fallback", "porter"}},
+	}
+
+	router, err := NewRouter(config, &fakeSink{name: PorterSinkName}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error creating router: %v", err)
+	}
+
+	targets := router.Route(&models.Incident{Namespace: "default"})
+
+	if len(targets) != 2 || targets[0].Sink.Name() != "fallback" || targets[1].Sink.Name() != PorterSinkName {
+		t.Errorf("expected the default receivers, got %d targets", len(targets))
+	}
+
+	for _, target := range targets {
+		if target.Route != config.Default {
+			t.Errorf("expected target %s to be selected by the default route", target.Sink.Name())
+		}
+	}
+}
+
+func TestNewRouterInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+	}{
+		{
+			name:   "unknown receiver",
+			config: &Config{Routes: []*Route{{Receivers: []string{"missing"}}}},
+		},
+		{
+			name: "duplicate receiver",
+			config: &Config{Receivers: []*ReceiverConfig{
+				{Name: "porter", Type: SinkTypeWebhook, URL: "http://example.com"},
+			}},
+		},
+		{
+			name:   "webhook without url",
+			config: &Config{Receivers: []*ReceiverConfig{{Name: "hook", Type: SinkTypeWebhook}}},
+		},
+		{
+			name: "invalid grouping",
+			config: &Config{Default: &Route{
+				Receivers: []string{"porter"},
+				Grouping:  &GroupingConfig{By: "release"},
+			}},
+		},
+		{
+			name: "invalid rate limit",
+			config: &Config{Default: &Route{
+				Receivers: []string{"porter"},
+				RateLimit: &RateLimitConfig{Max: 0},
+			}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewRouter(test.config, &fakeSink{name: PorterSinkName}, nil); err == nil {
+				t.Errorf("expected config to be rejected")
+			}
+		})
+	}
+}
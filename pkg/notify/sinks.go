@@ -0,0 +1,206 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/porter-dev/porter-agent/pkg/httpclient"
+)
+
+const (
+	SinkTypePorter    = "porter"
+	SinkTypeWebhook   = "webhook"
+	SinkTypePagerDuty = "pagerduty"
+
+	pagerDutyEventsHost = "https://events.pagerduty.com"
+)
+
+// PorterSink delivers notifications to the incidents endpoints of the Porter API
+type PorterSink struct {
+	name      string
+	client    *httpclient.Client
+	projectID string
+	clusterID string
+}
+
+func NewPorterSink(name string, client *httpclient.Client, projectID, clusterID string) *PorterSink {
+	return &PorterSink{
+		name:      name,
+		client:    client,
+		projectID: projectID,
+		clusterID: clusterID,
+	}
+}
+
+func (s *PorterSink) Name() string {
+	return s.name
+}
+
+func (s *PorterSink) Send(notification *Notification) error {
+	var endpoint string
+
+	// the Porter API only knows about new and resolved incidents
+	switch notification.Type {
+	case NotificationNew, NotificationReopened:
+		endpoint = "notify_new"
+	case NotificationResolved:
+		endpoint = "notify_resolved"
+	default:
+		return nil
+	}
+
+	return checkResponse(s.client.Post(
+		fmt.Sprintf("/api/projects/%s/clusters/%s/incidents/%s", s.projectID, s.clusterID, endpoint),
+		notification.Incident,
+	))
+}
+
+// WebhookSink posts the notification as JSON to an arbitrary URL
+type WebhookSink struct {
+	name   string
+	client *httpclient.Client
+}
+
+func NewWebhookSink(name string, client *httpclient.Client) *WebhookSink {
+	return &WebhookSink{
+		name:   name,
+		client: client,
+	}
+}
+
+func (s *WebhookSink) Name() string {
+	return s.name
+}
+
+func (s *WebhookSink) SupportsDigest() bool {
+	return true
+}
+
+func (s *WebhookSink) Send(notification *Notification) error {
+	return checkResponse(s.client.Post("", notification))
+}
+
+// PagerDutySink triggers and resolves PagerDuty alerts through the Events API v2,
+// using the incident ID as the deduplication key
+type PagerDutySink struct {
+	name       string
+	client     *httpclient.Client
+	routingKey string
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string      `json:"summary"`
+	Source        string      `json:"source"`
+	Severity      string      `json:"severity"`
+	Component     string      `json:"component,omitempty"`
+	Group         string      `json:"group,omitempty"`
+	Class         string      `json:"class,omitempty"`
+	CustomDetails interface{} `json:"custom_details,omitempty"`
+}
+
+func NewPagerDutySink(name string, client *httpclient.Client, routingKey string) *PagerDutySink {
+	return &PagerDutySink{
+		name:       name,
+		client:     client,
+		routingKey: routingKey,
+	}
+}
+
+func (s *PagerDutySink) Name() string {
+	return s.name
+}
+
+func (s *PagerDutySink) Send(notification *Notification) error {
+	incident := notification.Incident
+
+	event := &pagerDutyEvent{
+		RoutingKey: s.routingKey,
+		DedupKey:   incident.ID,
+	}
+
+	switch notification.Type {
+	case NotificationResolved:
+		event.EventAction = "resolve"
+	case NotificationAcknowledged:
+		event.EventAction = "acknowledge"
+	case NotificationCommented:
+		// comments have no equivalent in the Events API
+		return nil
+	default:
+		event.EventAction = "trigger"
+
+		// PagerDuty only knows about critical, error, warning and info
+		severity := string(incident.Severity)
+		if severity == "" {
+			severity = "error"
+		}
+
+		event.Payload = &pagerDutyPayload{
+			Summary:       fmt.Sprintf("%s/%s: %s", incident.Namespace, incident.ReleaseName, incident.LatestReason),
+			Source:        "porter-agent",
+			Severity:      severity,
+			Component:     incident.ReleaseName,
+			Group:         incident.Namespace,
+			Class:         incident.LatestFilterReason,
+			CustomDetails: incident,
+		}
+	}
+
+	return checkResponse(s.client.Post("/v2/enqueue", event))
+}
+
+// StatusError is returned when a sink answers with a non-2xx status code
+type StatusError struct {
+	StatusCode int
+	URL        string
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d from %s: %s", e.StatusCode, e.URL, e.Body)
+}
+
+// IsPermanentError returns whether a delivery failed in a way which retrying
+// cannot fix, such as a bad routing key or a missing webhook, that is a 4xx
+// status code other than 408 Request Timeout and 429 Too Many Requests
+func IsPermanentError(err error) bool {
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+
+	code := statusErr.StatusCode
+
+	return code >= 400 && code < 500 && code != http.StatusRequestTimeout && code != http.StatusTooManyRequests
+}
+
+// checkResponse closes the response body and turns non-2xx responses into errors
+func checkResponse(res *http.Response, err error) error {
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+
+		return &StatusError{
+			StatusCode: res.StatusCode,
+			URL:        res.Request.URL.String(),
+			Body:       string(body),
+		}
+	}
+
+	return nil
+}
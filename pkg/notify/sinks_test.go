@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/porter-dev/porter-agent/pkg/httpclient"
+	"github.com/porter-dev/porter-agent/pkg/models"
+)
+
+func TestIsPermanentError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: &StatusError{StatusCode: http.StatusBadRequest}, want: true},
+		{err: &StatusError{StatusCode: http.StatusNotFound}, want: true},
+		{err: fmt.Errorf("wrapped: %w", &StatusError{StatusCode: http.StatusForbidden}), want: true},
+		{err: &StatusError{StatusCode: http.StatusRequestTimeout}, want: false},
+		{err: &StatusError{StatusCode: http.StatusTooManyRequests}, want: false},
+		{err: &StatusError{StatusCode: http.StatusBadGateway}, want: false},
+		{err: errors.New("connection refused"), want: false},
+	}
+
+	for _, test := range tests {
+		if got := IsPermanentError(test.err); got != test.want {
+			t.Errorf("expected IsPermanentError(%v) to be %t", test.err, test.want)
+		}
+	}
+}
+
+func TestWebhookSinkStatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no such hook", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink("hook", httpclient.NewClient(server.URL, "", nil))
+
+	err := sink.Send(&Notification{Type: NotificationNew, Incident: &models.Incident{ID: "a"}})
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected a status error with code 404, got %v", err)
+	}
+
+	if !IsPermanentError(err) {
+		t.Errorf("expected a missing webhook to be a permanent error")
+	}
+}
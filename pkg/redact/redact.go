@@ -0,0 +1,236 @@
+// Package redact removes secrets and personal data from captured logs before
+// they are stored.
+package redact
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	enabled bool
+
+	// path to the YAML file holding the redaction config
+	configFile string
+)
+
+func init() {
+	viper.SetDefault("REDACTION_ENABLED", true)
+	viper.AutomaticEnv()
+
+	enabled = viper.GetBool("REDACTION_ENABLED")
+	configFile = viper.GetString("REDACTION_CONFIG_FILE")
+}
+
+// Detector finds one kind of sensitive value. If the regexp has capture
+// groups, only the first one is redacted, so that for instance the user of a
+// URL with credentials is kept.
+type Detector struct {
+	Name   string
+	Regexp *regexp.Regexp
+}
+
+// built-in detectors, applied in this order
+var builtinDetectors = []*Detector{
+	{
+		Name:   "jwt",
+		Regexp: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+	},
+	{
+		Name:   "aws-access-key",
+		Regexp: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	},
+	{
+		Name:   "aws-secret-key",
+		Regexp: regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[=:]\s*["']?([A-Za-z0-9/+=]{40})\b`),
+	},
+	{
+		Name:   "bearer-token",
+		Regexp: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9\-._~+/]+=*)`),
+	},
+	{
+		Name:   "url-credentials",
+		Regexp: regexp.MustCompile(`\b[A-Za-z][A-Za-z0-9+.-]*://[^\s:/@]*:([^\s/@]+)@`),
+	},
+	{
+		Name:   "password",
+		Regexp: regexp.MustCompile(`(?i)(?:password|passwd|pwd|secret|api[_-]?key|access[_-]?token)["']?\s*[=:]\s*["']?([^\s"',;&\]}]+)`),
+	},
+	{
+		Name:   "email",
+		Regexp: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}\b`),
+	},
+}
+
+// Config is the redaction config. An example:
+//
+//	detectors: [jwt, aws-access-key, aws-secret-key, bearer-token, url-credentials]
+//	patterns:
+//	  - name: stripe-key
+//	    regexp: 'sk_live_[0-9a-zA-Z]{24}'
+//	  - name: session-id
+//	    regexp: 'session=([0-9a-f]{32})'
+//
+// Detectors are the built-in detectors to use, which default to all of them,
+// and patterns are applied after them.
+type Config struct {
+	Detectors []string   `json:"detectors"`
+	Patterns  []*Pattern `json:"patterns"`
+}
+
+type Pattern struct {
+	Name   string `json:"name"`
+	Regexp string `json:"regexp"`
+}
+
+// Counts is the number of values redacted by detector
+type Counts map[string]int
+
+// Total returns the number of values redacted by all detectors
+func (c Counts) Total() int {
+	total := 0
+
+	for _, count := range c {
+		total += count
+	}
+
+	return total
+}
+
+// Add adds the counts of other to c
+func (c Counts) Add(other Counts) {
+	for name, count := range other {
+		c[name] += count
+	}
+}
+
+type Redactor struct {
+	detectors []*Detector
+}
+
+// NewRedactorFromEnv returns the redactor configured by REDACTION_ENABLED and
+// REDACTION_CONFIG_FILE, which redacts nothing if redaction is disabled
+func NewRedactorFromEnv() (*Redactor, error) {
+	if !enabled {
+		return &Redactor{}, nil
+	}
+
+	config := &Config{}
+
+	if configFile != "" {
+		data, err := ioutil.ReadFile(configFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading redaction config file %s. Error: %w", configFile, err)
+		}
+
+		if err := yaml.UnmarshalStrict(data, config); err != nil {
+			return nil, fmt.Errorf("error parsing redaction config file %s. Error: %w", configFile, err)
+		}
+	}
+
+	return NewRedactor(config)
+}
+
+// NewRedactor returns a redactor using the detectors and patterns of config
+func NewRedactor(config *Config) (*Redactor, error) {
+	r := &Redactor{}
+
+	if config.Detectors == nil {
+		r.detectors = append(r.detectors, builtinDetectors...)
+	}
+
+	for _, name := range config.Detectors {
+		detector := getBuiltinDetector(name)
+		if detector == nil {
+			return nil, fmt.Errorf("unknown redaction detector %q", name)
+		}
+
+		r.detectors = append(r.detectors, detector)
+	}
+
+	for _, pattern := range config.Patterns {
+		if pattern.Name == "" {
+			return nil, fmt.Errorf("redaction patterns must have a name")
+		}
+
+		re, err := regexp.Compile(pattern.Regexp)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %s. Error: %w", pattern.Name, err)
+		}
+
+		r.detectors = append(r.detectors, &Detector{
+			Name:   pattern.Name,
+			Regexp: re,
+		})
+	}
+
+	return r, nil
+}
+
+// Redact replaces the sensitive values in text with [REDACTED:<detector>]
+func (r *Redactor) Redact(text string) (string, Counts) {
+	counts := Counts{}
+
+	for _, detector := range r.detectors {
+		var count int
+
+		text, count = redact(text, detector)
+
+		if count > 0 {
+			counts[detector.Name] += count
+		}
+	}
+
+	return text, counts
+}
+
+func redact(text string, detector *Detector) (string, int) {
+	matches := detector.Regexp.FindAllStringSubmatchIndex(text, -1)
+	if matches == nil {
+		return text, 0
+	}
+
+	var b strings.Builder
+	count, last := 0, 0
+
+	for _, match := range matches {
+		start, end := match[0], match[1]
+
+		if len(match) > 2 {
+			start, end = match[2], match[3]
+		}
+
+		// values which are redacted already, such as the token of
+		// "token: [REDACTED:jwt]", are left as they are
+		if start < 0 || start == end || strings.HasPrefix(text[start:], "[REDACTED:") {
+			continue
+		}
+
+		b.WriteString(text[last:start])
+		b.WriteString("[REDACTED:")
+		b.WriteString(detector.Name)
+		b.WriteString("]")
+
+		last = end
+		count++
+	}
+
+	b.WriteString(text[last:])
+
+	return b.String(), count
+}
+
+func getBuiltinDetector(name string) *Detector {
+	for _, detector := range builtinDetectors {
+		if detector.Name == name {
+			return detector
+		}
+	}
+
+	return nil
+}
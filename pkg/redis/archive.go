@@ -0,0 +1,422 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	porterErrors "github.com/porter-dev/porter-agent/pkg/errors"
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/tracing"
+	"github.com/porter-dev/porter-agent/pkg/utils"
+)
+
+// Incidents and logs are marked once they are archived, so that they are only
+// written to the archive once. The marks expire along with what they mark.
+func archivedKey(id string) string {
+	return fmt.Sprintf("archived:%s", id)
+}
+
+// GetExpiringIncidents returns the IDs of the incidents which expire within
+// the given duration and have not been archived yet
+func (c *Client) GetExpiringIncidents(ctx context.Context, within time.Duration) ([]string, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.GetExpiringIncidents")
+	defer span.End()
+
+	if err := c.buildIncidentIndexes(ctx); err != nil {
+		return nil, err
+	}
+
+	incidentIDs, err := c.client.ZRange(ctx, incidentsByCreatedKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching incidents. Error: %w", err)
+	}
+
+	return c.getExpiring(ctx, incidentIDs, within)
+}
+
+// GetExpiringLogs returns the IDs of the logs which expire within the given
+// duration and have not been archived yet
+func (c *Client) GetExpiringLogs(ctx context.Context, within time.Duration) ([]string, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.GetExpiringLogs")
+	defer span.End()
+
+	logIDs, err := c.client.ZRange(ctx, logIndexAllKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching logs. Error: %w", err)
+	}
+
+	return c.getExpiring(ctx, logIDs, within)
+}
+
+func (c *Client) getExpiring(ctx context.Context, keys []string, within time.Duration) ([]string, error) {
+	ttlCmds := make([]*goredis.DurationCmd, 0, len(keys))
+	archivedCmds := make([]*goredis.IntCmd, 0, len(keys))
+
+	_, err := c.client.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		for _, key := range keys {
+			ttlCmds = append(ttlCmds, pipe.PTTL(ctx, key))
+			archivedCmds = append(archivedCmds, pipe.Exists(ctx, archivedKey(key)))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching expiration times. Error: %w", err)
+	}
+
+	var res []string
+
+	for i, key := range keys {
+		// keys which do not exist or do not expire have a negative TTL
+		if ttl := ttlCmds[i].Val(); ttl > 0 && ttl <= within && archivedCmds[i].Val() == 0 {
+			res = append(res, key)
+		}
+	}
+
+	return res, nil
+}
+
+// MarkArchived records that an incident or logs were written to the archive
+func (c *Client) MarkArchived(ctx context.Context, id string) error {
+	ctx, span := tracing.StartSpan(ctx, "redis.MarkArchived")
+	defer span.End()
+
+	ttl, err := c.client.PTTL(ctx, id).Result()
+	if err != nil {
+		return fmt.Errorf("error getting expiration of %s. Error: %w", id, err)
+	} else if ttl <= 0 {
+		// it has expired in the meantime
+		return nil
+	}
+
+	if _, err := c.client.Set(ctx, archivedKey(id), "true", ttl).Result(); err != nil {
+		return fmt.Errorf("error marking %s as archived. Error: %w", id, err)
+	}
+
+	return nil
+}
+
+// ExportIncident returns an incident along with its events and comments, to
+// write it to the archive
+func (c *Client) ExportIncident(ctx context.Context, incidentID string) (*models.ArchivedIncident, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.ExportIncident")
+	defer span.End()
+
+	incident, err := c.GetIncidentDetails(ctx, incidentID)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := c.GetIncidentEventsByID(ctx, incidentID)
+	if err != nil {
+		return nil, err
+	}
+
+	comments, err := c.GetIncidentComments(ctx, incidentID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots, err := c.GetSnapshots(ctx, incidentID)
+	if err != nil {
+		return nil, err
+	}
+
+	remediations, err := c.GetRemediations(ctx, incidentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ArchivedIncident{
+		Incident:     incident,
+		Events:       events,
+		Comments:     comments,
+		Snapshots:    snapshots,
+		Remediations: remediations,
+		ArchivedAt:   time.Now().Unix(),
+	}, nil
+}
+
+// ExportLogs returns captured logs along with the event they were captured
+// for, to write them to the archive
+func (c *Client) ExportLogs(ctx context.Context, logID string) (*models.ArchivedLog, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.ExportLogs")
+	defer span.End()
+
+	logObj, err := utils.NewLogFromString(logID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting log object for log ID: %s. Error: %w", logID, err)
+	}
+
+	contents, err := c.GetLogs(ctx, logID)
+	if err != nil {
+		return nil, err
+	}
+
+	eventID, containerName, err := c.GetLogEvent(ctx, logID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ArchivedLog{
+		LogID:         logID,
+		IncidentID:    logObj.GetIncident().ToString(),
+		EventID:       eventID,
+		ContainerName: containerName,
+		CapturedAt:    logObj.GetTimestamp(),
+		Contents:      contents,
+		ArchivedAt:    time.Now().Unix(),
+	}, nil
+}
+
+// RestoreIncident imports an archived incident, which is kept for the restore
+// retention and is not archived again. Incidents which are still stored are
+// not overwritten, and false is returned for them.
+func (c *Client) RestoreIncident(ctx context.Context, archived *models.ArchivedIncident) (bool, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.RestoreIncident")
+	defer span.End()
+
+	if archived.Incident == nil || len(archived.Events) == 0 {
+		return false, fmt.Errorf("archived incident has no events")
+	}
+
+	incidentID := archived.Incident.ID
+
+	if _, err := utils.NewIncidentFromString(incidentID); err != nil {
+		return false, fmt.Errorf("error getting incident object for incident ID: %s. Error: %w", incidentID, err)
+	}
+
+	if exists, err := c.IncidentExists(ctx, incidentID); err != nil {
+		return false, err
+	} else if exists {
+		return false, nil
+	}
+
+	ttl := c.retention.Restored.Duration
+
+	var latestEvent *models.PodEvent
+	var latestScore float64
+
+	members := make([]goredis.Z, 0, len(archived.Events))
+
+	for _, event := range archived.Events {
+		eventJSON, err := json.Marshal(event)
+		if err != nil {
+			return false, fmt.Errorf("error marshalling to JSON with event ID: %s. Error: %w", event.EventID, err)
+		}
+
+		score := eventScore(event)
+
+		if latestEvent == nil || score > latestScore {
+			latestEvent, latestScore = event, score
+		}
+
+		members = append(members, goredis.Z{
+			Score:  score,
+			Member: eventJSON,
+		})
+	}
+
+	if _, err := c.client.ZAddArgs(ctx, incidentID, goredis.ZAddArgs{Members: members}).Result(); err != nil {
+		return false, fmt.Errorf("error restoring events of incident with ID: %s. Error: %w", incidentID, err)
+	}
+
+	if _, err := c.client.PExpire(ctx, incidentID, ttl).Result(); err != nil {
+		return false, fmt.Errorf("error setting expiration to incident with ID: %s. Error: %w", incidentID, err)
+	}
+
+	values := map[string]string{}
+
+	if archived.Incident.ResolvedBy != "" {
+		values[fmt.Sprintf("resolved_by:%s", incidentID)] = archived.Incident.ResolvedBy
+	}
+
+	if archived.Incident.SilencedBy != "" {
+		values[fmt.Sprintf("silenced:%s", incidentID)] = archived.Incident.SilencedBy
+	}
+
+	if archived.Incident.Acknowledged {
+		ackJSON, err := json.Marshal(&models.IncidentAcknowledgement{
+			User:      archived.Incident.AcknowledgedBy,
+			Timestamp: archived.Incident.AcknowledgedAt,
+		})
+		if err != nil {
+			return false, fmt.Errorf("error marshalling acknowledgement for incident ID: %s. Error: %w", incidentID, err)
+		}
+
+		values[fmt.Sprintf("ack:%s", incidentID)] = string(ackJSON)
+	}
+
+	for key, value := range values {
+		if _, err := c.client.Set(ctx, key, value, ttl).Result(); err != nil {
+			return false, fmt.Errorf("error restoring %s of incident with ID: %s. Error: %w", key, incidentID, err)
+		}
+	}
+
+	if len(archived.Comments) > 0 {
+		key := fmt.Sprintf("comments:%s", incidentID)
+
+		for _, comment := range archived.Comments {
+			commentJSON, err := json.Marshal(comment)
+			if err != nil {
+				return false, fmt.Errorf("error marshalling comment for incident ID: %s. Error: %w", incidentID, err)
+			}
+
+			if _, err := c.client.HSet(ctx, key, comment.ID, commentJSON).Result(); err != nil {
+				return false, fmt.Errorf("error restoring comment of incident with ID: %s. Error: %w", incidentID, err)
+			}
+		}
+
+		if _, err := c.client.PExpire(ctx, key, ttl).Result(); err != nil {
+			return false, fmt.Errorf("error setting expiration for comments of incident ID: %s. Error: %w", incidentID, err)
+		}
+	}
+
+	if len(archived.Snapshots) > 0 {
+		key := snapshotsKey(incidentID)
+
+		for _, snapshot := range archived.Snapshots {
+			snapshotJSON, err := json.Marshal(snapshot)
+			if err != nil {
+				return false, fmt.Errorf("error marshalling snapshot for event ID: %s. Error: %w", snapshot.EventID, err)
+			}
+
+			if _, err := c.client.HSet(ctx, key, snapshot.EventID, snapshotJSON).Result(); err != nil {
+				return false, fmt.Errorf("error restoring snapshot of incident with ID: %s. Error: %w", incidentID, err)
+			}
+		}
+
+		if _, err := c.client.PExpire(ctx, key, ttl).Result(); err != nil {
+			return false, fmt.Errorf("error setting expiration for snapshots of incident ID: %s. Error: %w", incidentID, err)
+		}
+	}
+
+	if len(archived.Remediations) > 0 {
+		key := remediationsKey(incidentID)
+
+		for _, remediation := range archived.Remediations {
+			remediationJSON, err := json.Marshal(remediation)
+			if err != nil {
+				return false, fmt.Errorf("error marshalling remediation for incident ID: %s. Error: %w", incidentID, err)
+			}
+
+			if _, err := c.client.HSet(ctx, key, remediation.ID, remediationJSON).Result(); err != nil {
+				return false, fmt.Errorf("error restoring remediation of incident with ID: %s. Error: %w", incidentID, err)
+			}
+		}
+
+		if _, err := c.client.PExpire(ctx, key, ttl).Result(); err != nil {
+			return false, fmt.Errorf("error setting expiration for remediations of incident ID: %s. Error: %w", incidentID, err)
+		}
+	}
+
+	// the first event of incidents which started after a rollout describes
+	// it, and is indexed before the latest event
+	for _, event := range archived.Events {
+		if event.Rollout != nil && event != latestEvent {
+			if err := c.indexIncident(ctx, incidentID, event, ""); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	// restored incidents have no affected pods, so they are resolved
+	if err := c.indexIncident(ctx, incidentID, latestEvent, "RESOLVED"); err != nil {
+		return false, err
+	}
+
+	if err := c.MarkArchived(ctx, incidentID); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// RestoreLogs imports archived logs, which are kept for the restore retention
+// and are not archived again. Logs which are still stored are not overwritten,
+// and false is returned for them. Restored logs are indexed as captured when
+// they are restored, so that they are not removed from the search index.
+func (c *Client) RestoreLogs(ctx context.Context, archived *models.ArchivedLog) (bool, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.RestoreLogs")
+	defer span.End()
+
+	logID := archived.LogID
+
+	logObj, err := utils.NewLogFromString(logID)
+	if err != nil {
+		return false, fmt.Errorf("error getting log object for log ID: %s. Error: %w", logID, err)
+	}
+
+	if exists, err := c.client.Exists(ctx, logID).Result(); err != nil {
+		return false, fmt.Errorf("error checking existence of logs with ID: %s. Error: %w", logID, err)
+	} else if exists == 1 {
+		return false, nil
+	}
+
+	ttl := c.retention.Restored.Duration
+
+	if err := c.setLogContents(ctx, logID, archived.Contents, ttl); err != nil {
+		return false, err
+	}
+
+	logsID := fmt.Sprintf("logs:%s", archived.IncidentID)
+
+	if _, err := c.client.ZAdd(ctx, logsID, &goredis.Z{
+		Score:  float64(archived.CapturedAt),
+		Member: logID,
+	}).Result(); err != nil {
+		return false, fmt.Errorf("error adding restored log with ID: %s to logs set of incident ID: %s. Error: %w",
+			logID, archived.IncidentID, err)
+	}
+
+	// the logs set is kept as long as its longest-lived logs
+	if currentTTL, err := c.client.PTTL(ctx, logsID).Result(); err != nil {
+		return false, fmt.Errorf("error getting expiration of logs set for incident ID: %s. Error: %w",
+			archived.IncidentID, err)
+	} else if currentTTL < ttl {
+		if _, err := c.client.PExpire(ctx, logsID, ttl).Result(); err != nil {
+			return false, fmt.Errorf("error setting expiration time for logs set for incident ID: %s. Error: %w",
+				archived.IncidentID, err)
+		}
+	}
+
+	if archived.EventID != "" {
+		if err := c.SetLogEvent(ctx, logID, archived.EventID, archived.ContainerName); err != nil {
+			return false, err
+		}
+	}
+
+	if err := c.indexLogs(ctx, logID, logObj.GetIncident().GetNamespace(), time.Now().Unix(), utils.TokenizeLogs(archived.Contents)); err != nil {
+		return false, err
+	}
+
+	if err := c.MarkArchived(ctx, logID); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// eventScore returns the score of an event in its incident, which is the time
+// it was added at the end of its ID
+func eventScore(event *models.PodEvent) float64 {
+	if i := strings.LastIndex(event.EventID, ":"); i != -1 {
+		if score, err := strconv.ParseInt(event.EventID[i+1:], 10, 64); err == nil {
+			return float64(score)
+		}
+	}
+
+	return float64(event.Timestamp)
+}
+
+// IsExpiredError returns true if the error was caused by an incident or logs
+// which expired while they were read
+func IsExpiredError(err error) bool {
+	return errors.Is(err, porterErrors.IncidentNotFoundError) || errors.Is(err, porterErrors.LogsNotFoundError)
+}
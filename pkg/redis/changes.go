@@ -0,0 +1,163 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/tracing"
+	"github.com/porter-dev/porter-agent/pkg/utils"
+)
+
+const (
+	changesKey = "incident_changes"
+
+	// number of changes kept in the change feed, for clients resuming from
+	// an earlier change
+	maxChanges = 10000
+)
+
+// publishChange appends a change of the incident to the change feed
+func (c *Client) publishChange(ctx context.Context, incidentID string, change *models.IncidentChange) error {
+	incidentObj, err := utils.NewIncidentFromString(incidentID)
+	if err != nil {
+		return fmt.Errorf("error getting incident object for incident ID: %s. Error: %w", incidentID, err)
+	}
+
+	change.IncidentID = incidentID
+	change.ReleaseName = incidentObj.GetReleaseName()
+	change.Namespace = incidentObj.GetNamespace()
+	change.Timestamp = time.Now().Unix()
+
+	changeJSON, err := json.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("error marshalling %s change for incident ID: %s. Error: %w", change.Type, incidentID, err)
+	}
+
+	_, err = c.client.XAdd(ctx, &goredis.XAddArgs{
+		Stream: changesKey,
+		MaxLen: maxChanges,
+		Approx: true,
+		Values: map[string]interface{}{"change": changeJSON},
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("error publishing %s change for incident ID: %s. Error: %w", change.Type, incidentID, err)
+	}
+
+	return nil
+}
+
+// GetLatestIncidentChangeID returns the ID of the latest change in the change
+// feed, to read the changes made after it
+func (c *Client) GetLatestIncidentChangeID(ctx context.Context) (string, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.GetLatestIncidentChangeID")
+	defer span.End()
+
+	messages, err := c.client.XRevRangeN(ctx, changesKey, "+", "-", 1).Result()
+	if err != nil {
+		return "", fmt.Errorf("error fetching latest incident change. Error: %w", err)
+	}
+
+	if len(messages) == 0 {
+		return "0-0", nil
+	}
+
+	return messages[0].ID, nil
+}
+
+// ReadIncidentChanges returns the changes made after the change with the given
+// ID, waiting up to the block duration for one if there are none yet, or not
+// at all if it is negative
+func (c *Client) ReadIncidentChanges(ctx context.Context, lastID string, block time.Duration) ([]*models.IncidentChange, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.ReadIncidentChanges")
+	defer span.End()
+
+	streams, err := c.client.XRead(ctx, &goredis.XReadArgs{
+		Streams: []string{changesKey, lastID},
+		Count:   100,
+		Block:   block,
+	}).Result()
+	if errors.Is(err, goredis.Nil) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading incident changes after ID: %s. Error: %w", lastID, err)
+	}
+
+	var changes []*models.IncidentChange
+
+	for _, stream := range streams {
+		for _, message := range stream.Messages {
+			changeJSON, ok := message.Values["change"].(string)
+			if !ok {
+				continue
+			}
+
+			change := &models.IncidentChange{}
+
+			if err := json.Unmarshal([]byte(changeJSON), change); err != nil {
+				return nil, fmt.Errorf("error unmarshalling incident change with ID: %s. Error: %w", message.ID, err)
+			}
+
+			change.ID = message.ID
+
+			changes = append(changes, change)
+		}
+	}
+
+	return changes, nil
+}
+
+// FollowIncidentChanges calls handle with each change made after it is called
+// until the context is done. Errors reading the feed are passed to
+// handleError, and reading is retried after the block duration.
+func (c *Client) FollowIncidentChanges(
+	ctx context.Context,
+	block time.Duration,
+	handle func(change *models.IncidentChange),
+	handleError func(err error),
+) {
+	lastID := ""
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		var changes []*models.IncidentChange
+		var err error
+
+		if lastID == "" {
+			lastID, err = c.GetLatestIncidentChangeID(ctx)
+		} else {
+			changes, err = c.ReadIncidentChanges(ctx, lastID, block)
+		}
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			handleError(err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(block):
+			}
+
+			continue
+		}
+
+		for _, change := range changes {
+			lastID = change.ID
+
+			handle(change)
+		}
+	}
+}
@@ -5,44 +5,98 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"sort"
 	"strconv"
+	"sync"
 	"time"
 
 	goredis "github.com/go-redis/redis/v8"
 	porterErrors "github.com/porter-dev/porter-agent/pkg/errors"
 	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/retention"
+	"github.com/porter-dev/porter-agent/pkg/tracing"
 	"github.com/porter-dev/porter-agent/pkg/utils"
 )
 
-const (
-	PODSTORE = iota
-)
-
 var agentCreationTimestamp int64 = 0
 
 // Client is a redis client that also holds the
 // value for max log enteries to hold for each pod
 type Client struct {
-	client     *goredis.Client
+	client     goredis.UniversalClient
 	maxEntries int64
+	retention  *retention.Policy
 }
 
-func NewClient(host, port, username, password string, db int, maxEntries int64) *Client {
+// NewClient connects to the store described by the config
+func NewClient(config *Config, maxEntries int64) *Client {
+	client := newUniversalClient(config)
+
+	client.AddHook(tracingHook{})
+
+	policy := config.Retention
+	if policy == nil {
+		policy = retention.NewDefaultPolicy()
+	}
+
 	return &Client{
-		client: goredis.NewClient(&goredis.Options{
-			Addr:     fmt.Sprintf("%s:%s", host, port),
-			Username: username,
-			Password: password,
-			DB:       db,
-		}),
+		client:     client,
 		maxEntries: maxEntries,
+		retention:  policy,
+	}
+}
+
+// NewClientFromEnv connects to the store configured by the REDIS_*
+// environment variables
+func NewClientFromEnv(maxEntries int64) (*Client, error) {
+	config, err := ConfigFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis config. Error: %w", err)
 	}
+
+	return NewClient(config, maxEntries), nil
 }
 
+// keys returns the keys matching the pattern, from every master of a cluster
+func (c *Client) keys(ctx context.Context, pattern string) ([]string, error) {
+	cluster, ok := c.client.(*goredis.ClusterClient)
+	if !ok {
+		return c.client.Keys(ctx, pattern).Result()
+	}
+
+	var mu sync.Mutex
+	var res []string
+
+	err := cluster.ForEachMaster(ctx, func(ctx context.Context, master *goredis.Client) error {
+		keys, err := master.Keys(ctx, pattern).Result()
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		res = append(res, keys...)
+
+		return nil
+	})
+
+	return res, err
+}
+
+// AppendToNotifyWorkQueue queues a notification item. The trace context of the
+// caller is appended to the item, so that its delivery can be linked to it.
 func (c *Client) AppendToNotifyWorkQueue(ctx context.Context, packed []byte) error {
+	ctx, span := tracing.StartSpan(ctx, "redis.AppendToNotifyWorkQueue")
+	defer span.End()
+
 	key := "pending"
 
+	if traceParent := tracing.TraceParent(ctx); traceParent != "" {
+		packed = append(packed, []byte("|"+traceParent)...)
+	}
+
 	_, err := c.client.ZAdd(ctx, key, &goredis.Z{
 		Score:  float64(time.Now().Unix()),
 		Member: packed,
@@ -54,35 +108,51 @@ func (c *Client) AppendToNotifyWorkQueue(ctx context.Context, packed []byte) err
 	return nil
 }
 
+// GetItemFromPendingQueue pops the item of the pending queue with the lowest
+// score, as long as it is due. Items are scored with the unix time at which
+// they should be sent, so that retries and held notifications stay in the
+// queue until then.
 func (c *Client) GetItemFromPendingQueue(ctx context.Context) ([]byte, float64, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.GetItemFromPendingQueue")
+	defer span.End()
+
 	key := "pending"
 
-	// check if there's any item in pending queue
-	count, err := c.client.Exists(ctx, key).Result()
+	items, err := c.client.ZRangeByScoreWithScores(ctx, key, &goredis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(time.Now().Unix(), 10),
+		Count: 1,
+	}).Result()
 	if err != nil {
 		return []byte{}, 0, err
 	}
 
-	if count == 0 {
+	if len(items) == 0 {
 		return []byte{}, 0, porterErrors.NoPendingItemError
 	}
 
-	value, err := c.client.ZPopMin(ctx, key).Result()
-	if err != nil {
-		return []byte{}, 0, err
-	}
-
 	// cast the member to byte array which was originally stored in the array
-	member := value[0].Member
+	member := items[0].Member
 	rawBytes, ok := member.(string)
 	if !ok {
 		return []byte{}, 0, fmt.Errorf("cannot caste item to bytearray, actual type: %T", member)
 	}
 
-	return []byte(rawBytes), value[0].Score, nil
+	// another consumer may have popped the item in the meantime
+	removed, err := c.client.ZRem(ctx, key, rawBytes).Result()
+	if err != nil {
+		return []byte{}, 0, err
+	} else if removed == 0 {
+		return []byte{}, 0, porterErrors.NoPendingItemError
+	}
+
+	return []byte(rawBytes), items[0].Score, nil
 }
 
 func (c *Client) RequeueItemWithScore(ctx context.Context, packed []byte, score float64) error {
+	ctx, span := tracing.StartSpan(ctx, "redis.RequeueItemWithScore")
+	defer span.End()
+
 	key := "pending"
 
 	_, err := c.client.ZAdd(ctx, key, &goredis.Z{
@@ -97,7 +167,112 @@ func (c *Client) RequeueItemWithScore(ctx context.Context, packed []byte, score
 	return nil
 }
 
+// RemoveItemFromPendingQueue removes an item from the pending queue, such as a
+// held notification which was sent
+func (c *Client) RemoveItemFromPendingQueue(ctx context.Context, packed []byte) error {
+	ctx, span := tracing.StartSpan(ctx, "redis.RemoveItemFromPendingQueue")
+	defer span.End()
+
+	if _, err := c.client.ZRem(ctx, "pending", packed).Result(); err != nil {
+		return fmt.Errorf("error removing item from pending queue. Error: %w", err)
+	}
+
+	return nil
+}
+
+// maximum number of items kept in the dead-letter queue, the oldest items are
+// dropped first
+const maxDeadLetterItems = 1000
+
+// GetPendingQueueLength returns the number of notifications waiting to be sent
+func (c *Client) GetPendingQueueLength(ctx context.Context) (int64, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.GetPendingQueueLength")
+	defer span.End()
+
+	count, err := c.client.ZCard(ctx, "pending").Result()
+	if err != nil {
+		return 0, fmt.Errorf("error getting length of pending queue. Error: %w", err)
+	}
+
+	return count, nil
+}
+
+// AddToDeadLetterQueue stores an item of the pending queue which can never be
+// delivered, so that it can be inspected instead of being lost
+func (c *Client) AddToDeadLetterQueue(ctx context.Context, packed []byte) error {
+	ctx, span := tracing.StartSpan(ctx, "redis.AddToDeadLetterQueue")
+	defer span.End()
+
+	key := "dead_letter"
+
+	_, err := c.client.ZAdd(ctx, key, &goredis.Z{
+		Score:  float64(time.Now().Unix()),
+		Member: packed,
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("error adding item to dead-letter queue. Error: %w", err)
+	}
+
+	if _, err := c.client.ZRemRangeByRank(ctx, key, 0, -maxDeadLetterItems-1).Result(); err != nil {
+		return fmt.Errorf("error trimming dead-letter queue. Error: %w", err)
+	}
+
+	return nil
+}
+
+// GetDeadLetterQueueLength returns the number of items in the dead-letter queue
+func (c *Client) GetDeadLetterQueueLength(ctx context.Context) (int64, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.GetDeadLetterQueueLength")
+	defer span.End()
+
+	count, err := c.client.ZCard(ctx, "dead_letter").Result()
+	if err != nil {
+		return 0, fmt.Errorf("error getting length of dead-letter queue. Error: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetNextPendingItem returns the item of the pending queue with the lowest
+// score along with its score, including retries and held notifications which
+// are not due yet, or an empty item if the queue is empty
+func (c *Client) GetNextPendingItem(ctx context.Context) (string, float64, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.GetNextPendingItem")
+	defer span.End()
+
+	items, err := c.client.ZRangeWithScores(ctx, "pending", 0, 0).Result()
+	if err != nil {
+		return "", 0, fmt.Errorf("error getting next item of pending queue. Error: %w", err)
+	}
+
+	if len(items) == 0 {
+		return "", 0, nil
+	}
+
+	member, ok := items[0].Member.(string)
+	if !ok {
+		return "", 0, fmt.Errorf("cannot cast pending item to string, actual type: %T", items[0].Member)
+	}
+
+	return member, items[0].Score, nil
+}
+
+// Ping checks that the store can be reached
+func (c *Client) Ping(ctx context.Context) error {
+	ctx, span := tracing.StartSpan(ctx, "redis.Ping")
+	defer span.End()
+
+	if err := c.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("error pinging redis. Error: %w", err)
+	}
+
+	return nil
+}
+
 func (c *Client) IsFirstRun(ctx context.Context) (bool, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.IsFirstRun")
+	defer span.End()
+
 	key := "porter-agent-creation-timestamp"
 
 	exists, err := c.client.Exists(ctx, key).Result()
@@ -113,6 +288,9 @@ func (c *Client) IsFirstRun(ctx context.Context) (bool, error) {
 }
 
 func (c *Client) SetAgentCreationTimestamp(ctx context.Context) error {
+	ctx, span := tracing.StartSpan(ctx, "redis.SetAgentCreationTimestamp")
+	defer span.End()
+
 	firstRun, err := c.IsFirstRun(ctx)
 	if err != nil {
 		return err
@@ -133,6 +311,9 @@ func (c *Client) SetAgentCreationTimestamp(ctx context.Context) error {
 }
 
 func (c *Client) GetAgentCreationTimestamp(ctx context.Context) (int64, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.GetAgentCreationTimestamp")
+	defer span.End()
+
 	if agentCreationTimestamp != 0 {
 		return agentCreationTimestamp, nil
 	}
@@ -171,6 +352,9 @@ func (c *Client) GetAgentCreationTimestamp(ctx context.Context) (int64, error) {
 }
 
 func (c *Client) IncidentExists(ctx context.Context, incident string) (bool, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.IncidentExists")
+	defer span.End()
+
 	val, err := c.client.Exists(ctx, incident).Result()
 	if err != nil {
 		return false, fmt.Errorf("error checking if incident with ID: %s exists. Error: %w", incident, err)
@@ -184,6 +368,9 @@ func (c *Client) IncidentExists(ctx context.Context, incident string) (bool, err
 }
 
 func (c *Client) GetLatestEventForIncident(ctx context.Context, incidentID string) (*models.PodEvent, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.GetLatestEventForIncident")
+	defer span.End()
+
 	data, err := c.client.ZRangeArgsWithScores(ctx, goredis.ZRangeArgs{
 		Key:   incidentID,
 		Start: 0,
@@ -217,6 +404,9 @@ func (c *Client) GetLatestEventForIncident(ctx context.Context, incidentID strin
 }
 
 func (c *Client) AddEventToIncident(ctx context.Context, incidentID string, event *models.PodEvent, newIncident bool) error {
+	ctx, span := tracing.StartSpan(ctx, "redis.AddEventToIncident")
+	defer span.End()
+
 	if !newIncident {
 		events, err := c.client.ZRange(ctx, incidentID, 0, -1).Result()
 		if err != nil {
@@ -250,11 +440,21 @@ func (c *Client) AddEventToIncident(ctx context.Context, incidentID string, even
 		return fmt.Errorf("error adding new pod event to incident with ID: %s. Error: %w", incidentID, err)
 	}
 
+	for _, containerEvent := range event.ContainerEvents {
+		if containerEvent.LogID == "" {
+			continue
+		}
+
+		if err := c.SetLogEvent(ctx, containerEvent.LogID, event.EventID, containerEvent.Name); err != nil {
+			return err
+		}
+	}
+
 	incidentObj, _ := utils.NewIncidentFromString(incidentID)
+	expiry := c.newIncidentExpiry(incidentObj)
 
 	if newIncident {
-		// set a TTL for 2 weeks
-		_, err = c.client.ExpireAt(ctx, incidentID, incidentObj.GetTimestampAsTime().Add(time.Hour*24*14)).Result()
+		_, err = c.client.ExpireAt(ctx, incidentID, expiry).Result()
 		if err != nil {
 			return fmt.Errorf("error setting expiration to incident with ID: %s. Error: %w", incidentID, err)
 		}
@@ -267,8 +467,7 @@ func (c *Client) AddEventToIncident(ctx context.Context, incidentID string, even
 	}
 
 	if newIncident {
-		_, err = c.client.ExpireAt(ctx, fmt.Sprintf("pods:%s", incidentID),
-			incidentObj.GetTimestampAsTime().Add(time.Hour*24*14)).Result()
+		_, err = c.client.ExpireAt(ctx, fmt.Sprintf("pods:%s", incidentID), expiry).Result()
 		if err != nil {
 			return fmt.Errorf("error setting expiration for pod set for incident ID: %s. Error: %w", incidentID, err)
 		}
@@ -277,14 +476,33 @@ func (c *Client) AddEventToIncident(ctx context.Context, incidentID string, even
 		c.AppendToNotifyWorkQueue(ctx, []byte("new:"+incidentID))
 	}
 
-	return nil
+	changeType := models.IncidentChangeEventAdded
+	state := ""
+
+	if newIncident {
+		changeType = models.IncidentChangeCreated
+		state = "ONGOING"
+	}
+
+	if err := c.indexIncident(ctx, incidentID, event, state); err != nil {
+		return err
+	}
+
+	return c.publishChange(ctx, incidentID, &models.IncidentChange{
+		Type:  changeType,
+		Event: event,
+	})
 }
 
 func (c *Client) SetPodResolved(ctx context.Context, podName, incidentID string) error {
+	ctx, span := tracing.StartSpan(ctx, "redis.SetPodResolved")
+	defer span.End()
+
 	if exists, err := c.IncidentExists(ctx, incidentID); err != nil {
 		return err
 	} else if !exists {
-		return fmt.Errorf("trying to set pod resolved for non-existent incident with ID: %s", incidentID)
+		return fmt.Errorf("trying to set pod resolved for non-existent incident with ID: %s. Error: %w", incidentID,
+			porterErrors.IncidentNotFoundError)
 	}
 
 	key := fmt.Sprintf("pods:%s", incidentID)
@@ -316,16 +534,32 @@ func (c *Client) SetPodResolved(ctx context.Context, podName, incidentID string)
 		if err != nil {
 			return fmt.Errorf("error adding resolved incident to work queue with ID: %s. Error: %w", incidentID, err)
 		}
+
+		if err := c.indexIncident(ctx, incidentID, nil, "RESOLVED"); err != nil {
+			return err
+		}
+
+		return c.publishChange(ctx, incidentID, &models.IncidentChange{
+			Type: models.IncidentChangeResolved,
+		})
 	}
 
 	return nil
 }
 
 func (c *Client) SetJobIncidentResolved(ctx context.Context, incidentID string) error {
+	ctx, span := tracing.StartSpan(ctx, "redis.SetJobIncidentResolved")
+	defer span.End()
+
+	return c.setIncidentResolved(ctx, incidentID, "")
+}
+
+func (c *Client) setIncidentResolved(ctx context.Context, incidentID, user string) error {
 	if exists, err := c.IncidentExists(ctx, incidentID); err != nil {
 		return err
 	} else if !exists {
-		return fmt.Errorf("trying to set job incident resolved for non-existent incident with ID: %s", incidentID)
+		return fmt.Errorf("trying to set job incident resolved for non-existent incident with ID: %s. Error: %w", incidentID,
+			porterErrors.IncidentNotFoundError)
 	}
 
 	incidentObj, _ := utils.NewIncidentFromString(incidentID)
@@ -346,16 +580,94 @@ func (c *Client) SetJobIncidentResolved(ctx context.Context, incidentID string)
 		return fmt.Errorf("error adding resolved incident to work queue with ID: %s. Error: %w", incidentID, err)
 	}
 
-	return nil
+	if err := c.indexIncident(ctx, incidentID, nil, "RESOLVED"); err != nil {
+		return err
+	}
+
+	return c.publishChange(ctx, incidentID, &models.IncidentChange{
+		Type: models.IncidentChangeResolved,
+		User: user,
+	})
 }
 
 func (c *Client) GetIncidentDetails(ctx context.Context, incidentID string) (*models.Incident, error) {
-	if exists, err := c.IncidentExists(ctx, incidentID); err != nil {
+	ctx, span := tracing.StartSpan(ctx, "redis.GetIncidentDetails")
+	defer span.End()
+
+	incidents, err := c.GetIncidentsDetails(ctx, []string{incidentID})
+	if err != nil {
 		return nil, err
-	} else if !exists {
-		return nil, fmt.Errorf("trying to get details of non-existent incident with ID: %s", incidentID)
+	} else if len(incidents) == 0 {
+		return nil, fmt.Errorf("trying to get details of non-existent incident with ID: %s. Error: %w", incidentID,
+			porterErrors.IncidentNotFoundError)
 	}
 
+	return incidents[0], nil
+}
+
+// incidentDetailsCmds are the commands fetching the details of an incident
+type incidentDetailsCmds struct {
+	exists        *goredis.IntCmd
+	pods          *goredis.StringSliceCmd
+	latestEvent   *goredis.ZSliceCmd
+	indexedValues *goredis.SliceCmd
+	silencedBy    *goredis.StringCmd
+	ack           *goredis.StringCmd
+	resolvedBy    *goredis.StringCmd
+}
+
+// GetIncidentsDetails returns the details of the given incidents in order,
+// fetched in a single round trip. Incidents which do not exist are left out.
+func (c *Client) GetIncidentsDetails(ctx context.Context, incidentIDs []string) ([]*models.Incident, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.GetIncidentsDetails")
+	defer span.End()
+
+	cmds := make([]*incidentDetailsCmds, 0, len(incidentIDs))
+
+	_, err := c.client.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		for _, incidentID := range incidentIDs {
+			cmds = append(cmds, &incidentDetailsCmds{
+				exists: pipe.Exists(ctx, incidentID),
+				pods:   pipe.SMembers(ctx, fmt.Sprintf("pods:%s", incidentID)),
+				latestEvent: pipe.ZRangeArgsWithScores(ctx, goredis.ZRangeArgs{
+					Key:   incidentID,
+					Start: 0,
+					Stop:  0,
+					Rev:   true,
+				}),
+				indexedValues: pipe.HMGet(ctx, fmt.Sprintf("incident_index_values:%s", incidentID),
+					IndexFieldFingerprint, IndexFieldAfterRollout),
+				silencedBy: pipe.Get(ctx, fmt.Sprintf("silenced:%s", incidentID)),
+				ack:        pipe.Get(ctx, fmt.Sprintf("ack:%s", incidentID)),
+				resolvedBy: pipe.Get(ctx, fmt.Sprintf("resolved_by:%s", incidentID)),
+			})
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, goredis.Nil) {
+		return nil, fmt.Errorf("error fetching details of incidents. Error: %w", err)
+	}
+
+	incidents := make([]*models.Incident, 0, len(incidentIDs))
+
+	for i, incidentID := range incidentIDs {
+		if cmds[i].exists.Val() == 0 {
+			continue
+		}
+
+		incident, err := newIncidentDetails(incidentID, cmds[i])
+		if err != nil {
+			return nil, err
+		}
+
+		incidents = append(incidents, incident)
+	}
+
+	return incidents, nil
+}
+
+func newIncidentDetails(incidentID string, cmds *incidentDetailsCmds) (*models.Incident, error) {
 	incidentObj, err := utils.NewIncidentFromString(incidentID)
 	if err != nil {
 		return nil, fmt.Errorf("error getting incident object for incident ID: %s. Error: %w", incidentID, err)
@@ -364,31 +676,108 @@ func (c *Client) GetIncidentDetails(ctx context.Context, incidentID string) (*mo
 	incident := &models.Incident{
 		ID:          incidentID,
 		ReleaseName: incidentObj.GetReleaseName(),
+		Namespace:   incidentObj.GetNamespace(),
 		CreatedAt:   incidentObj.GetTimestamp(),
 	}
 
-	resolved, err := c.IsIncidentResolved(ctx, incidentID)
-	if err != nil {
+	if err := cmds.pods.Err(); err != nil {
 		return nil, fmt.Errorf("error checking if incident is resolved with incidentID: %s. Error: %w", incidentID, err)
 	}
 
-	if resolved {
+	if len(cmds.pods.Val()) == 0 {
 		incident.LatestState = "RESOLVED"
 	} else {
 		incident.LatestState = "ONGOING"
 	}
 
-	latestEvent, err := c.GetLatestEventForIncident(ctx, incidentID)
-	if err != nil {
+	latestEvent := &models.PodEvent{}
+
+	if err := cmds.latestEvent.Err(); err != nil {
 		return nil, fmt.Errorf("error fetching latest event with incidentID: %s. Error: %w", incidentID, err)
+	} else if data := cmds.latestEvent.Val(); len(data) > 0 {
+		payload, ok := data[0].Member.(string)
+		if !ok {
+			return nil, fmt.Errorf("error casting Redis Z Member to bytearray for incident ID: %s with score: %f",
+				incidentID, data[0].Score)
+		}
+
+		if err := json.Unmarshal([]byte(payload), latestEvent); err != nil {
+			return nil, fmt.Errorf("error unmarshalling event to json for incident ID: %s with score: %f. Error: %w",
+				incidentID, data[0].Score, err)
+		}
 	}
 
 	incident.ChartName = latestEvent.ChartName
 	incident.UpdatedAt = latestEvent.Timestamp
+	incident.OwnerKind = latestEvent.OwnerType
+	incident.Severity = latestEvent.Severity
+	incident.LatestFilterReason = latestEvent.FilterReason
+	incident.NodeName = latestEvent.NodeName
+	incident.Labels = latestEvent.Labels
+
+	for _, containerEvent := range latestEvent.ContainerEvents {
+		if containerEvent.Image != "" {
+			incident.Images = append(incident.Images, containerEvent.Image)
+		}
+	}
+
+	sort.Strings(incident.Images)
+
+	if err := cmds.indexedValues.Err(); err != nil {
+		return nil, fmt.Errorf("error fetching indexed values of incident with ID: %s. Error: %w", incidentID, err)
+	}
+
+	// missing fields are nil
+	indexedValues := cmds.indexedValues.Val()
+
+	if encoded, ok := indexedValues[0].(string); ok {
+		if values, err := decodeIndexedValues(encoded); err == nil && len(values) > 0 {
+			incident.Fingerprint = values[0]
+		}
+	}
+
+	if encoded, ok := indexedValues[1].(string); ok {
+		values, err := decodeIndexedValues(encoded)
+		incident.AfterRollout = err == nil && containsString(values, "true")
+	}
+
+	silencedBy, err := cmds.silencedBy.Result()
+	if err != nil && !errors.Is(err, goredis.Nil) {
+		return nil, fmt.Errorf("error checking if incident with ID: %s is silenced. Error: %w", incidentID, err)
+	}
+
+	incident.Silenced = silencedBy != ""
+	incident.SilencedBy = silencedBy
+
+	ackJSON, err := cmds.ack.Result()
+	if err != nil && !errors.Is(err, goredis.Nil) {
+		return nil, fmt.Errorf("error fetching acknowledgement for incident ID: %s. Error: %w", incidentID, err)
+	} else if ackJSON != "" {
+		ack := &models.IncidentAcknowledgement{}
+
+		if err := json.Unmarshal([]byte(ackJSON), ack); err != nil {
+			return nil, fmt.Errorf("error unmarshalling acknowledgement for incident ID: %s. Error: %w", incidentID, err)
+		}
+
+		incident.Acknowledged = true
+		incident.AcknowledgedBy = ack.User
+		incident.AcknowledgedAt = ack.Timestamp
+	}
 
 	if incident.LatestState == "RESOLVED" {
+		resolvedBy, err := cmds.resolvedBy.Result()
+		if err != nil && !errors.Is(err, goredis.Nil) {
+			return nil, fmt.Errorf("error fetching resolver of incident with ID: %s. Error: %w", incidentID, err)
+		}
+
+		incident.ResolvedBy = resolvedBy
 		incident.LatestReason = "Resolved"
-		incident.LatestMessage = "This incident has been resolved"
+
+		if resolvedBy != "" {
+			incident.LatestMessage = fmt.Sprintf("This incident has been manually resolved by %s", resolvedBy)
+		} else {
+			incident.LatestMessage = "This incident has been resolved"
+		}
 	} else {
 		incident.LatestReason = latestEvent.Reason
 		incident.LatestMessage = latestEvent.Message
@@ -398,6 +787,9 @@ func (c *Client) GetIncidentDetails(ctx context.Context, incidentID string) (*mo
 }
 
 func (c *Client) IsIncidentResolved(ctx context.Context, incidentID string) (bool, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.IsIncidentResolved")
+	defer span.End()
+
 	pods, err := c.client.SMembers(ctx, fmt.Sprintf("pods:%s", incidentID)).Result()
 	if err != nil {
 		return false, fmt.Errorf("error getting pod members for incident ID: %s. Error: %w", incidentID, err)
@@ -411,7 +803,10 @@ func (c *Client) IsIncidentResolved(ctx context.Context, incidentID string) (boo
 }
 
 func (c *Client) GetAllIncidents(ctx context.Context) ([]string, error) {
-	incidents, err := c.client.Keys(ctx, "incident:*:*:*").Result()
+	ctx, span := tracing.StartSpan(ctx, "redis.GetAllIncidents")
+	defer span.End()
+
+	incidents, err := c.keys(ctx, "incident:*:*:*")
 	if err != nil {
 		return nil, err
 	}
@@ -427,7 +822,10 @@ func (c *Client) GetAllIncidents(ctx context.Context) ([]string, error) {
 }
 
 func (c *Client) GetIncidentsByReleaseNamespace(ctx context.Context, releaseName, namespace string) ([]string, error) {
-	incidents, err := c.client.Keys(ctx, fmt.Sprintf("incident:%s:%s:*", releaseName, namespace)).Result()
+	ctx, span := tracing.StartSpan(ctx, "redis.GetIncidentsByReleaseNamespace")
+	defer span.End()
+
+	incidents, err := c.keys(ctx, fmt.Sprintf("incident:%s:%s:*", releaseName, namespace))
 	if err != nil {
 		return nil, err
 	}
@@ -443,6 +841,9 @@ func (c *Client) GetIncidentsByReleaseNamespace(ctx context.Context, releaseName
 }
 
 func (c *Client) GetIncidentEventsByID(ctx context.Context, incidentID string) ([]*models.PodEvent, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.GetIncidentEventsByID")
+	defer span.End()
+
 	payload, err := c.client.ZRangeArgsWithScores(ctx, goredis.ZRangeArgs{
 		Key:   incidentID,
 		Start: 0,
@@ -477,13 +878,23 @@ func (c *Client) GetIncidentEventsByID(ctx context.Context, incidentID string) (
 	return events, nil
 }
 
-func (c *Client) AddLogs(ctx context.Context, incidentID, strLogs string) (string, error) {
+// AddLogs stores the logs captured for a container of an incident, and
+// returns their ID
+func (c *Client) AddLogs(ctx context.Context, incidentID, containerName, strLogs string) (string, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.AddLogs")
+	defer span.End()
+
 	score := time.Now().Unix()
 
-	logID := fmt.Sprintf("log:%s:%d", incidentID, score)
+	logID := fmt.Sprintf("log:%s:%d:%s", incidentID, score, containerName)
 
-	if _, err := c.client.Set(ctx, logID, strLogs, time.Hour*24*14).Result(); err != nil {
-		return "", errors.New("error adding logs")
+	expiry, err := c.getLogExpiry(ctx, incidentID, time.Unix(score, 0))
+	if err != nil {
+		return "", err
+	}
+
+	if err := c.setLogContents(ctx, logID, strLogs, time.Until(expiry)); err != nil {
+		return "", err
 	}
 
 	logsID := fmt.Sprintf("logs:%s", incidentID)
@@ -500,86 +911,125 @@ func (c *Client) AddLogs(ctx context.Context, incidentID, strLogs string) (strin
 			logID, incidentID, err)
 	}
 
-	if exists, err := c.client.Exists(ctx, logsID).Result(); err != nil {
-		return "", fmt.Errorf("error checking existence of logs set for incident ID: %s. Error: %w",
+	incidentExpiry, err := c.getIncidentExpiry(ctx, incidentID)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := c.client.ExpireAt(ctx, logsID, incidentExpiry).Result(); err != nil {
+		return "", fmt.Errorf("error setting expiration time for logs set for incident ID: %s. Error: %w",
 			incidentID, err)
-	} else if exists == 0 {
-		incidentObj, err := utils.NewIncidentFromString(incidentID)
-		if err != nil {
-			return "", fmt.Errorf("error converting incident from string to object while creating new logs set for incident ID: %s. Error: %w",
-				incidentID, err)
-		}
+	}
 
-		if _, err := c.client.ExpireAt(ctx, logsID, incidentObj.GetTimestampAsTime().Add(time.Hour*24*14)).Result(); err != nil {
-			return "", fmt.Errorf("error setting expiration time for logs set for incident ID: %s. Error: %w",
-				incidentID, err)
-		}
+	incidentObj, err := utils.NewIncidentFromString(incidentID)
+	if err != nil {
+		return "", fmt.Errorf("error getting incident object for incident ID: %s. Error: %w", incidentID, err)
+	}
+
+	if err := c.indexLogs(ctx, logID, incidentObj.GetNamespace(), score, utils.TokenizeLogs(strLogs)); err != nil {
+		return "", err
 	}
 
 	return logID, nil
 }
 
-func (c *Client) DuplicateLogs(ctx context.Context, incidentID, strLogs string) (bool, error) {
-	logsID := fmt.Sprintf("logs:%s", incidentID)
-
-	// check if any logs exist for this incident
-	if exists, err := c.client.Exists(ctx, logsID).Result(); err != nil {
-		return false, fmt.Errorf("error checking for logs set existence for incident ID: %s while checking for duplicate logs. Error: %w",
-			incidentID, err)
-	} else if exists == 0 {
-		return false, nil
+// GetDuplicateContainerLogs returns the ID of the logs last captured for a
+// container of an incident when the logs of its previous and current runs are
+// the same as then, or an empty ID when they changed or are no longer stored
+func (c *Client) GetDuplicateContainerLogs(
+	ctx context.Context, incidentID, containerName, previousLogs, currentLogs string,
+) (string, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.GetDuplicateContainerLogs")
+	defer span.End()
+
+	values, err := c.client.HMGet(ctx, containerLogsKey(incidentID),
+		containerLogsField(containerName, "previous"),
+		containerLogsField(containerName, "current"),
+		containerLogsField(containerName, "log_id"),
+	).Result()
+	if err != nil {
+		return "", fmt.Errorf("error getting logs of container %s for incident ID: %s. Error: %w",
+			containerName, incidentID, err)
 	}
 
-	previousLogID, err := c.client.ZRangeArgsWithScores(ctx, goredis.ZRangeArgs{
-		Key:   logsID,
-		Start: 0,
-		Stop:  1,
-		Rev:   true,
-	}).Result()
-	if err != nil {
-		return false, fmt.Errorf("error getting log IDs from logs set for incident ID: %s. Error: %w",
-			incidentID, err)
+	previousRef, _ := values[0].(string)
+	currentRef, _ := values[1].(string)
+	logID, _ := values[2].(string)
+
+	if logID == "" || previousRef != hashLogs(previousLogs) || currentRef != hashLogs(currentLogs) {
+		return "", nil
 	}
 
-	if len(previousLogID) == 0 {
-		// FIXME: do we need to check for existence before in this case?
-		return false, nil
+	// the logs may have expired before their incident
+	if exists, err := c.client.Exists(ctx, logID).Result(); err != nil {
+		return "", fmt.Errorf("error checking for logs with ID: %s while checking for duplicate logs. Error: %w",
+			logID, err)
+	} else if exists == 0 {
+		return "", nil
 	}
 
-	logID, ok := previousLogID[0].Member.(string)
-	if !ok {
-		return false, fmt.Errorf("error converting logs set member to string for incident ID: %s", incidentID)
+	return logID, nil
+}
+
+// SetContainerLogs records the logs last captured for a container of an
+// incident, by the hashes of the logs of its previous and current runs, so
+// that they are only stored again once they change
+func (c *Client) SetContainerLogs(
+	ctx context.Context, incidentID, containerName, previousLogs, currentLogs, logID string,
+) error {
+	ctx, span := tracing.StartSpan(ctx, "redis.SetContainerLogs")
+	defer span.End()
+
+	key := containerLogsKey(incidentID)
+
+	if _, err := c.client.HSet(ctx, key,
+		containerLogsField(containerName, "previous"), hashLogs(previousLogs),
+		containerLogsField(containerName, "current"), hashLogs(currentLogs),
+		containerLogsField(containerName, "log_id"), logID,
+	).Result(); err != nil {
+		return fmt.Errorf("error setting logs of container %s for incident ID: %s. Error: %w",
+			containerName, incidentID, err)
 	}
 
-	log, err := c.client.Get(ctx, logID).Result()
+	incidentExpiry, err := c.getIncidentExpiry(ctx, incidentID)
 	if err != nil {
-		return false, fmt.Errorf("error getting logs with ID: %s while checking for duplicate logs. Error: %w",
-			logID, err)
+		return err
 	}
 
-	if log == strLogs {
-		return true, nil
+	if _, err := c.client.ExpireAt(ctx, key, incidentExpiry).Result(); err != nil {
+		return fmt.Errorf("error setting expiration time for container logs of incident ID: %s. Error: %w",
+			incidentID, err)
 	}
 
-	return false, nil
+	return nil
+}
+
+func containerLogsKey(incidentID string) string {
+	return fmt.Sprintf("container_logs:%s", incidentID)
+}
+
+func containerLogsField(containerName, field string) string {
+	return fmt.Sprintf("%s:%s", containerName, field)
 }
 
 func (c *Client) GetLogs(ctx context.Context, logID string) (string, error) {
-	if exists, err := c.client.Exists(ctx, logID).Result(); err != nil {
-		return "", fmt.Errorf("error fetching logs with ID: %s", logID)
-	} else if exists == 0 {
-		return "", fmt.Errorf("no such logs with ID: %s", logID)
-	}
+	ctx, span := tracing.StartSpan(ctx, "redis.GetLogs")
+	defer span.End()
 
-	logs, err := c.client.Get(ctx, logID).Result()
-	if err != nil {
+	value, err := c.client.Get(ctx, logID).Result()
+	if errors.Is(err, goredis.Nil) {
+		return "", fmt.Errorf("%w: %s", porterErrors.LogsNotFoundError, logID)
+	} else if err != nil {
 		return "", fmt.Errorf("error fetching logs with ID: %s. Error: %w", logID, err)
 	}
 
-	return logs, nil
+	return c.getLogContents(ctx, logID, value)
 }
 
 func (c *Client) GetActiveIncident(ctx context.Context, releaseName, namespace string) (string, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.GetActiveIncident")
+	defer span.End()
+
 	key := fmt.Sprintf("active_incident:%s:%s", releaseName, namespace)
 
 	incidentID, err := c.client.Get(ctx, key).Result()
@@ -592,6 +1042,9 @@ func (c *Client) GetActiveIncident(ctx context.Context, releaseName, namespace s
 }
 
 func (c *Client) ActiveIncidentExists(ctx context.Context, releaseName, namespace string) (bool, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.ActiveIncidentExists")
+	defer span.End()
+
 	key := fmt.Sprintf("active_incident:%s:%s", releaseName, namespace)
 
 	exists, err := c.client.Exists(ctx, key).Result()
@@ -608,11 +1061,14 @@ func (c *Client) ActiveIncidentExists(ctx context.Context, releaseName, namespac
 }
 
 func (c *Client) CreateActiveIncident(ctx context.Context, releaseName, namespace string) (string, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.CreateActiveIncident")
+	defer span.End()
+
 	key := fmt.Sprintf("active_incident:%s:%s", releaseName, namespace)
 
 	newIncident := utils.NewIncident(releaseName, namespace, time.Now().Unix())
 
-	_, err := c.client.Set(ctx, key, newIncident.ToString(), time.Hour*24*14).Result()
+	_, err := c.client.Set(ctx, key, newIncident.ToString(), c.retention.IncidentRetention(namespace)).Result()
 	if err != nil {
 		return "", fmt.Errorf("error creating new active incident for release %s with namespace %s. Error: %w",
 			releaseName, namespace, err)
@@ -620,3 +1076,379 @@ func (c *Client) CreateActiveIncident(ctx context.Context, releaseName, namespac
 
 	return newIncident.ToString(), nil
 }
+
+// SetIncidentSilenced marks the incident as silenced by the given silence, which
+// suppresses all further notifications for it
+func (c *Client) SetIncidentSilenced(ctx context.Context, incidentID, silenceName string) error {
+	ctx, span := tracing.StartSpan(ctx, "redis.SetIncidentSilenced")
+	defer span.End()
+
+	expiry, err := c.getIncidentExpiry(ctx, incidentID)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.client.Set(ctx, fmt.Sprintf("silenced:%s", incidentID), silenceName, time.Until(expiry)).Result()
+	if err != nil {
+		return fmt.Errorf("error setting incident with ID: %s as silenced. Error: %w", incidentID, err)
+	}
+
+	return c.publishChange(ctx, incidentID, &models.IncidentChange{
+		Type:  models.IncidentChangeStateChanged,
+		State: "silenced",
+	})
+}
+
+// GetIncidentSilence returns the name of the silence which silenced the
+// incident, or an empty string if the incident is not silenced
+func (c *Client) GetIncidentSilence(ctx context.Context, incidentID string) (string, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.GetIncidentSilence")
+	defer span.End()
+
+	silenceName, err := c.client.Get(ctx, fmt.Sprintf("silenced:%s", incidentID)).Result()
+	if errors.Is(err, goredis.Nil) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("error checking if incident with ID: %s is silenced. Error: %w", incidentID, err)
+	}
+
+	return silenceName, nil
+}
+
+// getLogExpiry returns the time at which logs captured for an incident
+// expire, following the retention policy of its namespace. Logs never outlive
+// their incident.
+func (c *Client) getLogExpiry(ctx context.Context, incidentID string, capturedAt time.Time) (time.Time, error) {
+	incidentObj, err := utils.NewIncidentFromString(incidentID)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error getting incident object for incident ID: %s. Error: %w", incidentID, err)
+	}
+
+	incidentExpiry, err := c.getIncidentExpiry(ctx, incidentID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	expiry := capturedAt.Add(c.retention.LogRetention(incidentObj.GetNamespace()))
+	if expiry.After(incidentExpiry) {
+		return incidentExpiry, nil
+	}
+
+	return expiry, nil
+}
+
+// newIncidentExpiry returns the time at which a new incident expires, following
+// the retention policy of its namespace
+func (c *Client) newIncidentExpiry(incidentObj *utils.Incident) time.Time {
+	return incidentObj.GetTimestampAsTime().Add(c.retention.IncidentRetention(incidentObj.GetNamespace()))
+}
+
+// getIncidentExpiry returns the time at which all the keys of an incident
+// expire, which is the expiry of the incident itself. Incidents restored from
+// the archive expire later than their retention.
+func (c *Client) getIncidentExpiry(ctx context.Context, incidentID string) (time.Time, error) {
+	incidentObj, err := utils.NewIncidentFromString(incidentID)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error getting incident object for incident ID: %s. Error: %w", incidentID, err)
+	}
+
+	ttl, err := c.client.PTTL(ctx, incidentID).Result()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error getting expiration of incident ID: %s. Error: %w", incidentID, err)
+	}
+
+	if ttl <= 0 {
+		return c.newIncidentExpiry(incidentObj), nil
+	}
+
+	return time.Now().Add(ttl), nil
+}
+
+func (c *Client) checkIncidentExists(ctx context.Context, incidentID string) error {
+	if exists, err := c.IncidentExists(ctx, incidentID); err != nil {
+		return err
+	} else if !exists {
+		return fmt.Errorf("%w: %s", porterErrors.IncidentNotFoundError, incidentID)
+	}
+
+	return nil
+}
+
+// AcknowledgeIncident records that the user is looking into the incident
+func (c *Client) AcknowledgeIncident(ctx context.Context, incidentID, user string) error {
+	ctx, span := tracing.StartSpan(ctx, "redis.AcknowledgeIncident")
+	defer span.End()
+
+	if err := c.checkIncidentExists(ctx, incidentID); err != nil {
+		return err
+	}
+
+	if resolved, err := c.IsIncidentResolved(ctx, incidentID); err != nil {
+		return err
+	} else if resolved {
+		return fmt.Errorf("%w: %s", porterErrors.IncidentAlreadyResolvedError, incidentID)
+	}
+
+	expiry, err := c.getIncidentExpiry(ctx, incidentID)
+	if err != nil {
+		return err
+	}
+
+	ackJSON, err := json.Marshal(&models.IncidentAcknowledgement{
+		User:      user,
+		Timestamp: time.Now().Unix(),
+	})
+	if err != nil {
+		return fmt.Errorf("error marshalling acknowledgement for incident ID: %s. Error: %w", incidentID, err)
+	}
+
+	if _, err := c.client.Set(ctx, fmt.Sprintf("ack:%s", incidentID), ackJSON, time.Until(expiry)).Result(); err != nil {
+		return fmt.Errorf("error acknowledging incident with ID: %s. Error: %w", incidentID, err)
+	}
+
+	if err := c.AppendToNotifyWorkQueue(ctx, []byte("acknowledged:"+incidentID)); err != nil {
+		return fmt.Errorf("error adding acknowledged incident to work queue with ID: %s. Error: %w", incidentID, err)
+	}
+
+	return c.publishChange(ctx, incidentID, &models.IncidentChange{
+		Type:  models.IncidentChangeStateChanged,
+		State: "acknowledged",
+		User:  user,
+	})
+}
+
+func (c *Client) GetIncidentAcknowledgement(ctx context.Context, incidentID string) (*models.IncidentAcknowledgement, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.GetIncidentAcknowledgement")
+	defer span.End()
+
+	ackJSON, err := c.client.Get(ctx, fmt.Sprintf("ack:%s", incidentID)).Result()
+	if errors.Is(err, goredis.Nil) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error fetching acknowledgement for incident ID: %s. Error: %w", incidentID, err)
+	}
+
+	ack := &models.IncidentAcknowledgement{}
+
+	if err := json.Unmarshal([]byte(ackJSON), ack); err != nil {
+		return nil, fmt.Errorf("error unmarshalling acknowledgement for incident ID: %s. Error: %w", incidentID, err)
+	}
+
+	return ack, nil
+}
+
+// ResolveIncident manually resolves an incident, regardless of the pods which
+// are still marked as affected by it
+func (c *Client) ResolveIncident(ctx context.Context, incidentID, user string) error {
+	ctx, span := tracing.StartSpan(ctx, "redis.ResolveIncident")
+	defer span.End()
+
+	if err := c.checkIncidentExists(ctx, incidentID); err != nil {
+		return err
+	}
+
+	if resolved, err := c.IsIncidentResolved(ctx, incidentID); err != nil {
+		return err
+	} else if resolved {
+		return fmt.Errorf("%w: %s", porterErrors.IncidentAlreadyResolvedError, incidentID)
+	}
+
+	expiry, err := c.getIncidentExpiry(ctx, incidentID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.client.Set(ctx, fmt.Sprintf("resolved_by:%s", incidentID), user, time.Until(expiry)).Result(); err != nil {
+		return fmt.Errorf("error setting resolver of incident with ID: %s. Error: %w", incidentID, err)
+	}
+
+	return c.setIncidentResolved(ctx, incidentID, user)
+}
+
+// ReopenIncident marks a resolved incident as ongoing again, with all the pods
+// seen in its events as affected. It fails if another incident has been opened
+// for the same release in the meantime.
+func (c *Client) ReopenIncident(ctx context.Context, incidentID, user string) error {
+	ctx, span := tracing.StartSpan(ctx, "redis.ReopenIncident")
+	defer span.End()
+
+	if err := c.checkIncidentExists(ctx, incidentID); err != nil {
+		return err
+	}
+
+	if resolved, err := c.IsIncidentResolved(ctx, incidentID); err != nil {
+		return err
+	} else if !resolved {
+		return fmt.Errorf("%w: %s", porterErrors.IncidentNotResolvedError, incidentID)
+	}
+
+	incidentObj, err := utils.NewIncidentFromString(incidentID)
+	if err != nil {
+		return fmt.Errorf("error getting incident object for incident ID: %s. Error: %w", incidentID, err)
+	}
+
+	expiry, err := c.getIncidentExpiry(ctx, incidentID)
+	if err != nil {
+		return err
+	}
+
+	activeKey := fmt.Sprintf("active_incident:%s:%s", incidentObj.GetReleaseName(), incidentObj.GetNamespace())
+
+	// only claim the active incident slot if it is free
+	ok, err := c.client.SetNX(ctx, activeKey, incidentID, time.Until(expiry)).Result()
+	if err != nil {
+		return fmt.Errorf("error reopening incident with ID: %s. Error: %w", incidentID, err)
+	} else if !ok {
+		return fmt.Errorf("%w: %s", porterErrors.ActiveIncidentExistsError, incidentID)
+	}
+
+	events, err := c.GetIncidentEventsByID(ctx, incidentID)
+	if err != nil {
+		return err
+	}
+
+	podsKey := fmt.Sprintf("pods:%s", incidentID)
+
+	for _, event := range events {
+		if _, err := c.client.SAdd(ctx, podsKey, event.PodName).Result(); err != nil {
+			return fmt.Errorf("error adding pod: %s to pod set with incident ID: %s. Error: %w",
+				event.PodName, incidentID, err)
+		}
+	}
+
+	if _, err := c.client.ExpireAt(ctx, podsKey, expiry).Result(); err != nil {
+		return fmt.Errorf("error setting expiration for pod set for incident ID: %s. Error: %w", incidentID, err)
+	}
+
+	// the keys are deleted one at a time, as they can be on different nodes
+	// of a cluster
+	for _, key := range []string{fmt.Sprintf("resolved_by:%s", incidentID), fmt.Sprintf("ack:%s", incidentID)} {
+		if _, err := c.client.Del(ctx, key).Result(); err != nil {
+			return fmt.Errorf("error clearing resolution of incident with ID: %s. Error: %w", incidentID, err)
+		}
+	}
+
+	if err := c.AppendToNotifyWorkQueue(ctx, []byte("reopened:"+incidentID)); err != nil {
+		return fmt.Errorf("error adding reopened incident to work queue with ID: %s. Error: %w", incidentID, err)
+	}
+
+	if err := c.indexIncident(ctx, incidentID, nil, "ONGOING"); err != nil {
+		return err
+	}
+
+	return c.publishChange(ctx, incidentID, &models.IncidentChange{
+		Type:  models.IncidentChangeStateChanged,
+		State: "reopened",
+		User:  user,
+	})
+}
+
+func (c *Client) AddIncidentComment(ctx context.Context, incidentID, user, body string) (*models.IncidentComment, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.AddIncidentComment")
+	defer span.End()
+
+	if err := c.checkIncidentExists(ctx, incidentID); err != nil {
+		return nil, err
+	}
+
+	expiry, err := c.getIncidentExpiry(ctx, incidentID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	comment := &models.IncidentComment{
+		ID:         strconv.FormatInt(now.UnixNano(), 10),
+		IncidentID: incidentID,
+		User:       user,
+		Body:       body,
+		Timestamp:  now.Unix(),
+	}
+
+	commentJSON, err := json.Marshal(comment)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling comment for incident ID: %s. Error: %w", incidentID, err)
+	}
+
+	key := fmt.Sprintf("comments:%s", incidentID)
+
+	if _, err := c.client.HSet(ctx, key, comment.ID, commentJSON).Result(); err != nil {
+		return nil, fmt.Errorf("error adding comment to incident with ID: %s. Error: %w", incidentID, err)
+	}
+
+	if _, err := c.client.ExpireAt(ctx, key, expiry).Result(); err != nil {
+		return nil, fmt.Errorf("error setting expiration for comments of incident ID: %s. Error: %w", incidentID, err)
+	}
+
+	if err := c.AppendToNotifyWorkQueue(ctx, []byte(fmt.Sprintf("commented:%s#%s", incidentID, comment.ID))); err != nil {
+		return nil, fmt.Errorf("error adding comment to work queue for incident ID: %s. Error: %w", incidentID, err)
+	}
+
+	if err := c.publishChange(ctx, incidentID, &models.IncidentChange{
+		Type:  models.IncidentChangeStateChanged,
+		State: "commented",
+		User:  user,
+	}); err != nil {
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+// GetIncidentComments returns the comments of an incident, oldest first
+func (c *Client) GetIncidentComments(ctx context.Context, incidentID string) ([]*models.IncidentComment, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.GetIncidentComments")
+	defer span.End()
+
+	payload, err := c.client.HGetAll(ctx, fmt.Sprintf("comments:%s", incidentID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching comments for incident ID: %s. Error: %w", incidentID, err)
+	}
+
+	comments := make([]*models.IncidentComment, 0, len(payload))
+
+	for id, commentJSON := range payload {
+		comment := &models.IncidentComment{}
+
+		if err := json.Unmarshal([]byte(commentJSON), comment); err != nil {
+			return nil, fmt.Errorf("error unmarshalling comment with ID: %s for incident ID: %s. Error: %w",
+				id, incidentID, err)
+		}
+
+		comments = append(comments, comment)
+	}
+
+	sort.SliceStable(comments, func(i, j int) bool {
+		return comments[i].ID < comments[j].ID
+	})
+
+	return comments, nil
+}
+
+func (c *Client) GetIncidentComment(ctx context.Context, incidentID, commentID string) (*models.IncidentComment, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.GetIncidentComment")
+	defer span.End()
+
+	commentJSON, err := c.client.HGet(ctx, fmt.Sprintf("comments:%s", incidentID), commentID).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching comment with ID: %s for incident ID: %s. Error: %w", commentID, incidentID, err)
+	}
+
+	comment := &models.IncidentComment{}
+
+	if err := json.Unmarshal([]byte(commentJSON), comment); err != nil {
+		return nil, fmt.Errorf("error unmarshalling comment with ID: %s for incident ID: %s. Error: %w",
+			commentID, incidentID, err)
+	}
+
+	return comment, nil
+}
+
+// IsUnavailableError returns true if the error was caused by Redis being
+// unreachable
+func IsUnavailableError(err error) bool {
+	var netErr net.Error
+
+	return errors.As(err, &netErr) || errors.Is(err, goredis.ErrClosed)
+}
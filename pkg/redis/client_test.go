@@ -0,0 +1,38 @@
+package redis
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAddLogsOfContainers(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+
+	incidentID, err := c.CreateActiveIncident(ctx, "web", "prod")
+	if err != nil {
+		t.Fatalf("unexpected error creating incident: %v", err)
+	}
+
+	// the containers of a pod are captured within the same second
+	logIDs := make(map[string]string)
+
+	for _, containerName := range []string{"web", "sidecar"} {
+		logID, err := c.AddLogs(ctx, incidentID, containerName, "panic: "+containerName)
+		if err != nil {
+			t.Fatalf("unexpected error adding logs: %v", err)
+		}
+
+		logIDs[containerName] = logID
+	}
+
+	if logIDs["web"] == logIDs["sidecar"] {
+		t.Fatalf("expected the logs of each container to have their own ID, got %s", logIDs["web"])
+	}
+
+	for containerName, logID := range logIDs {
+		if logs, err := c.GetLogs(ctx, logID); err != nil || logs != "panic: "+containerName {
+			t.Errorf("expected the logs of %s, got %q, %v", containerName, logs, err)
+		}
+	}
+}
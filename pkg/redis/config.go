@@ -0,0 +1,261 @@
+package redis
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/porter-dev/porter-agent/pkg/retention"
+	"github.com/spf13/viper"
+)
+
+// The modes of connecting to the store, set by the scheme of the store URL
+const (
+	ModeStandalone = "standalone"
+	ModeSentinel   = "sentinel"
+	ModeCluster    = "cluster"
+)
+
+var (
+	// URL of the store, which takes precedence over the host and port
+	storeURL string
+
+	storeHost string
+	storePort string
+	storeDB   int
+
+	// credentials, which override the ones in the URL so that they can be
+	// read from a secret
+	storeUsername    string
+	storePassword    string
+	sentinelPassword string
+
+	tlsCAFile             string
+	tlsServerName         string
+	tlsInsecureSkipVerify bool
+)
+
+func init() {
+	viper.SetDefault("REDIS_HOST", "porter-redis-master")
+	viper.SetDefault("REDIS_PORT", "6379")
+	viper.AutomaticEnv()
+
+	storeURL = viper.GetString("REDIS_URL")
+	storeHost = viper.GetString("REDIS_HOST")
+	storePort = viper.GetString("REDIS_PORT")
+	storeDB = viper.GetInt("REDIS_DB")
+
+	storeUsername = viper.GetString("REDIS_USERNAME")
+	storePassword = viper.GetString("REDIS_PASSWORD")
+	sentinelPassword = viper.GetString("REDIS_SENTINEL_PASSWORD")
+
+	tlsCAFile = viper.GetString("REDIS_TLS_CA_FILE")
+	tlsServerName = viper.GetString("REDIS_TLS_SERVER_NAME")
+	tlsInsecureSkipVerify = viper.GetBool("REDIS_TLS_INSECURE_SKIP_VERIFY")
+}
+
+// Config describes how to connect to the store, and how long the store keeps
+// incidents and logs. The default retention policy is used when Retention is
+// nil.
+type Config struct {
+	Mode      string
+	Options   *goredis.UniversalOptions
+	Retention *retention.Policy
+}
+
+// ConfigFromEnv reads the store config from REDIS_URL, or from REDIS_HOST,
+// REDIS_PORT and REDIS_DB when no URL is set. Credentials and TLS settings
+// are read from their own variables, and the retention policy from the
+// RETENTION_* variables.
+func ConfigFromEnv() (*Config, error) {
+	rawURL := storeURL
+
+	if rawURL == "" {
+		rawURL = fmt.Sprintf("redis://%s/%d", net.JoinHostPort(storeHost, storePort), storeDB)
+	}
+
+	config, err := ParseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Retention, err = retention.LoadPolicy(); err != nil {
+		return nil, err
+	}
+
+	opts := config.Options
+
+	if storeUsername != "" {
+		opts.Username = storeUsername
+	}
+
+	if storePassword != "" {
+		opts.Password = storePassword
+	}
+
+	if sentinelPassword != "" {
+		opts.SentinelPassword = sentinelPassword
+	}
+
+	if opts.TLSConfig == nil && (tlsCAFile != "" || tlsInsecureSkipVerify) {
+		opts.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	if opts.TLSConfig != nil {
+		if tlsServerName != "" {
+			opts.TLSConfig.ServerName = tlsServerName
+		}
+
+		opts.TLSConfig.InsecureSkipVerify = tlsInsecureSkipVerify
+
+		if tlsCAFile != "" {
+			caPEM, err := ioutil.ReadFile(tlsCAFile)
+			if err != nil {
+				return nil, fmt.Errorf("error reading redis CA file %s. Error: %w", tlsCAFile, err)
+			}
+
+			rootCAs := x509.NewCertPool()
+			if !rootCAs.AppendCertsFromPEM(caPEM) {
+				return nil, fmt.Errorf("no certificates found in redis CA file %s", tlsCAFile)
+			}
+
+			opts.TLSConfig.RootCAs = rootCAs
+		}
+	}
+
+	return config, nil
+}
+
+// ParseURL parses a store URL, which is one of:
+//
+//	redis://[user[:password]@]host[:port][/db]
+//	redis+sentinel://[user[:password]@]host[:port][,host[:port]...][/db]?master=<name>
+//	redis+cluster://[user[:password]@]host[:port][,host[:port]...]
+//
+// The schemes starting with rediss instead of redis connect over TLS.
+func ParseURL(rawURL string) (*Config, error) {
+	hosts, rawURL := splitHosts(rawURL)
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL. Error: %w", err)
+	}
+
+	config := &Config{
+		Options: &goredis.UniversalOptions{},
+	}
+
+	opts := config.Options
+
+	scheme := u.Scheme
+	defaultPort := "6379"
+
+	switch scheme {
+	case "redis", "rediss":
+		config.Mode = ModeStandalone
+	case "redis+sentinel", "rediss+sentinel":
+		config.Mode = ModeSentinel
+		defaultPort = "26379"
+	case "redis+cluster", "rediss+cluster":
+		config.Mode = ModeCluster
+	default:
+		return nil, fmt.Errorf("invalid redis URL scheme: %s", u.Scheme)
+	}
+
+	for _, host := range hosts {
+		if host == "" {
+			continue
+		}
+
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(strings.Trim(host, "[]"), defaultPort)
+		}
+
+		opts.Addrs = append(opts.Addrs, host)
+	}
+
+	if len(opts.Addrs) == 0 {
+		return nil, errors.New("redis URL has no host")
+	} else if len(opts.Addrs) > 1 && config.Mode == ModeStandalone {
+		return nil, errors.New("redis URL has several hosts, use the redis+sentinel or redis+cluster scheme")
+	}
+
+	if u.User != nil {
+		opts.Username = u.User.Username()
+		opts.Password, _ = u.User.Password()
+	}
+
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if opts.DB, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid redis database number: %s", db)
+		}
+
+		if opts.DB != 0 && config.Mode == ModeCluster {
+			return nil, errors.New("redis cluster only has database 0")
+		}
+	}
+
+	query := u.Query()
+
+	opts.MasterName = query.Get("master")
+	opts.SentinelPassword = query.Get("sentinel_password")
+
+	if config.Mode == ModeSentinel && opts.MasterName == "" {
+		return nil, errors.New("redis sentinel URL has no master name, set it with ?master=<name>")
+	}
+
+	if strings.HasPrefix(scheme, "rediss") {
+		// the certificates of every host are verified against the name of
+		// the first host, unless REDIS_TLS_SERVER_NAME is set
+		serverName, _, _ := net.SplitHostPort(opts.Addrs[0])
+
+		opts.TLSConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			ServerName: serverName,
+		}
+	}
+
+	return config, nil
+}
+
+// splitHosts returns the comma-separated hosts of a URL, and the URL with a
+// single placeholder host, as url.Parse only accepts a single host
+func splitHosts(rawURL string) ([]string, string) {
+	schemeEnd := strings.Index(rawURL, "://")
+	if schemeEnd == -1 {
+		return nil, rawURL
+	}
+
+	start := schemeEnd + len("://")
+
+	end := len(rawURL)
+	if i := strings.IndexAny(rawURL[start:], "/?#"); i != -1 {
+		end = start + i
+	}
+
+	// the user info can contain a comma in the password
+	if i := strings.LastIndex(rawURL[start:end], "@"); i != -1 {
+		start += i + 1
+	}
+
+	return strings.Split(rawURL[start:end], ","), rawURL[:start] + "hosts" + rawURL[end:]
+}
+
+// newUniversalClient connects to the store in the mode of the config
+func newUniversalClient(config *Config) goredis.UniversalClient {
+	switch config.Mode {
+	case ModeSentinel:
+		return goredis.NewFailoverClient(config.Options.Failover())
+	case ModeCluster:
+		return goredis.NewClusterClient(config.Options.Cluster())
+	default:
+		return goredis.NewClient(config.Options.Simple())
+	}
+}
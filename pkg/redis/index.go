@@ -0,0 +1,537 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/tracing"
+	"github.com/porter-dev/porter-agent/pkg/utils"
+)
+
+// Incidents are indexed by the fields they can be filtered on, with one set of
+// incident IDs per field value, and by their creation and update timestamps,
+// with one sorted set per timestamp. The keys of the indexes share a hash tag,
+// so that they are combined on a single node of a cluster.
+const (
+	incidentsByCreatedKey = "{incident_index}:by_created"
+	incidentsByUpdatedKey = "{incident_index}:by_updated"
+
+	// set of all the field value sets, to clean them up
+	incidentIndexKeysKey = "{incident_index}:keys"
+
+	// set once the incidents stored before the indexes existed are indexed
+	incidentIndexBuiltKey = "{incident_index}:built"
+
+	IndexFieldState     = "state"
+	IndexFieldSeverity  = "severity"
+	IndexFieldNamespace = "namespace"
+	IndexFieldRelease   = "release"
+	IndexFieldOwnerKind = "owner_kind"
+	IndexFieldReason    = "reason"
+	IndexFieldChartName = "chart_name"
+
+	// the fingerprint of the latest crash extracted from the logs, which is
+	// kept by events without one
+	IndexFieldFingerprint = "fingerprint"
+
+	// set to true for incidents which started shortly after a rollout, from
+	// their first event
+	IndexFieldAfterRollout = "after_rollout"
+
+	SortByCreatedAt = "created_at"
+	SortByUpdatedAt = "updated_at"
+)
+
+// IncidentQuery filters and sorts incidents. Each filter matches any of its
+// values, and incidents have to match all of the filters which are set.
+// Time ranges are in unix seconds, and zero means unbounded.
+type IncidentQuery struct {
+	Filters map[string][]string
+
+	CreatedAfter  int64
+	CreatedBefore int64
+	UpdatedAfter  int64
+	UpdatedBefore int64
+
+	// SortBy is either created_at or updated_at, and defaults to created_at
+	SortBy    string
+	Ascending bool
+}
+
+func incidentIndexKey(field, value string) string {
+	return fmt.Sprintf("{incident_index}:%s:%s", field, value)
+}
+
+// indexIncident updates the indexes of an incident with the fields of its
+// latest event and its state. Either may be left out to keep the indexed
+// values.
+func (c *Client) indexIncident(ctx context.Context, incidentID string, event *models.PodEvent, state string) error {
+	incidentObj, err := utils.NewIncidentFromString(incidentID)
+	if err != nil {
+		return fmt.Errorf("error getting incident object for incident ID: %s. Error: %w", incidentID, err)
+	}
+
+	values := map[string][]string{
+		IndexFieldNamespace: {incidentObj.GetNamespace()},
+		IndexFieldRelease:   {incidentObj.GetReleaseName()},
+	}
+
+	if state != "" {
+		values[IndexFieldState] = []string{state}
+	}
+
+	if event != nil {
+		values[IndexFieldSeverity] = []string{string(event.Severity)}
+		values[IndexFieldOwnerKind] = []string{event.OwnerType}
+		values[IndexFieldReason] = []string{event.Reason}
+		values[IndexFieldChartName] = []string{event.ChartName}
+
+		if event.FilterReason != "" && event.FilterReason != event.Reason {
+			values[IndexFieldReason] = append(values[IndexFieldReason], event.FilterReason)
+		}
+
+		if event.StackTrace != nil {
+			values[IndexFieldFingerprint] = []string{event.StackTrace.Fingerprint}
+		}
+
+		if event.Rollout != nil {
+			values[IndexFieldAfterRollout] = []string{"true"}
+		}
+	}
+
+	// the currently indexed values of the incident, to remove it from the
+	// sets of values it no longer has. The values of each field are stored as
+	// a JSON array, since values such as reasons may contain any character.
+	valuesKey := fmt.Sprintf("incident_index_values:%s", incidentID)
+
+	for field, fieldValues := range values {
+		oldValues, err := c.getIndexedValues(ctx, valuesKey, field)
+		if err != nil {
+			return fmt.Errorf("error fetching indexed %s of incident ID: %s. Error: %w", field, incidentID, err)
+		}
+
+		for _, oldValue := range oldValues {
+			if containsString(fieldValues, oldValue) {
+				continue
+			}
+
+			if _, err := c.client.SRem(ctx, incidentIndexKey(field, oldValue), incidentID).Result(); err != nil {
+				return fmt.Errorf("error removing incident ID: %s from %s index. Error: %w", incidentID, field, err)
+			}
+		}
+
+		for _, value := range fieldValues {
+			if value == "" {
+				continue
+			}
+
+			key := incidentIndexKey(field, value)
+
+			if _, err := c.client.SAdd(ctx, key, incidentID).Result(); err != nil {
+				return fmt.Errorf("error adding incident ID: %s to %s index. Error: %w", incidentID, field, err)
+			}
+
+			if _, err := c.client.SAdd(ctx, incidentIndexKeysKey, key).Result(); err != nil {
+				return fmt.Errorf("error registering index key: %s. Error: %w", key, err)
+			}
+		}
+
+		encoded, err := json.Marshal(fieldValues)
+		if err != nil {
+			return fmt.Errorf("error encoding indexed %s of incident ID: %s. Error: %w", field, incidentID, err)
+		}
+
+		if _, err := c.client.HSet(ctx, valuesKey, field, encoded).Result(); err != nil {
+			return fmt.Errorf("error setting indexed %s of incident ID: %s. Error: %w", field, incidentID, err)
+		}
+	}
+
+	expiry, err := c.getIncidentExpiry(ctx, incidentID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.client.ExpireAt(ctx, valuesKey, expiry).Result(); err != nil {
+		return fmt.Errorf("error setting expiration for indexed values of incident ID: %s. Error: %w", incidentID, err)
+	}
+
+	if _, err := c.client.ZAdd(ctx, incidentsByCreatedKey, &goredis.Z{
+		Score:  float64(incidentObj.GetTimestamp()),
+		Member: incidentID,
+	}).Result(); err != nil {
+		return fmt.Errorf("error indexing creation time of incident ID: %s. Error: %w", incidentID, err)
+	}
+
+	if event != nil {
+		if _, err := c.client.ZAdd(ctx, incidentsByUpdatedKey, &goredis.Z{
+			Score:  float64(event.Timestamp),
+			Member: incidentID,
+		}).Result(); err != nil {
+			return fmt.Errorf("error indexing update time of incident ID: %s. Error: %w", incidentID, err)
+		}
+	}
+
+	return nil
+}
+
+// getIndexedValues returns the values an incident is indexed by for a field
+func (c *Client) getIndexedValues(ctx context.Context, valuesKey, field string) ([]string, error) {
+	encoded, err := c.client.HGet(ctx, valuesKey, field).Result()
+	if errors.Is(err, goredis.Nil) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return decodeIndexedValues(encoded)
+}
+
+func decodeIndexedValues(encoded string) ([]string, error) {
+	var values []string
+
+	if err := json.Unmarshal([]byte(encoded), &values); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// buildIncidentIndexes indexes the incidents stored before the indexes existed
+func (c *Client) buildIncidentIndexes(ctx context.Context) error {
+	if built, err := c.client.Exists(ctx, incidentIndexBuiltKey).Result(); err != nil {
+		return fmt.Errorf("error checking if incident indexes are built. Error: %w", err)
+	} else if built == 1 {
+		return nil
+	}
+
+	incidentIDs, err := c.GetAllIncidents(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, incidentID := range incidentIDs {
+		latestEvent, err := c.GetLatestEventForIncident(ctx, incidentID)
+		if err != nil {
+			return err
+		}
+
+		resolved, err := c.IsIncidentResolved(ctx, incidentID)
+		if err != nil {
+			return err
+		}
+
+		state := "ONGOING"
+		if resolved {
+			state = "RESOLVED"
+		}
+
+		if err := c.indexIncident(ctx, incidentID, latestEvent, state); err != nil {
+			return err
+		}
+	}
+
+	if _, err := c.client.Set(ctx, incidentIndexBuiltKey, "true", 0).Result(); err != nil {
+		return fmt.Errorf("error marking incident indexes as built. Error: %w", err)
+	}
+
+	return nil
+}
+
+// pruneIncidentIndexes removes the incidents which have expired from the
+// indexes. Only the incidents older than the shortest retention can have
+// expired, unless they were restored from the archive.
+func (c *Client) pruneIncidentIndexes(ctx context.Context) error {
+	cutoff := time.Now().Add(-c.retention.MinIncidentRetention()).Unix()
+
+	candidates, err := c.client.ZRangeByScore(ctx, incidentsByCreatedKey, &goredis.ZRangeBy{
+		Min: "-inf",
+		Max: fmt.Sprintf("(%d", cutoff),
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("error fetching expired incidents. Error: %w", err)
+	} else if len(candidates) == 0 {
+		return nil
+	}
+
+	existsCmds := make([]*goredis.IntCmd, 0, len(candidates))
+
+	_, err = c.client.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		for _, incidentID := range candidates {
+			existsCmds = append(existsCmds, pipe.Exists(ctx, incidentID))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error checking for expired incidents. Error: %w", err)
+	}
+
+	var expired []string
+
+	for i, cmd := range existsCmds {
+		if cmd.Val() == 0 {
+			expired = append(expired, candidates[i])
+		}
+	}
+
+	if len(expired) == 0 {
+		return nil
+	}
+
+	members := make([]interface{}, 0, len(expired))
+
+	for _, incidentID := range expired {
+		members = append(members, incidentID)
+	}
+
+	indexKeys, err := c.client.SMembers(ctx, incidentIndexKeysKey).Result()
+	if err != nil {
+		return fmt.Errorf("error fetching incident index keys. Error: %w", err)
+	}
+
+	for _, key := range indexKeys {
+		if _, err := c.client.SRem(ctx, key, members...).Result(); err != nil {
+			return fmt.Errorf("error removing expired incidents from index: %s. Error: %w", key, err)
+		}
+	}
+
+	for _, key := range []string{incidentsByCreatedKey, incidentsByUpdatedKey} {
+		if _, err := c.client.ZRem(ctx, key, members...).Result(); err != nil {
+			return fmt.Errorf("error removing expired incidents from index: %s. Error: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// QueryIncidents returns the IDs of the incidents matching the query, sorted
+// as requested
+func (c *Client) QueryIncidents(ctx context.Context, query *IncidentQuery) ([]string, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.QueryIncidents")
+	defer span.End()
+
+	if err := c.buildIncidentIndexes(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := c.pruneIncidentIndexes(ctx); err != nil {
+		return nil, err
+	}
+
+	sortKey, sortMin, sortMax := incidentsByCreatedKey, query.CreatedAfter, query.CreatedBefore
+	otherKey, otherMin, otherMax := incidentsByUpdatedKey, query.UpdatedAfter, query.UpdatedBefore
+
+	if query.SortBy == SortByUpdatedAt {
+		sortKey, otherKey = otherKey, sortKey
+		sortMin, otherMin = otherMin, sortMin
+		sortMax, otherMax = otherMax, sortMax
+	}
+
+	// temporary keys holding the intermediate results of this query
+	tmpPrefix := fmt.Sprintf("{incident_index}:query:%d", time.Now().UnixNano())
+	var tmpKeys []string
+
+	defer func() {
+		if len(tmpKeys) > 0 {
+			c.client.Del(ctx, tmpKeys...)
+		}
+	}()
+
+	newTmpKey := func() string {
+		key := fmt.Sprintf("%s:%d", tmpPrefix, len(tmpKeys))
+		tmpKeys = append(tmpKeys, key)
+		return key
+	}
+
+	// the sorted set of timestamps is intersected with one set per filter,
+	// which contributes nothing to the scores
+	keys := []string{sortKey}
+	weights := []float64{1}
+
+	for field, values := range query.Filters {
+		if len(values) == 0 {
+			continue
+		}
+
+		key := incidentIndexKey(field, values[0])
+
+		if len(values) > 1 {
+			var valueKeys []string
+
+			for _, value := range values {
+				valueKeys = append(valueKeys, incidentIndexKey(field, value))
+			}
+
+			key = newTmpKey()
+
+			if _, err := c.client.SUnionStore(ctx, key, valueKeys...).Result(); err != nil {
+				return nil, fmt.Errorf("error combining %s filters. Error: %w", field, err)
+			}
+		}
+
+		keys = append(keys, key)
+		weights = append(weights, 0)
+	}
+
+	resultKey := sortKey
+
+	if len(keys) > 1 {
+		resultKey = newTmpKey()
+
+		if _, err := c.client.ZInterStore(ctx, resultKey, &goredis.ZStore{
+			Keys:    keys,
+			Weights: weights,
+		}).Result(); err != nil {
+			return nil, fmt.Errorf("error filtering incidents. Error: %w", err)
+		}
+
+		// in case the request is interrupted before the keys are deleted
+		c.client.Expire(ctx, resultKey, time.Minute)
+	}
+
+	rangeBy := &goredis.ZRangeBy{
+		Min: scoreBound(sortMin, "-inf"),
+		Max: scoreBound(sortMax, "+inf"),
+	}
+
+	var incidentIDs []string
+	var err error
+
+	if query.Ascending {
+		incidentIDs, err = c.client.ZRangeByScore(ctx, resultKey, rangeBy).Result()
+	} else {
+		incidentIDs, err = c.client.ZRevRangeByScore(ctx, resultKey, rangeBy).Result()
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error fetching filtered incidents. Error: %w", err)
+	}
+
+	if otherMin == 0 && otherMax == 0 {
+		return incidentIDs, nil
+	}
+
+	inRange, err := c.client.ZRangeByScore(ctx, otherKey, &goredis.ZRangeBy{
+		Min: scoreBound(otherMin, "-inf"),
+		Max: scoreBound(otherMax, "+inf"),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching incidents in time range. Error: %w", err)
+	}
+
+	inRangeSet := make(map[string]bool, len(inRange))
+
+	for _, incidentID := range inRange {
+		inRangeSet[incidentID] = true
+	}
+
+	var res []string
+
+	for _, incidentID := range incidentIDs {
+		if inRangeSet[incidentID] {
+			res = append(res, incidentID)
+		}
+	}
+
+	return res, nil
+}
+
+// OpenIncidentCount is the number of ongoing incidents with the same labels
+type OpenIncidentCount struct {
+	Namespace string
+	Release   string
+	Reason    string
+	Severity  string
+	Count     int
+}
+
+// CountOpenIncidents returns the number of ongoing incidents by namespace,
+// release, reason and severity, as they are indexed
+func (c *Client) CountOpenIncidents(ctx context.Context) ([]*OpenIncidentCount, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.CountOpenIncidents")
+	defer span.End()
+
+	incidentIDs, err := c.QueryIncidents(ctx, &IncidentQuery{
+		Filters: map[string][]string{
+			IndexFieldState: {"ONGOING"},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cmds := make([]*goredis.SliceCmd, 0, len(incidentIDs))
+
+	_, err = c.client.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		for _, incidentID := range incidentIDs {
+			cmds = append(cmds, pipe.HMGet(ctx, fmt.Sprintf("incident_index_values:%s", incidentID),
+				IndexFieldNamespace, IndexFieldRelease, IndexFieldReason, IndexFieldSeverity))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching indexed values of open incidents. Error: %w", err)
+	}
+
+	counts := make(map[OpenIncidentCount]int)
+
+	for _, cmd := range cmds {
+		var labels [4]string
+
+		for i, value := range cmd.Val() {
+			str, ok := value.(string)
+			if !ok {
+				continue
+			}
+
+			// only the reason of the latest event is counted, not the reason
+			// given by the filter
+			if values, err := decodeIndexedValues(str); err == nil && len(values) > 0 {
+				labels[i] = values[0]
+			}
+		}
+
+		counts[OpenIncidentCount{
+			Namespace: labels[0],
+			Release:   labels[1],
+			Reason:    labels[2],
+			Severity:  labels[3],
+		}]++
+	}
+
+	res := make([]*OpenIncidentCount, 0, len(counts))
+
+	for labels, count := range counts {
+		openCount := labels
+		openCount.Count = count
+
+		res = append(res, &openCount)
+	}
+
+	return res, nil
+}
+
+func scoreBound(value int64, unbounded string) string {
+	if value == 0 {
+		return unbounded
+	}
+
+	return strconv.FormatInt(value, 10)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
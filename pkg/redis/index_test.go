@@ -0,0 +1,139 @@
+package redis
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/porter-dev/porter-agent/pkg/models"
+)
+
+func newTestClient(t *testing.T) *Client {
+	t.Helper()
+
+	m := miniredis.RunT(t)
+
+	config, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error reading redis config: %v", err)
+	}
+
+	config.Options.Addrs = []string{m.Addr()}
+
+	return NewClient(config, 100)
+}
+
+func TestIndexMultilineValues(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+
+	// build the indexes before there are incidents, so that the ones below
+	// are indexed as their events are added
+	if _, err := c.QueryIncidents(ctx, &IncidentQuery{}); err != nil {
+		t.Fatalf("unexpected error building indexes: %v", err)
+	}
+
+	incidentID, err := c.CreateActiveIncident(ctx, "web", "prod")
+	if err != nil {
+		t.Fatalf("unexpected error creating incident: %v", err)
+	}
+
+	// the reason of a pod with several crashing containers has one line per
+	// container
+	for i, reason := range []string{"OOMKilled\nCrashLoopBackOff", "Error"} {
+		event := &models.PodEvent{
+			PodName:   "web-1",
+			Namespace: "prod",
+			Reason:    reason,
+			Severity:  models.SeverityCritical,
+		}
+
+		if err := c.AddEventToIncident(ctx, incidentID, event, i == 0); err != nil {
+			t.Fatalf("unexpected error adding event: %v", err)
+		}
+	}
+
+	tests := []struct {
+		reason string
+		want   []string
+	}{
+		{reason: "Error", want: []string{incidentID}},
+		{reason: "OOMKilled\nCrashLoopBackOff"},
+		{reason: "OOMKilled"},
+		{reason: "CrashLoopBackOff"},
+	}
+
+	for _, test := range tests {
+		incidentIDs, err := c.QueryIncidents(ctx, &IncidentQuery{
+			Filters: map[string][]string{IndexFieldReason: {test.reason}},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error querying incidents: %v", err)
+		}
+
+		if len(incidentIDs) != len(test.want) || (len(test.want) > 0 && !reflect.DeepEqual(incidentIDs, test.want)) {
+			t.Errorf("expected reason %q to match %v, got %v", test.reason, test.want, incidentIDs)
+		}
+	}
+
+	counts, err := c.CountOpenIncidents(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error counting incidents: %v", err)
+	}
+
+	want := []*OpenIncidentCount{{Namespace: "prod", Release: "web", Reason: "Error", Severity: "critical", Count: 1}}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("expected counts %+v, got %+v", want[0], counts)
+	}
+}
+
+func TestGetIncidentsDetails(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+
+	var incidentIDs []string
+
+	for _, release := range []string{"web", "worker"} {
+		incidentID, err := c.CreateActiveIncident(ctx, release, "prod")
+		if err != nil {
+			t.Fatalf("unexpected error creating incident: %v", err)
+		}
+
+		event := &models.PodEvent{PodName: release + "-1", Namespace: "prod", Reason: "Error"}
+
+		if err := c.AddEventToIncident(ctx, incidentID, event, true); err != nil {
+			t.Fatalf("unexpected error adding event: %v", err)
+		}
+
+		incidentIDs = append(incidentIDs, incidentID)
+	}
+
+	if err := c.AcknowledgeIncident(ctx, incidentIDs[1], "jane"); err != nil {
+		t.Fatalf("unexpected error acknowledging incident: %v", err)
+	}
+
+	// incidents which no longer exist are left out
+	incidents, err := c.GetIncidentsDetails(ctx, []string{incidentIDs[1], "incident:gone:prod:1", incidentIDs[0]})
+	if err != nil {
+		t.Fatalf("unexpected error getting incidents: %v", err)
+	}
+
+	if len(incidents) != 2 || incidents[0].ID != incidentIDs[1] || incidents[1].ID != incidentIDs[0] {
+		t.Fatalf("expected the existing incidents in order, got %+v", incidents)
+	}
+
+	if !incidents[0].Acknowledged || incidents[0].AcknowledgedBy != "jane" || incidents[1].Acknowledged {
+		t.Errorf("expected only the first incident to be acknowledged, got %+v", incidents)
+	}
+
+	for _, incident := range incidents {
+		if incident.LatestState != "ONGOING" || incident.LatestReason != "Error" {
+			t.Errorf("unexpected state of incident %s: %s, %s", incident.ID, incident.LatestState, incident.LatestReason)
+		}
+	}
+
+	if _, err := c.GetIncidentDetails(ctx, "incident:gone:prod:1"); err == nil {
+		t.Errorf("expected an error getting an incident which does not exist")
+	}
+}
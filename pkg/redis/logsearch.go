@@ -0,0 +1,267 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/porter-dev/porter-agent/pkg/tracing"
+)
+
+// Captured logs are indexed with one sorted set of log IDs per token, scored
+// by the time the logs were captured, so that searches only read the logs
+// containing all of the tokens of a query. The keys of the index share a hash
+// tag, so that they are intersected on a single node of a cluster.
+const (
+	// sorted set of all the log IDs, for searches which cannot use the index
+	logIndexAllKey = "{log_index}:all"
+
+	// set of the namespaces with logs in the index
+	logIndexNamespacesKey = "{log_index}:namespaces"
+
+	// set of the token keys of the index, so that they can be pruned
+	logIndexKeysKey = "{log_index}:keys"
+
+	// maximum number of logs read to answer a search
+	maxLogSearchCandidates = 1000
+)
+
+func logIndexKey(token string) string {
+	return fmt.Sprintf("{log_index}:token:%s", token)
+}
+
+// logIndexNamespaceKey is the key of the sorted set of the log IDs of a
+// namespace, so that searches only read the logs of the namespaces they can
+// return
+func logIndexNamespaceKey(namespace string) string {
+	return fmt.Sprintf("{log_index}:namespace:%s", namespace)
+}
+
+// indexLogs adds the logs to the search index under their namespace and each
+// of their tokens
+func (c *Client) indexLogs(ctx context.Context, logID, namespace string, timestamp int64, tokens []string) error {
+	member := &goredis.Z{
+		Score:  float64(timestamp),
+		Member: logID,
+	}
+
+	_, err := c.client.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		for _, token := range tokens {
+			key := logIndexKey(token)
+
+			pipe.ZAdd(ctx, key, member)
+
+			// tokens which stop appearing in logs expire along with the logs
+			pipe.Expire(ctx, key, c.retention.MaxLogRetention())
+
+			pipe.SAdd(ctx, logIndexKeysKey, key)
+		}
+
+		pipe.ZAdd(ctx, logIndexAllKey, member)
+		pipe.ZAdd(ctx, logIndexNamespaceKey(namespace), member)
+		pipe.SAdd(ctx, logIndexNamespacesKey, namespace)
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error indexing logs with ID: %s. Error: %w", logID, err)
+	}
+
+	return nil
+}
+
+// PruneLogIndex removes the logs which have expired from the search index
+func (c *Client) PruneLogIndex(ctx context.Context) error {
+	ctx, span := tracing.StartSpan(ctx, "redis.PruneLogIndex")
+	defer span.End()
+
+	cutoff := fmt.Sprintf("(%d", time.Now().Add(-c.retention.MaxLogRetention()).Unix())
+
+	if _, err := c.client.ZRemRangeByScore(ctx, logIndexAllKey, "-inf", cutoff).Result(); err != nil {
+		return fmt.Errorf("error removing expired logs from index: %s. Error: %w", logIndexAllKey, err)
+	}
+
+	namespaces, err := c.GetLogNamespaces(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, namespace := range namespaces {
+		key := logIndexNamespaceKey(namespace)
+
+		if _, err := c.client.ZRemRangeByScore(ctx, key, "-inf", cutoff).Result(); err != nil {
+			return fmt.Errorf("error removing expired logs from index: %s. Error: %w", key, err)
+		}
+
+		// namespaces are only listed while they have logs
+		if count, err := c.client.ZCard(ctx, key).Result(); err != nil {
+			return fmt.Errorf("error counting logs in index: %s. Error: %w", key, err)
+		} else if count == 0 {
+			if _, err := c.client.SRem(ctx, logIndexNamespacesKey, namespace).Result(); err != nil {
+				return fmt.Errorf("error removing namespace: %s from log index. Error: %w", namespace, err)
+			}
+		}
+	}
+
+	keys, err := c.client.SMembers(ctx, logIndexKeysKey).Result()
+	if err != nil {
+		return fmt.Errorf("error fetching log index keys. Error: %w", err)
+	}
+
+	for _, key := range keys {
+		if _, err := c.client.ZRemRangeByScore(ctx, key, "-inf", cutoff).Result(); err != nil {
+			return fmt.Errorf("error removing expired logs from index: %s. Error: %w", key, err)
+		}
+
+		// the keys of the tokens which stopped appearing in logs have expired
+		if exists, err := c.client.Exists(ctx, key).Result(); err != nil {
+			return fmt.Errorf("error checking for log index key: %s. Error: %w", key, err)
+		} else if exists == 0 {
+			if _, err := c.client.SRem(ctx, logIndexKeysKey, key).Result(); err != nil {
+				return fmt.Errorf("error removing log index key: %s. Error: %w", key, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetLogNamespaces returns the namespaces with logs in the search index
+func (c *Client) GetLogNamespaces(ctx context.Context) ([]string, error) {
+	namespaces, err := c.client.SMembers(ctx, logIndexNamespacesKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching namespaces of log index. Error: %w", err)
+	}
+
+	return namespaces, nil
+}
+
+// SetLogEvent records the event and container the logs were captured for
+func (c *Client) SetLogEvent(ctx context.Context, logID, eventID, containerName string) error {
+	ctx, span := tracing.StartSpan(ctx, "redis.SetLogEvent")
+	defer span.End()
+
+	key := fmt.Sprintf("log_event:%s", logID)
+
+	if _, err := c.client.HSet(ctx, key, "event_id", eventID, "container_name", containerName).Result(); err != nil {
+		return fmt.Errorf("error setting event of logs with ID: %s. Error: %w", logID, err)
+	}
+
+	// the event of the logs expires along with the logs
+	ttl, err := c.client.PTTL(ctx, logID).Result()
+	if err != nil {
+		return fmt.Errorf("error getting expiration of logs with ID: %s. Error: %w", logID, err)
+	} else if ttl <= 0 {
+		ttl = c.retention.MaxLogRetention()
+	}
+
+	if _, err := c.client.PExpire(ctx, key, ttl).Result(); err != nil {
+		return fmt.Errorf("error setting expiration for event of logs with ID: %s. Error: %w", logID, err)
+	}
+
+	return nil
+}
+
+// GetLogEvent returns the event and container the logs were captured for, or
+// empty strings if they are not known
+func (c *Client) GetLogEvent(ctx context.Context, logID string) (string, string, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.GetLogEvent")
+	defer span.End()
+
+	values, err := c.client.HGetAll(ctx, fmt.Sprintf("log_event:%s", logID)).Result()
+	if err != nil && !errors.Is(err, goredis.Nil) {
+		return "", "", fmt.Errorf("error fetching event of logs with ID: %s. Error: %w", logID, err)
+	}
+
+	return values["event_id"], values["container_name"], nil
+}
+
+// SearchLogs returns the IDs of the most recent logs captured since the given
+// time which contain all of the tokens, newest first. Without tokens, it
+// returns the most recent logs, which then have to be scanned. The search is
+// limited to the given namespaces unless they are nil. At most
+// maxLogSearchCandidates logs are returned, and the returned bool is true if
+// older logs were left out.
+func (c *Client) SearchLogs(ctx context.Context, tokens, namespaces []string, since int64) ([]string, bool, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.SearchLogs")
+	defer span.End()
+
+	var keys []string
+
+	for _, token := range tokens {
+		keys = append(keys, logIndexKey(token))
+	}
+
+	if namespaces != nil {
+		if len(namespaces) == 0 {
+			return nil, false, nil
+		}
+
+		namespaceKeys := make([]string, 0, len(namespaces))
+
+		for _, namespace := range namespaces {
+			namespaceKeys = append(namespaceKeys, logIndexNamespaceKey(namespace))
+		}
+
+		if len(namespaceKeys) == 1 {
+			keys = append(keys, namespaceKeys[0])
+		} else {
+			unionKey := fmt.Sprintf("{log_index}:search:%d:namespaces", time.Now().UnixNano())
+
+			if _, err := c.client.ZUnionStore(ctx, unionKey, &goredis.ZStore{
+				Keys:      namespaceKeys,
+				Aggregate: "MAX",
+			}).Result(); err != nil {
+				return nil, false, fmt.Errorf("error searching log index. Error: %w", err)
+			}
+
+			defer c.client.Del(ctx, unionKey)
+
+			keys = append(keys, unionKey)
+		}
+	}
+
+	resultKey := logIndexAllKey
+
+	switch {
+	case len(keys) == 1:
+		resultKey = keys[0]
+	case len(keys) > 1:
+		resultKey = fmt.Sprintf("{log_index}:search:%d", time.Now().UnixNano())
+
+		if _, err := c.client.ZInterStore(ctx, resultKey, &goredis.ZStore{
+			Keys:      keys,
+			Aggregate: "MAX",
+		}).Result(); err != nil {
+			return nil, false, fmt.Errorf("error searching log index. Error: %w", err)
+		}
+
+		defer c.client.Del(ctx, resultKey)
+	}
+
+	// logs which have expired are only pruned from the index by the compactor,
+	// so they are skipped here
+	min := time.Now().Add(-c.retention.MaxLogRetention()).Unix()
+	if since > min {
+		min = since
+	}
+
+	// read one more candidate to find out if older logs are left out
+	logIDs, err := c.client.ZRevRangeByScore(ctx, resultKey, &goredis.ZRangeBy{
+		Min:   strconv.FormatInt(min, 10),
+		Max:   "+inf",
+		Count: maxLogSearchCandidates + 1,
+	}).Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("error fetching matching logs. Error: %w", err)
+	}
+
+	if len(logIDs) > maxLogSearchCandidates {
+		return logIDs[:maxLogSearchCandidates], true, nil
+	}
+
+	return logIDs, false, nil
+}
@@ -0,0 +1,118 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestSearchLogs(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+
+	now := time.Now().Unix()
+	expired := time.Now().Add(-2 * c.retention.MaxLogRetention()).Unix()
+
+	logs := []struct {
+		logID     string
+		namespace string
+		timestamp int64
+		tokens    []string
+	}{
+		{logID: "a-1", namespace: "a", timestamp: now - 2, tokens: []string{"boom"}},
+		{logID: "a-2", namespace: "a", timestamp: now - 1, tokens: []string{"fine"}},
+		{logID: "b-1", namespace: "b", timestamp: now, tokens: []string{"boom"}},
+		{logID: "c-1", namespace: "c", timestamp: expired, tokens: []string{"boom"}},
+	}
+
+	for _, log := range logs {
+		if err := c.indexLogs(ctx, log.logID, log.namespace, log.timestamp, log.tokens); err != nil {
+			t.Fatalf("unexpected error indexing logs: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		tokens     []string
+		namespaces []string
+		want       []string
+	}{
+		{name: "all logs", want: []string{"b-1", "a-2", "a-1"}},
+		{name: "token", tokens: []string{"boom"}, want: []string{"b-1", "a-1"}},
+		{name: "namespace", namespaces: []string{"a"}, want: []string{"a-2", "a-1"}},
+		{name: "token and namespaces", tokens: []string{"boom"}, namespaces: []string{"a", "c"}, want: []string{"a-1"}},
+		{name: "no namespaces", tokens: []string{"boom"}, namespaces: []string{}, want: nil},
+	}
+
+	for _, test := range tests {
+		got, truncated, err := c.SearchLogs(ctx, test.tokens, test.namespaces, 0)
+		if err != nil {
+			t.Fatalf("%s: unexpected error searching logs: %v", test.name, err)
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.want, got)
+		}
+
+		if truncated {
+			t.Errorf("%s: expected the search not to be truncated", test.name)
+		}
+	}
+
+	// searches do not remove the expired logs from the index
+	if count := c.client.ZCard(ctx, logIndexAllKey).Val(); count != int64(len(logs)) {
+		t.Errorf("expected %d logs in the index before pruning, got %d", len(logs), count)
+	}
+
+	if err := c.PruneLogIndex(ctx); err != nil {
+		t.Fatalf("unexpected error pruning log index: %v", err)
+	}
+
+	if count := c.client.ZCard(ctx, logIndexAllKey).Val(); count != int64(len(logs)-1) {
+		t.Errorf("expected %d logs in the index after pruning, got %d", len(logs)-1, count)
+	}
+
+	if count := c.client.ZCard(ctx, logIndexKey("boom")).Val(); count != 2 {
+		t.Errorf("expected 2 logs for the token after pruning, got %d", count)
+	}
+
+	namespaces, err := c.GetLogNamespaces(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error getting log namespaces: %v", err)
+	}
+
+	sort.Strings(namespaces)
+
+	if want := []string{"a", "b"}; !reflect.DeepEqual(namespaces, want) {
+		t.Errorf("expected namespaces %v after pruning, got %v", want, namespaces)
+	}
+}
+
+func TestSearchLogsTruncated(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+
+	now := time.Now().Unix()
+
+	for i := 0; i <= maxLogSearchCandidates; i++ {
+		if err := c.indexLogs(ctx, fmt.Sprintf("a-%d", i), "a", now, []string{"boom"}); err != nil {
+			t.Fatalf("unexpected error indexing logs: %v", err)
+		}
+	}
+
+	got, truncated, err := c.SearchLogs(ctx, []string{"boom"}, []string{"a"}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error searching logs: %v", err)
+	}
+
+	if len(got) != maxLogSearchCandidates {
+		t.Errorf("expected %d logs, got %d", maxLogSearchCandidates, len(got))
+	}
+
+	if !truncated {
+		t.Errorf("expected the search to be truncated")
+	}
+}
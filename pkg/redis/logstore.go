@@ -0,0 +1,126 @@
+package redis
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	porterErrors "github.com/porter-dev/porter-agent/pkg/errors"
+)
+
+// The contents of captured logs are stored gzipped, once per distinct contents,
+// under the SHA-256 hash of the contents. The key of each log holds a
+// reference to its contents, so that logs which are captured again are only
+// stored once, and are found to be duplicates by comparing hashes.
+//
+// Logs captured before were stored as plain text under the key of each log,
+// which are still read as they are.
+const logContentsRefPrefix = "sha256:"
+
+func logContentsKey(hash string) string {
+	return fmt.Sprintf("log_contents:%s", hash)
+}
+
+// hashLogs returns the reference to logs with the given contents
+func hashLogs(contents string) string {
+	sum := sha256.Sum256([]byte(contents))
+	return logContentsRefPrefix + hex.EncodeToString(sum[:])
+}
+
+// setLogContents stores the contents of logs if they are not stored yet, and
+// points the logs to them. The contents are kept as long as the logs which
+// point to them.
+func (c *Client) setLogContents(ctx context.Context, logID, contents string, ttl time.Duration) error {
+	ref := hashLogs(contents)
+	key := logContentsKey(strings.TrimPrefix(ref, logContentsRefPrefix))
+
+	currentTTL, err := c.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return fmt.Errorf("error getting expiration of log contents for log ID: %s. Error: %w", logID, err)
+	}
+
+	// missing keys have a negative TTL
+	if currentTTL < 0 {
+		compressed, err := compressLogs(contents)
+		if err != nil {
+			return fmt.Errorf("error compressing logs with ID: %s. Error: %w", logID, err)
+		}
+
+		if _, err := c.client.Set(ctx, key, compressed, ttl).Result(); err != nil {
+			return fmt.Errorf("error adding log contents for log ID: %s. Error: %w", logID, err)
+		}
+	} else if currentTTL < ttl {
+		if _, err := c.client.PExpire(ctx, key, ttl).Result(); err != nil {
+			return fmt.Errorf("error setting expiration of log contents for log ID: %s. Error: %w", logID, err)
+		}
+	}
+
+	if _, err := c.client.Set(ctx, logID, ref, ttl).Result(); err != nil {
+		return fmt.Errorf("error adding logs with ID: %s. Error: %w", logID, err)
+	}
+
+	return nil
+}
+
+// getLogContents returns the decompressed contents of the logs with the given
+// value, which is either a reference to the contents or the plain contents of
+// logs stored before the contents were compressed
+func (c *Client) getLogContents(ctx context.Context, logID, value string) (string, error) {
+	if !strings.HasPrefix(value, logContentsRefPrefix) {
+		return value, nil
+	}
+
+	compressed, err := c.client.Get(ctx, logContentsKey(strings.TrimPrefix(value, logContentsRefPrefix))).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return "", fmt.Errorf("%w: %s", porterErrors.LogsNotFoundError, logID)
+	} else if err != nil {
+		return "", fmt.Errorf("error fetching contents of logs with ID: %s. Error: %w", logID, err)
+	}
+
+	contents, err := decompressLogs(compressed)
+	if err != nil {
+		return "", fmt.Errorf("error decompressing logs with ID: %s. Error: %w", logID, err)
+	}
+
+	return contents, nil
+}
+
+func compressLogs(contents string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w := gzip.NewWriter(&buf)
+
+	if _, err := w.Write([]byte(contents)); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func decompressLogs(compressed []byte) (string, error) {
+	r, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return "", err
+	}
+
+	defer r.Close()
+
+	contents, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	return string(contents), nil
+}
@@ -0,0 +1,90 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/tracing"
+)
+
+// The remediations of an incident are stored in a hash by ID, which expires
+// along with the incident.
+func remediationsKey(incidentID string) string {
+	return fmt.Sprintf("remediations:%s", incidentID)
+}
+
+// AddRemediation records an action taken to remediate an incident, assigning
+// its ID and timestamp
+func (c *Client) AddRemediation(ctx context.Context, incidentID string, remediation *models.Remediation) error {
+	ctx, span := tracing.StartSpan(ctx, "redis.AddRemediation")
+	defer span.End()
+
+	if err := c.checkIncidentExists(ctx, incidentID); err != nil {
+		return err
+	}
+
+	expiry, err := c.getIncidentExpiry(ctx, incidentID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	remediation.ID = strconv.FormatInt(now.UnixNano(), 10)
+	remediation.IncidentID = incidentID
+	remediation.Timestamp = now.Unix()
+
+	remediationJSON, err := json.Marshal(remediation)
+	if err != nil {
+		return fmt.Errorf("error marshalling remediation for incident ID: %s. Error: %w", incidentID, err)
+	}
+
+	key := remediationsKey(incidentID)
+
+	if _, err := c.client.HSet(ctx, key, remediation.ID, remediationJSON).Result(); err != nil {
+		return fmt.Errorf("error adding remediation to incident with ID: %s. Error: %w", incidentID, err)
+	}
+
+	if _, err := c.client.ExpireAt(ctx, key, expiry).Result(); err != nil {
+		return fmt.Errorf("error setting expiration for remediations of incident ID: %s. Error: %w", incidentID, err)
+	}
+
+	return c.publishChange(ctx, incidentID, &models.IncidentChange{
+		Type:  models.IncidentChangeStateChanged,
+		State: "remediated",
+	})
+}
+
+// GetRemediations returns the remediations of an incident, oldest first
+func (c *Client) GetRemediations(ctx context.Context, incidentID string) ([]*models.Remediation, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.GetRemediations")
+	defer span.End()
+
+	payload, err := c.client.HGetAll(ctx, remediationsKey(incidentID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching remediations for incident ID: %s. Error: %w", incidentID, err)
+	}
+
+	remediations := make([]*models.Remediation, 0, len(payload))
+
+	for id, remediationJSON := range payload {
+		remediation := &models.Remediation{}
+
+		if err := json.Unmarshal([]byte(remediationJSON), remediation); err != nil {
+			return nil, fmt.Errorf("error unmarshalling remediation with ID: %s. Error: %w", id, err)
+		}
+
+		remediations = append(remediations, remediation)
+	}
+
+	sort.SliceStable(remediations, func(i, j int) bool {
+		return remediations[i].ID < remediations[j].ID
+	})
+
+	return remediations, nil
+}
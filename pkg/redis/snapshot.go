@@ -0,0 +1,99 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	goredis "github.com/go-redis/redis/v8"
+	porterErrors "github.com/porter-dev/porter-agent/pkg/errors"
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/tracing"
+)
+
+// The snapshots of the pods of the events of an incident are stored in a hash
+// by event ID, which expires along with the incident.
+func snapshotsKey(incidentID string) string {
+	return fmt.Sprintf("snapshots:%s", incidentID)
+}
+
+// AddSnapshot stores the snapshot of the pod of an event of an incident
+func (c *Client) AddSnapshot(ctx context.Context, incidentID string, snapshot *models.PodSnapshot) error {
+	ctx, span := tracing.StartSpan(ctx, "redis.AddSnapshot")
+	defer span.End()
+
+	expiry, err := c.getIncidentExpiry(ctx, incidentID)
+	if err != nil {
+		return err
+	}
+
+	snapshotJSON, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("error marshalling snapshot for event ID: %s. Error: %w", snapshot.EventID, err)
+	}
+
+	key := snapshotsKey(incidentID)
+
+	if _, err := c.client.HSet(ctx, key, snapshot.EventID, snapshotJSON).Result(); err != nil {
+		return fmt.Errorf("error adding snapshot for event ID: %s. Error: %w", snapshot.EventID, err)
+	}
+
+	if _, err := c.client.ExpireAt(ctx, key, expiry).Result(); err != nil {
+		return fmt.Errorf("error setting expiration for snapshots of incident ID: %s. Error: %w", incidentID, err)
+	}
+
+	return nil
+}
+
+// GetSnapshot returns the snapshot of the pod of an event of an incident
+func (c *Client) GetSnapshot(ctx context.Context, incidentID, eventID string) (*models.PodSnapshot, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.GetSnapshot")
+	defer span.End()
+
+	snapshotJSON, err := c.client.HGet(ctx, snapshotsKey(incidentID), eventID).Result()
+	if errors.Is(err, goredis.Nil) {
+		return nil, fmt.Errorf("%w: %s", porterErrors.SnapshotNotFoundError, eventID)
+	} else if err != nil {
+		return nil, fmt.Errorf("error fetching snapshot for event ID: %s. Error: %w", eventID, err)
+	}
+
+	snapshot := &models.PodSnapshot{}
+
+	if err := json.Unmarshal([]byte(snapshotJSON), snapshot); err != nil {
+		return nil, fmt.Errorf("error unmarshalling snapshot for event ID: %s. Error: %w", eventID, err)
+	}
+
+	return snapshot, nil
+}
+
+// GetSnapshots returns the snapshots of the events of an incident, oldest
+// first
+func (c *Client) GetSnapshots(ctx context.Context, incidentID string) ([]*models.PodSnapshot, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.GetSnapshots")
+	defer span.End()
+
+	payload, err := c.client.HGetAll(ctx, snapshotsKey(incidentID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching snapshots for incident ID: %s. Error: %w", incidentID, err)
+	}
+
+	snapshots := make([]*models.PodSnapshot, 0, len(payload))
+
+	for eventID, snapshotJSON := range payload {
+		snapshot := &models.PodSnapshot{}
+
+		if err := json.Unmarshal([]byte(snapshotJSON), snapshot); err != nil {
+			return nil, fmt.Errorf("error unmarshalling snapshot for event ID: %s. Error: %w", eventID, err)
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].CapturedAt < snapshots[j].CapturedAt
+	})
+
+	return snapshots, nil
+}
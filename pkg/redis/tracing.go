@@ -0,0 +1,67 @@
+package redis
+
+import (
+	"context"
+	"errors"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/porter-dev/porter-agent/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracingHook records a span for every command and pipeline sent to Redis
+type tracingHook struct{}
+
+func (tracingHook) BeforeProcess(ctx context.Context, cmd goredis.Cmder) (context.Context, error) {
+	ctx, _ = tracing.StartSpan(ctx, "redis "+cmd.Name(),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "redis"),
+			attribute.String("db.operation", cmd.Name()),
+		),
+	)
+
+	return ctx, nil
+}
+
+func (tracingHook) AfterProcess(ctx context.Context, cmd goredis.Cmder) error {
+	tracing.EndSpan(trace.SpanFromContext(ctx), commandError(cmd))
+	return nil
+}
+
+func (tracingHook) BeforeProcessPipeline(ctx context.Context, cmds []goredis.Cmder) (context.Context, error) {
+	ctx, _ = tracing.StartSpan(ctx, "redis pipeline",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "redis"),
+			attribute.Int("db.redis.num_cmd", len(cmds)),
+		),
+	)
+
+	return ctx, nil
+}
+
+func (tracingHook) AfterProcessPipeline(ctx context.Context, cmds []goredis.Cmder) error {
+	var err error
+
+	for _, cmd := range cmds {
+		if err = commandError(cmd); err != nil {
+			break
+		}
+	}
+
+	tracing.EndSpan(trace.SpanFromContext(ctx), err)
+
+	return nil
+}
+
+// commandError returns the error of a command, except for missing keys which
+// are expected
+func commandError(cmd goredis.Cmder) error {
+	if err := cmd.Err(); err != nil && !errors.Is(err, goredis.Nil) {
+		return err
+	}
+
+	return nil
+}
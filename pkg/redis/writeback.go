@@ -0,0 +1,58 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/porter-dev/porter-agent/pkg/tracing"
+)
+
+// The workload an incident was written back to is stored as <kind>/<name>,
+// and expires along with the incident.
+func workloadKey(incidentID string) string {
+	return fmt.Sprintf("workload:%s", incidentID)
+}
+
+// SetIncidentWorkload records the Deployment or Job an incident was written
+// back to, so that it can be found again when the incident changes
+func (c *Client) SetIncidentWorkload(ctx context.Context, incidentID, kind, name string) error {
+	ctx, span := tracing.StartSpan(ctx, "redis.SetIncidentWorkload")
+	defer span.End()
+
+	expiry, err := c.getIncidentExpiry(ctx, incidentID)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.client.Set(ctx, workloadKey(incidentID), kind+"/"+name, time.Until(expiry)).Result()
+	if err != nil {
+		return fmt.Errorf("error recording workload of incident with ID: %s. Error: %w", incidentID, err)
+	}
+
+	return nil
+}
+
+// GetIncidentWorkload returns the kind and name of the workload an incident
+// was written back to, or empty strings if it was not written back
+func (c *Client) GetIncidentWorkload(ctx context.Context, incidentID string) (string, string, error) {
+	ctx, span := tracing.StartSpan(ctx, "redis.GetIncidentWorkload")
+	defer span.End()
+
+	workload, err := c.client.Get(ctx, workloadKey(incidentID)).Result()
+	if errors.Is(err, goredis.Nil) {
+		return "", "", nil
+	} else if err != nil {
+		return "", "", fmt.Errorf("error fetching workload of incident with ID: %s. Error: %w", incidentID, err)
+	}
+
+	parts := strings.SplitN(workload, "/", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("invalid workload %q recorded for incident with ID: %s", workload, incidentID)
+	}
+
+	return parts[0], parts[1], nil
+}
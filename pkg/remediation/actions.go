@@ -0,0 +1,253 @@
+package remediation
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// increased memory limits are rounded up to a multiple of this
+const memoryIncrement = 1024 * 1024
+
+// planRollback plans rolling back the deployment of an event to its previous
+// ReplicaSet, like kubectl rollout undo, when every pod of the ReplicaSet of
+// the event crash-loops within the rollback window of its creation
+func (r *Remediator) planRollback(ctx context.Context, event *models.PodEvent, _ *corev1.Pod) ([]*action, error) {
+	if event.OwnerType != "Deployment" || event.Revision == nil || event.Revision.ReplicaSet == "" {
+		return nil, nil
+	}
+
+	rs, err := r.getReplicaSet(ctx, event.Namespace, event.Revision.ReplicaSet)
+	if err != nil || rs == nil {
+		return nil, err
+	}
+
+	if time.Since(rs.CreationTimestamp.Time) > r.policy.Rollback.Window.Duration {
+		return nil, nil
+	}
+
+	deployment, err := r.getDeployment(ctx, rs)
+	if err != nil || deployment == nil {
+		return nil, err
+	}
+
+	// the deployment was rolled out again since
+	revision := rs.Annotations[utils.RevisionAnnotation]
+	if deployment.Annotations[utils.RevisionAnnotation] != revision {
+		return nil, nil
+	}
+
+	if crashLooping, err := r.allPodsCrashLooping(ctx, rs); err != nil || !crashLooping {
+		return nil, err
+	}
+
+	previous, err := utils.GetPreviousReplicaSet(ctx, r.client, rs, deployment.UID)
+	if err != nil || previous == nil {
+		return nil, err
+	}
+
+	previousRevision := previous.Annotations[utils.RevisionAnnotation]
+
+	return []*action{{
+		remediation: &models.Remediation{
+			Action: ActionRollback,
+			Target: "deployment/" + deployment.Name,
+			Reason: fmt.Sprintf("every pod of revision %s crash-loops within %s of its rollout", revision,
+				duration.HumanDuration(r.policy.Rollback.Window.Duration)),
+			Changes: []*models.RevisionChange{{
+				Field:    "revision",
+				Previous: revision,
+				Current:  previousRevision,
+			}},
+		},
+		apply: func(ctx context.Context, dryRun bool) error {
+			patch := client.MergeFrom(deployment.DeepCopy())
+
+			template := previous.Spec.Template.DeepCopy()
+			delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+
+			deployment.Spec.Template = *template
+
+			if err := r.client.Patch(ctx, deployment, patch, patchOptions(dryRun)...); err != nil {
+				return fmt.Errorf("error rolling back deployment %s to revision %s. Error: %w", deployment.Name,
+					previousRevision, err)
+			}
+
+			return nil
+		},
+	}}, nil
+}
+
+// allPodsCrashLooping returns whether a ReplicaSet has pods and all of them
+// crash-loop
+func (r *Remediator) allPodsCrashLooping(ctx context.Context, rs *appsv1.ReplicaSet) (bool, error) {
+	selector, err := metav1.LabelSelectorAsSelector(rs.Spec.Selector)
+	if err != nil {
+		return false, fmt.Errorf("error parsing selector of replicaset %s. Error: %w", rs.Name, err)
+	}
+
+	pods := &corev1.PodList{}
+
+	if err := r.client.List(ctx, pods, client.InNamespace(rs.Namespace),
+		client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return false, fmt.Errorf("error listing pods of replicaset %s. Error: %w", rs.Name, err)
+	}
+
+	count := 0
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+
+		if owner := metav1.GetControllerOf(pod); owner == nil || owner.UID != rs.UID {
+			continue
+		}
+
+		if !crashLooping(pod) {
+			return false, nil
+		}
+
+		count++
+	}
+
+	return count > 0, nil
+}
+
+// crashLooping returns whether a container of the pod is not ready after
+// having been restarted
+func crashLooping(pod *corev1.Pod) bool {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.RestartCount > 0 && !status.Ready {
+			return true
+		}
+	}
+
+	return false
+}
+
+// planIncreaseMemory plans increasing the memory limit of the containers of
+// the pod of an event which were OOMKilled and restarted at least the
+// configured number of times, in the template of its deployment. Containers
+// without a memory limit are left alone, since they were killed because their
+// node ran out of memory.
+func (r *Remediator) planIncreaseMemory(ctx context.Context, event *models.PodEvent, pod *corev1.Pod) ([]*action, error) {
+	if event.OwnerType != "Deployment" || pod == nil {
+		return nil, nil
+	}
+
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil || owner.Kind != "ReplicaSet" {
+		return nil, nil
+	}
+
+	rs, err := r.getReplicaSet(ctx, pod.Namespace, owner.Name)
+	if err != nil || rs == nil {
+		return nil, err
+	}
+
+	deployment, err := r.getDeployment(ctx, rs)
+	if err != nil || deployment == nil {
+		return nil, err
+	}
+
+	policy := r.policy.IncreaseMemory
+
+	var actions []*action
+
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.RestartCount < policy.AfterRestarts || !oomKilled(status) {
+			continue
+		}
+
+		container := getContainer(&deployment.Spec.Template.Spec, status.Name)
+		if container == nil {
+			continue
+		}
+
+		limit, ok := container.Resources.Limits[corev1.ResourceMemory]
+		if !ok || limit.Cmp(policy.MaxLimit) >= 0 {
+			continue
+		}
+
+		newLimit := scaleQuantity(limit, policy.Factor, policy.MaxLimit)
+		containerName := status.Name
+
+		actions = append(actions, &action{
+			remediation: &models.Remediation{
+				Action:    ActionIncreaseMemory,
+				Target:    "deployment/" + deployment.Name,
+				Container: containerName,
+				Reason:    fmt.Sprintf("container was OOMKilled and restarted %d times", status.RestartCount),
+				Changes: []*models.RevisionChange{{
+					Container: containerName,
+					Field:     "limits.memory",
+					Previous:  limit.String(),
+					Current:   newLimit.String(),
+				}},
+			},
+			apply: func(ctx context.Context, dryRun bool) error {
+				patch := client.MergeFrom(deployment.DeepCopy())
+
+				container := getContainer(&deployment.Spec.Template.Spec, containerName)
+				if container == nil {
+					return fmt.Errorf("container %s is not in deployment %s", containerName, deployment.Name)
+				}
+
+				container.Resources.Limits[corev1.ResourceMemory] = newLimit
+
+				if err := r.client.Patch(ctx, deployment, patch, patchOptions(dryRun)...); err != nil {
+					return fmt.Errorf("error increasing memory limit of container %s of deployment %s. Error: %w",
+						containerName, deployment.Name, err)
+				}
+
+				return nil
+			},
+		})
+	}
+
+	return actions, nil
+}
+
+// oomKilled returns whether the current or the last run of a container was
+// OOMKilled
+func oomKilled(status corev1.ContainerStatus) bool {
+	if terminated := status.State.Terminated; terminated != nil && terminated.Reason == "OOMKilled" {
+		return true
+	}
+
+	terminated := status.LastTerminationState.Terminated
+
+	return terminated != nil && terminated.Reason == "OOMKilled"
+}
+
+func getContainer(spec *corev1.PodSpec, name string) *corev1.Container {
+	for i := range spec.Containers {
+		if spec.Containers[i].Name == name {
+			return &spec.Containers[i]
+		}
+	}
+
+	return nil
+}
+
+// scaleQuantity multiplies a quantity by a factor, rounded up to a multiple of
+// memoryIncrement and capped at max
+func scaleQuantity(q resource.Quantity, factor float64, max resource.Quantity) resource.Quantity {
+	value := int64(math.Ceil(float64(q.Value())*factor/memoryIncrement)) * memoryIncrement
+
+	scaled := resource.NewQuantity(value, resource.BinarySI)
+	if scaled.Cmp(max) > 0 {
+		return max.DeepCopy()
+	}
+
+	return *scaled
+}
@@ -0,0 +1,327 @@
+package remediation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTestPolicy() *Policy {
+	policy := &Policy{}
+	policy.setDefaults()
+
+	return policy
+}
+
+func controllerRef(kind, name string, uid types.UID) []metav1.OwnerReference {
+	controller := true
+
+	return []metav1.OwnerReference{{
+		APIVersion: "apps/v1",
+		Kind:       kind,
+		Name:       name,
+		UID:        uid,
+		Controller: &controller,
+	}}
+}
+
+func newTestDeployment(revision, memoryLimit string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "web",
+			Namespace:   "prod",
+			UID:         "deployment",
+			Annotations: map[string]string{utils.RevisionAnnotation: revision},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:  "web",
+						Image: "web:v2",
+						Resources: corev1.ResourceRequirements{
+							Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse(memoryLimit)},
+						},
+					}},
+				},
+			},
+		},
+	}
+}
+
+func newTestReplicaSet(revision, image string, createdAt time.Time) *appsv1.ReplicaSet {
+	labels := map[string]string{"app": "web", appsv1.DefaultDeploymentUniqueLabelKey: revision}
+
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "web-" + revision,
+			Namespace:         "prod",
+			UID:               types.UID("replicaset-" + revision),
+			Annotations:       map[string]string{utils.RevisionAnnotation: revision},
+			OwnerReferences:   controllerRef("Deployment", "web", "deployment"),
+			CreationTimestamp: metav1.NewTime(createdAt),
+		},
+		Spec: appsv1.ReplicaSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "web", Image: image}},
+				},
+			},
+		},
+	}
+}
+
+func newTestPod(name, revision string, status corev1.ContainerStatus) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       "prod",
+			Labels:          map[string]string{"app": "web", appsv1.DefaultDeploymentUniqueLabelKey: revision},
+			OwnerReferences: controllerRef("ReplicaSet", "web-"+revision, types.UID("replicaset-"+revision)),
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{status},
+		},
+	}
+}
+
+var crashLoopingStatus = corev1.ContainerStatus{
+	Name:         "web",
+	RestartCount: 3,
+	LastTerminationState: corev1.ContainerState{
+		Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled"},
+	},
+}
+
+func TestPlanRollback(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	event := &models.PodEvent{
+		Namespace: "prod",
+		OwnerType: "Deployment",
+		Revision:  &models.Revision{Revision: "2", ReplicaSet: "web-2"},
+	}
+
+	tests := []struct {
+		name       string
+		deployment *appsv1.Deployment
+		createdAt  time.Time
+		status     corev1.ContainerStatus
+		want       bool
+	}{
+		{
+			name:       "every pod crash-loops after the rollout",
+			deployment: newTestDeployment("2", "512Mi"),
+			createdAt:  now.Add(-time.Minute),
+			status:     crashLoopingStatus,
+			want:       true,
+		},
+		{
+			name:       "rollout outside of the window",
+			deployment: newTestDeployment("2", "512Mi"),
+			createdAt:  now.Add(-time.Hour),
+			status:     crashLoopingStatus,
+		},
+		{
+			name:       "deployment rolled out again",
+			deployment: newTestDeployment("3", "512Mi"),
+			createdAt:  now.Add(-time.Minute),
+			status:     crashLoopingStatus,
+		},
+		{
+			name:       "pod is ready",
+			deployment: newTestDeployment("2", "512Mi"),
+			createdAt:  now.Add(-time.Minute),
+			status:     corev1.ContainerStatus{Name: "web", RestartCount: 1, Ready: true},
+		},
+	}
+
+	for _, test := range tests {
+		c := fake.NewClientBuilder().WithObjects(
+			test.deployment,
+			newTestReplicaSet("1", "web:v1", now.Add(-24*time.Hour)),
+			newTestReplicaSet("2", "web:v2", test.createdAt),
+			newTestPod("web-2-a", "2", test.status),
+		).Build()
+
+		r := NewRemediator(nil, c, newTestPolicy())
+
+		actions, err := r.planRollback(ctx, event, nil)
+		if err != nil {
+			t.Fatalf("%s: unexpected error planning rollback: %v", test.name, err)
+		}
+
+		if !test.want {
+			if len(actions) != 0 {
+				t.Errorf("%s: expected no rollback, got %v", test.name, actions[0].remediation)
+			}
+
+			continue
+		}
+
+		if len(actions) != 1 {
+			t.Fatalf("%s: expected a rollback, got %d actions", test.name, len(actions))
+		}
+
+		if remediation := actions[0].remediation; remediation.Target != "deployment/web" ||
+			remediation.Changes[0].Previous != "2" || remediation.Changes[0].Current != "1" {
+			t.Errorf("%s: expected a rollback of deployment/web from 2 to 1, got %+v", test.name, remediation)
+		}
+
+		if err := actions[0].apply(ctx, false); err != nil {
+			t.Fatalf("%s: unexpected error rolling back: %v", test.name, err)
+		}
+
+		deployment := &appsv1.Deployment{}
+
+		if err := c.Get(ctx, client.ObjectKey{Namespace: "prod", Name: "web"}, deployment); err != nil {
+			t.Fatalf("%s: unexpected error getting deployment: %v", test.name, err)
+		}
+
+		if image := deployment.Spec.Template.Spec.Containers[0].Image; image != "web:v1" {
+			t.Errorf("%s: expected the template of the previous revision, got image %s", test.name, image)
+		}
+
+		if _, ok := deployment.Spec.Template.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; ok {
+			t.Errorf("%s: expected the pod template hash label to be removed", test.name)
+		}
+	}
+}
+
+func TestPlanIncreaseMemory(t *testing.T) {
+	ctx := context.Background()
+
+	event := &models.PodEvent{
+		Namespace: "prod",
+		OwnerType: "Deployment",
+	}
+
+	tests := []struct {
+		name        string
+		memoryLimit string
+		status      corev1.ContainerStatus
+		want        string
+	}{
+		{
+			name:        "OOMKilled enough times",
+			memoryLimit: "512Mi",
+			status:      crashLoopingStatus,
+			want:        "768Mi",
+		},
+		{
+			name:        "capped at the maximum limit",
+			memoryLimit: "3Gi",
+			status:      crashLoopingStatus,
+			want:        "4Gi",
+		},
+		{
+			name:        "already at the maximum limit",
+			memoryLimit: "4Gi",
+			status:      crashLoopingStatus,
+		},
+		{
+			name:        "not restarted enough times",
+			memoryLimit: "512Mi",
+			status: corev1.ContainerStatus{
+				Name:                 "web",
+				RestartCount:         1,
+				LastTerminationState: crashLoopingStatus.LastTerminationState,
+			},
+		},
+		{
+			name:        "not OOMKilled",
+			memoryLimit: "512Mi",
+			status: corev1.ContainerStatus{
+				Name:         "web",
+				RestartCount: 3,
+				LastTerminationState: corev1.ContainerState{
+					Terminated: &corev1.ContainerStateTerminated{Reason: "Error"},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		pod := newTestPod("web-2-a", "2", test.status)
+
+		c := fake.NewClientBuilder().WithObjects(
+			newTestDeployment("2", test.memoryLimit),
+			newTestReplicaSet("2", "web:v2", time.Now()),
+			pod,
+		).Build()
+
+		r := NewRemediator(nil, c, newTestPolicy())
+
+		actions, err := r.planIncreaseMemory(ctx, event, pod)
+		if err != nil {
+			t.Fatalf("%s: unexpected error planning memory increase: %v", test.name, err)
+		}
+
+		if test.want == "" {
+			if len(actions) != 0 {
+				t.Errorf("%s: expected no memory increase, got %v", test.name, actions[0].remediation)
+			}
+
+			continue
+		}
+
+		if len(actions) != 1 {
+			t.Fatalf("%s: expected a memory increase, got %d actions", test.name, len(actions))
+		}
+
+		if change := actions[0].remediation.Changes[0]; change.Previous != test.memoryLimit || change.Current != test.want {
+			t.Errorf("%s: expected the limit to go from %s to %s, got %+v", test.name, test.memoryLimit, test.want, change)
+		}
+
+		if err := actions[0].apply(ctx, false); err != nil {
+			t.Fatalf("%s: unexpected error increasing memory: %v", test.name, err)
+		}
+
+		deployment := &appsv1.Deployment{}
+
+		if err := c.Get(ctx, client.ObjectKey{Namespace: "prod", Name: "web"}, deployment); err != nil {
+			t.Fatalf("%s: unexpected error getting deployment: %v", test.name, err)
+		}
+
+		limit := deployment.Spec.Template.Spec.Containers[0].Resources.Limits[corev1.ResourceMemory]
+		if want := resource.MustParse(test.want); limit.Cmp(want) != 0 {
+			t.Errorf("%s: expected the deployment limit to be %s, got %s", test.name, test.want, limit.String())
+		}
+	}
+}
+
+func TestScaleQuantity(t *testing.T) {
+	tests := []struct {
+		quantity string
+		factor   float64
+		max      string
+		want     string
+	}{
+		{quantity: "512Mi", factor: 1.5, max: "4Gi", want: "768Mi"},
+		{quantity: "100M", factor: 1.5, max: "4Gi", want: "144Mi"},
+		{quantity: "3Gi", factor: 1.5, max: "4Gi", want: "4Gi"},
+		{quantity: "1Gi", factor: 2, max: "2Gi", want: "2Gi"},
+	}
+
+	for _, test := range tests {
+		got := scaleQuantity(resource.MustParse(test.quantity), test.factor, resource.MustParse(test.max))
+
+		if want := resource.MustParse(test.want); got.Cmp(want) != 0 {
+			t.Errorf("expected %s scaled by %v up to %s to be %s, got %s", test.quantity, test.factor, test.max,
+				test.want, got.String())
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// Package remediation takes actions to remediate known failure patterns of
+// incidents, in the namespaces which opt in.
+package remediation
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"time"
+
+	"github.com/spf13/viper"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// The actions which namespaces can opt in to
+const (
+	ActionRollback       = "rollback"
+	ActionIncreaseMemory = "increase-memory"
+	ActionDeleteStuckPod = "delete-stuck-pod"
+)
+
+// memory limits are increased by at most this factor at a time
+const maxMemoryFactor = 2.0
+
+var (
+	// global kill switch, no action is taken unless it is set
+	enabled bool
+
+	// only record the actions which would be taken, in every namespace
+	dryRun bool
+
+	// path to the YAML file holding the remediation policy
+	configFile string
+)
+
+func init() {
+	viper.SetDefault("REMEDIATION_ENABLED", false)
+	viper.SetDefault("REMEDIATION_DRY_RUN", false)
+	viper.AutomaticEnv()
+
+	enabled = viper.GetBool("REMEDIATION_ENABLED")
+	dryRun = viper.GetBool("REMEDIATION_DRY_RUN")
+	configFile = viper.GetString("REMEDIATION_CONFIG_FILE")
+}
+
+// Enabled returns whether remediation is enabled, which is the global kill
+// switch of every action
+func Enabled() bool {
+	return enabled
+}
+
+// Policy is the remediation policy of the agent. An example:
+//
+//	namespaces:
+//	  - match: ["prod-*"]
+//	    actions: [rollback, delete-stuck-pod]
+//	  - match: ["staging"]
+//	    actions: [rollback, increase-memory, delete-stuck-pod]
+//	    dryRun: true
+//	rollback:
+//	  window: 5m
+//	increaseMemory:
+//	  afterRestarts: 3
+//	  factor: 1.5
+//	  maxLimit: 4Gi
+//	deleteStuckPod:
+//	  notReadyFor: 5m
+//
+// Namespaces which match none of the namespace policies are not remediated.
+type Policy struct {
+	Namespaces     []*NamespacePolicy   `json:"namespaces"`
+	Rollback       RollbackPolicy       `json:"rollback"`
+	IncreaseMemory IncreaseMemoryPolicy `json:"increaseMemory"`
+	DeleteStuckPod DeleteStuckPodPolicy `json:"deleteStuckPod"`
+}
+
+// NamespacePolicy opts the namespaces matching one of the glob patterns of
+// Match in to the actions. The first matching policy is used. In dry-run
+// mode, the actions are validated by the API server and recorded, but not
+// applied.
+type NamespacePolicy struct {
+	Match   []string `json:"match"`
+	Actions []string `json:"actions"`
+	DryRun  bool     `json:"dryRun"`
+}
+
+// RollbackPolicy rolls a deployment back to its previous ReplicaSet when
+// every pod of its new ReplicaSet crash-loops within Window of its rollout
+type RollbackPolicy struct {
+	Window metav1.Duration `json:"window"`
+}
+
+// IncreaseMemoryPolicy multiplies the memory limit of a container of a
+// deployment by Factor, up to MaxLimit, once it has been OOMKilled and
+// restarted AfterRestarts times
+type IncreaseMemoryPolicy struct {
+	AfterRestarts int32             `json:"afterRestarts"`
+	Factor        float64           `json:"factor"`
+	MaxLimit      resource.Quantity `json:"maxLimit"`
+}
+
+// DeleteStuckPodPolicy deletes the pods of controllers on nodes which have
+// not been ready for NotReadyFor, so that they are scheduled elsewhere, once
+// they no longer tolerate it. Pods which are already terminating are deleted
+// without a grace period.
+type DeleteStuckPodPolicy struct {
+	NotReadyFor metav1.Duration `json:"notReadyFor"`
+}
+
+// LoadPolicy reads the remediation policy from REMEDIATION_CONFIG_FILE, which
+// opts no namespace in when it is not set
+func LoadPolicy() (*Policy, error) {
+	policy := &Policy{}
+
+	if configFile != "" {
+		data, err := ioutil.ReadFile(configFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading remediation config file %s. Error: %w", configFile, err)
+		}
+
+		if err := yaml.UnmarshalStrict(data, policy); err != nil {
+			return nil, fmt.Errorf("error parsing remediation config file %s. Error: %w", configFile, err)
+		}
+	}
+
+	policy.setDefaults()
+
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+func (p *Policy) setDefaults() {
+	if p.Rollback.Window.Duration == 0 {
+		p.Rollback.Window.Duration = 5 * time.Minute
+	}
+
+	if p.IncreaseMemory.AfterRestarts == 0 {
+		p.IncreaseMemory.AfterRestarts = 3
+	}
+
+	if p.IncreaseMemory.Factor == 0 {
+		p.IncreaseMemory.Factor = 1.5
+	}
+
+	if p.IncreaseMemory.MaxLimit.IsZero() {
+		p.IncreaseMemory.MaxLimit = resource.MustParse("4Gi")
+	}
+
+	if p.DeleteStuckPod.NotReadyFor.Duration == 0 {
+		p.DeleteStuckPod.NotReadyFor.Duration = 5 * time.Minute
+	}
+}
+
+// Validate checks that the namespace patterns and actions are valid and that
+// the settings of the actions are within bounds
+func (p *Policy) Validate() error {
+	for _, nsPolicy := range p.Namespaces {
+		if len(nsPolicy.Match) == 0 {
+			return fmt.Errorf("namespace remediation policies must match at least one namespace")
+		}
+
+		for _, pattern := range nsPolicy.Match {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid namespace pattern %q. Error: %w", pattern, err)
+			}
+		}
+
+		for _, action := range nsPolicy.Actions {
+			switch action {
+			case ActionRollback, ActionIncreaseMemory, ActionDeleteStuckPod:
+			default:
+				return fmt.Errorf("unknown remediation action %q", action)
+			}
+		}
+	}
+
+	if p.Rollback.Window.Duration < 0 || p.DeleteStuckPod.NotReadyFor.Duration < 0 {
+		return fmt.Errorf("remediation durations must not be negative")
+	}
+
+	if p.IncreaseMemory.AfterRestarts < 1 {
+		return fmt.Errorf("increaseMemory.afterRestarts must be positive")
+	}
+
+	if p.IncreaseMemory.Factor <= 1 || p.IncreaseMemory.Factor > maxMemoryFactor {
+		return fmt.Errorf("increaseMemory.factor must be greater than 1 and at most %g", maxMemoryFactor)
+	}
+
+	if p.IncreaseMemory.MaxLimit.Sign() <= 0 {
+		return fmt.Errorf("increaseMemory.maxLimit must be positive")
+	}
+
+	return nil
+}
+
+// Allowed returns whether the namespace opted in to the action, and whether
+// the action is only a dry run there
+func (p *Policy) Allowed(namespace, action string) (allowed bool, isDryRun bool) {
+	nsPolicy := p.match(namespace)
+	if nsPolicy == nil {
+		return false, false
+	}
+
+	for _, nsAction := range nsPolicy.Actions {
+		if nsAction == action {
+			return true, dryRun || nsPolicy.DryRun
+		}
+	}
+
+	return false, false
+}
+
+// AllowedAny returns whether the namespace opted in to any action
+func (p *Policy) AllowedAny(namespace string) bool {
+	nsPolicy := p.match(namespace)
+
+	return nsPolicy != nil && len(nsPolicy.Actions) > 0
+}
+
+// match returns the first namespace policy matching the namespace
+func (p *Policy) match(namespace string) *NamespacePolicy {
+	for _, nsPolicy := range p.Namespaces {
+		for _, pattern := range nsPolicy.Match {
+			if ok, _ := path.Match(pattern, namespace); ok {
+				return nsPolicy
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,245 @@
+package remediation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/porter-dev/porter-agent/pkg/metrics"
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/redis"
+	"github.com/porter-dev/porter-agent/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var remediationLog = ctrl.Log.WithName("remediation")
+
+// how long to wait for a change before reading the feed again
+const readBlock = 15 * time.Second
+
+//+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;patch
+//+kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;delete
+//+kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+
+// action is an action planned to remediate an incident, which is applied, or
+// sent to the API server as a dry run
+type action struct {
+	remediation *models.Remediation
+	apply       func(ctx context.Context, dryRun bool) error
+}
+
+// Remediator takes the actions of the remediation policy as events are added
+// to incidents, and deletes the stuck pods of nodes which are not ready. Each
+// action is applied at most once per incident and target, and is recorded on
+// the incident whether it was applied, dry run or failed. Actions which were
+// dry run or failed are taken again on the next event.
+type Remediator struct {
+	redisClient *redis.Client
+	client      client.Client
+	policy      *Policy
+
+	// the stuck pods the delete-stuck-pod action was taken on
+	stuckPods map[types.UID]bool
+}
+
+func NewRemediator(redisClient *redis.Client, client client.Client, policy *Policy) *Remediator {
+	return &Remediator{
+		redisClient: redisClient,
+		client:      client,
+		policy:      policy,
+		stuckPods:   make(map[types.UID]bool),
+	}
+}
+
+// Start remediates the incidents which events are added to after it starts,
+// and checks the pods of nodes which are not ready periodically, until the
+// context is done. It is run by the manager, so that only the leader takes
+// actions.
+func (r *Remediator) Start(ctx context.Context) error {
+	go r.deleteStuckPodsEvery(ctx, stuckPodsInterval)
+
+	r.redisClient.FollowIncidentChanges(ctx, readBlock, func(change *models.IncidentChange) {
+		if change.Event == nil {
+			return
+		}
+
+		if err := r.remediate(ctx, change.IncidentID, change.Event); err != nil {
+			remediationLog.Error(err, "error remediating incident", "incidentID", change.IncidentID)
+		}
+	}, func(err error) {
+		remediationLog.Error(err, "error reading incident changes")
+	})
+
+	return nil
+}
+
+// remediate plans the actions the namespace of an event opted in to, and
+// takes the ones which were not already taken for the incident
+func (r *Remediator) remediate(ctx context.Context, incidentID string, event *models.PodEvent) error {
+	ctx, span := tracing.StartSpan(ctx, "remediation.remediate", trace.WithAttributes(
+		attribute.String("incident_id", incidentID),
+	))
+	defer span.End()
+
+	planners := []struct {
+		name string
+		plan func(ctx context.Context, event *models.PodEvent, pod *corev1.Pod) ([]*action, error)
+	}{
+		{ActionRollback, r.planRollback},
+		{ActionIncreaseMemory, r.planIncreaseMemory},
+	}
+
+	if !r.policy.AllowedAny(event.Namespace) {
+		return nil
+	}
+
+	pod, err := r.getPod(ctx, event.Namespace, event.PodName)
+	if err != nil {
+		return err
+	}
+
+	taken, err := r.redisClient.GetRemediations(ctx, incidentID)
+	if err != nil {
+		return err
+	}
+
+	for _, planner := range planners {
+		allowed, isDryRun := r.policy.Allowed(event.Namespace, planner.name)
+		if !allowed {
+			continue
+		}
+
+		actions, err := planner.plan(ctx, event, pod)
+		if err != nil {
+			return fmt.Errorf("error planning %s for event ID: %s. Error: %w", planner.name, event.EventID, err)
+		}
+
+		for _, action := range actions {
+			if wasTaken(taken, action.remediation) {
+				continue
+			}
+
+			r.take(ctx, incidentID, event.EventID, action, isDryRun)
+
+			taken = append(taken, action.remediation)
+		}
+	}
+
+	return nil
+}
+
+// take applies an action and records it on the incident
+func (r *Remediator) take(ctx context.Context, incidentID, eventID string, action *action, isDryRun bool) {
+	remediation := action.remediation
+	remediation.EventID = eventID
+
+	r.apply(ctx, action, isDryRun)
+
+	remediationLog.Info("remediation action taken", "incidentID", incidentID, "action", remediation.Action,
+		"target", remediation.Target, "container", remediation.Container, "status", remediation.Status,
+		"error", remediation.Error)
+
+	if err := r.redisClient.AddRemediation(ctx, incidentID, remediation); err != nil {
+		remediationLog.Error(err, "error recording remediation", "incidentID", incidentID, "action", remediation.Action)
+	}
+}
+
+// takeWithoutIncident applies an action on a target which has no active
+// incident to record it on
+func (r *Remediator) takeWithoutIncident(ctx context.Context, action *action, isDryRun bool) {
+	remediation := action.remediation
+
+	r.apply(ctx, action, isDryRun)
+
+	remediationLog.Info("remediation action taken without incident", "action", remediation.Action,
+		"target", remediation.Target, "status", remediation.Status, "error", remediation.Error)
+}
+
+// apply applies an action, or sends it to the API server as a dry run, and
+// sets the status of its remediation
+func (r *Remediator) apply(ctx context.Context, action *action, isDryRun bool) {
+	remediation := action.remediation
+	remediation.Status = models.RemediationApplied
+
+	if isDryRun {
+		remediation.Status = models.RemediationDryRun
+	}
+
+	if err := action.apply(ctx, isDryRun); err != nil {
+		remediation.Status = models.RemediationFailed
+		remediation.Error = err.Error()
+	}
+
+	metrics.RemediationActions.WithLabelValues(remediation.Action, string(remediation.Status)).Inc()
+}
+
+// wasTaken returns whether an action was already applied on the same target
+func wasTaken(taken []*models.Remediation, remediation *models.Remediation) bool {
+	for _, prev := range taken {
+		if prev.Status == models.RemediationApplied && prev.Action == remediation.Action &&
+			prev.Target == remediation.Target && prev.Container == remediation.Container {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getPod returns the pod of an event, or nil if it is gone
+func (r *Remediator) getPod(ctx context.Context, namespace, name string) (*corev1.Pod, error) {
+	pod := &corev1.Pod{}
+
+	if err := r.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, pod); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("error getting pod %s. Error: %w", name, err)
+	}
+
+	return pod, nil
+}
+
+// getDeployment returns the deployment which owns a ReplicaSet, or nil if it
+// is not owned by a deployment
+func (r *Remediator) getDeployment(ctx context.Context, rs *appsv1.ReplicaSet) (*appsv1.Deployment, error) {
+	owner := metav1.GetControllerOf(rs)
+	if owner == nil || owner.Kind != "Deployment" {
+		return nil, nil
+	}
+
+	deployment := &appsv1.Deployment{}
+
+	if err := r.client.Get(ctx, types.NamespacedName{Namespace: rs.Namespace, Name: owner.Name}, deployment); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+
+	return deployment, nil
+}
+
+// getReplicaSet returns a ReplicaSet, or nil if it is gone
+func (r *Remediator) getReplicaSet(ctx context.Context, namespace, name string) (*appsv1.ReplicaSet, error) {
+	rs := &appsv1.ReplicaSet{}
+
+	if err := r.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, rs); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+
+	return rs, nil
+}
+
+func patchOptions(dryRun bool) []client.PatchOption {
+	if dryRun {
+		return []client.PatchOption{client.DryRunAll}
+	}
+
+	return nil
+}
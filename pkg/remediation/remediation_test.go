@@ -0,0 +1,45 @@
+package remediation
+
+import (
+	"testing"
+
+	"github.com/porter-dev/porter-agent/pkg/models"
+)
+
+func TestWasTaken(t *testing.T) {
+	remediation := &models.Remediation{Action: ActionIncreaseMemory, Target: "deployment/web", Container: "web"}
+
+	tests := []struct {
+		name string
+		prev *models.Remediation
+		want bool
+	}{
+		{
+			name: "applied",
+			prev: &models.Remediation{Action: ActionIncreaseMemory, Target: "deployment/web", Container: "web",
+				Status: models.RemediationApplied},
+			want: true,
+		},
+		{
+			name: "dry run",
+			prev: &models.Remediation{Action: ActionIncreaseMemory, Target: "deployment/web", Container: "web",
+				Status: models.RemediationDryRun},
+		},
+		{
+			name: "failed",
+			prev: &models.Remediation{Action: ActionIncreaseMemory, Target: "deployment/web", Container: "web",
+				Status: models.RemediationFailed},
+		},
+		{
+			name: "other container",
+			prev: &models.Remediation{Action: ActionIncreaseMemory, Target: "deployment/web", Container: "sidecar",
+				Status: models.RemediationApplied},
+		},
+	}
+
+	for _, test := range tests {
+		if got := wasTaken([]*models.Remediation{test.prev}, remediation); got != test.want {
+			t.Errorf("%s: expected %v, got %v", test.name, test.want, got)
+		}
+	}
+}
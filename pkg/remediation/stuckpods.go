@@ -0,0 +1,224 @@
+package remediation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/porter-dev/porter-agent/pkg/models"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// how often the pods of nodes which are not ready are checked, since they do
+// not change while their node is gone
+const stuckPodsInterval = time.Minute
+
+// deleteStuckPodsEvery checks the pods of the nodes which are not ready at
+// every interval until the context is done
+func (r *Remediator) deleteStuckPodsEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.deleteStuckPods(ctx, time.Now()); err != nil {
+				remediationLog.Error(err, "error deleting stuck pods")
+			}
+		}
+	}
+}
+
+// deleteStuckPods takes the delete-stuck-pod action on the pods of nodes which
+// are not ready, in the namespaces which opted in to it. The action is recorded
+// on the active incident of the release of the pod if there is one, and is
+// taken at most once per pod.
+func (r *Remediator) deleteStuckPods(ctx context.Context, now time.Time) error {
+	nodes := &corev1.NodeList{}
+
+	if err := r.client.List(ctx, nodes); err != nil {
+		return fmt.Errorf("error listing nodes. Error: %w", err)
+	}
+
+	notReady := make(map[string]*corev1.NodeCondition)
+
+	for i := range nodes.Items {
+		if ready := getReadyCondition(&nodes.Items[i]); ready != nil && ready.Status != corev1.ConditionTrue {
+			notReady[nodes.Items[i].Name] = ready
+		}
+	}
+
+	pods := &corev1.PodList{}
+
+	if len(notReady) > 0 {
+		if err := r.client.List(ctx, pods); err != nil {
+			return fmt.Errorf("error listing pods. Error: %w", err)
+		}
+	}
+
+	seen := make(map[types.UID]bool)
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+
+		ready, ok := notReady[pod.Spec.NodeName]
+		if !ok {
+			continue
+		}
+
+		allowed, isDryRun := r.policy.Allowed(pod.Namespace, ActionDeleteStuckPod)
+		if !allowed {
+			continue
+		}
+
+		seen[pod.UID] = true
+
+		if r.stuckPods[pod.UID] {
+			continue
+		}
+
+		action := r.planDeleteStuckPod(pod, ready, now)
+		if action == nil {
+			continue
+		}
+
+		incidentID, err := r.getActiveIncident(ctx, pod)
+		if err != nil {
+			return err
+		}
+
+		if incidentID == "" {
+			r.takeWithoutIncident(ctx, action, isDryRun)
+		} else {
+			taken, err := r.redisClient.GetRemediations(ctx, incidentID)
+			if err != nil {
+				return err
+			}
+
+			if !wasTaken(taken, action.remediation) {
+				r.take(ctx, incidentID, "", action, isDryRun)
+			}
+		}
+
+		r.stuckPods[pod.UID] = true
+	}
+
+	// forget the pods which were deleted or whose node is ready again
+	for uid := range r.stuckPods {
+		if !seen[uid] {
+			delete(r.stuckPods, uid)
+		}
+	}
+
+	return nil
+}
+
+// planDeleteStuckPod plans deleting a pod when its node has not been ready for
+// the configured duration, so that its controller schedules it elsewhere. Pods
+// without a controller are left alone, since they would not be recreated, and
+// so are pods until their toleration of the node not being ready expires.
+func (r *Remediator) planDeleteStuckPod(pod *corev1.Pod, ready *corev1.NodeCondition, now time.Time) *action {
+	if metav1.GetControllerOf(pod) == nil {
+		return nil
+	}
+
+	notReadyFor := now.Sub(ready.LastTransitionTime.Time)
+
+	tolerated, ok := toleratedFor(pod, ready)
+	if !ok || notReadyFor < tolerated || notReadyFor < r.policy.DeleteStuckPod.NotReadyFor.Duration {
+		return nil
+	}
+
+	return &action{
+		remediation: &models.Remediation{
+			Action: ActionDeleteStuckPod,
+			Target: "pod/" + pod.Name,
+			Reason: fmt.Sprintf("node %s has not been ready for %s", pod.Spec.NodeName,
+				duration.HumanDuration(notReadyFor)),
+		},
+		apply: func(ctx context.Context, dryRun bool) error {
+			var opts []client.DeleteOption
+
+			// the kubelet of the node cannot confirm the deletion of pods
+			// which are already terminating
+			if pod.DeletionTimestamp != nil {
+				opts = append(opts, client.GracePeriodSeconds(0))
+			}
+
+			if dryRun {
+				opts = append(opts, client.DryRunAll)
+			}
+
+			if err := r.client.Delete(ctx, pod, opts...); err != nil {
+				return fmt.Errorf("error deleting pod %s. Error: %w", pod.Name, err)
+			}
+
+			return nil
+		},
+	}
+}
+
+// toleratedFor returns how long a pod tolerates the taint the node lifecycle
+// controller puts on a node with the given ready condition, and false if it
+// tolerates it forever
+func toleratedFor(pod *corev1.Pod, ready *corev1.NodeCondition) (time.Duration, bool) {
+	taint := &corev1.Taint{
+		Key:    corev1.TaintNodeNotReady,
+		Effect: corev1.TaintEffectNoExecute,
+	}
+
+	if ready.Status == corev1.ConditionUnknown {
+		taint.Key = corev1.TaintNodeUnreachable
+	}
+
+	var tolerated time.Duration
+
+	for i := range pod.Spec.Tolerations {
+		toleration := &pod.Spec.Tolerations[i]
+
+		if !toleration.ToleratesTaint(taint) {
+			continue
+		}
+
+		if toleration.TolerationSeconds == nil {
+			return 0, false
+		}
+
+		if seconds := time.Duration(*toleration.TolerationSeconds) * time.Second; seconds > tolerated {
+			tolerated = seconds
+		}
+	}
+
+	return tolerated, true
+}
+
+func getReadyCondition(node *corev1.Node) *corev1.NodeCondition {
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == corev1.NodeReady {
+			return &node.Status.Conditions[i]
+		}
+	}
+
+	return nil
+}
+
+// getActiveIncident returns the active incident of the release of a pod, or an
+// empty string if there is none
+func (r *Remediator) getActiveIncident(ctx context.Context, pod *corev1.Pod) (string, error) {
+	releaseName := pod.Labels["app.kubernetes.io/instance"]
+	if releaseName == "" {
+		return "", nil
+	}
+
+	if exists, err := r.redisClient.ActiveIncidentExists(ctx, releaseName, pod.Namespace); err != nil || !exists {
+		return "", err
+	}
+
+	return r.redisClient.GetActiveIncident(ctx, releaseName, pod.Namespace)
+}
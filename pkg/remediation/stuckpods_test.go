@@ -0,0 +1,87 @@
+package remediation
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestToleratedFor(t *testing.T) {
+	noExecute := func(key string, seconds int64) corev1.Toleration {
+		toleration := corev1.Toleration{Key: key, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute}
+
+		if seconds > 0 {
+			toleration.TolerationSeconds = &seconds
+		}
+
+		return toleration
+	}
+
+	tenMinutes := int64(600)
+
+	notReady := &corev1.NodeCondition{Type: corev1.NodeReady, Status: corev1.ConditionFalse}
+	unreachable := &corev1.NodeCondition{Type: corev1.NodeReady, Status: corev1.ConditionUnknown}
+
+	tests := []struct {
+		name        string
+		tolerations []corev1.Toleration
+		ready       *corev1.NodeCondition
+		want        time.Duration
+		wantLimited bool
+	}{
+		{
+			name:        "no tolerations",
+			ready:       notReady,
+			want:        0,
+			wantLimited: true,
+		},
+		{
+			name: "default tolerations",
+			tolerations: []corev1.Toleration{
+				noExecute(corev1.TaintNodeNotReady, 300),
+				noExecute(corev1.TaintNodeUnreachable, 60),
+			},
+			ready:       unreachable,
+			want:        time.Minute,
+			wantLimited: true,
+		},
+		{
+			name: "longest matching toleration",
+			tolerations: []corev1.Toleration{
+				noExecute(corev1.TaintNodeNotReady, 300),
+				{Operator: corev1.TolerationOpExists, TolerationSeconds: &tenMinutes},
+			},
+			ready:       notReady,
+			want:        10 * time.Minute,
+			wantLimited: true,
+		},
+		{
+			name: "tolerated forever",
+			tolerations: []corev1.Toleration{
+				noExecute(corev1.TaintNodeNotReady, 0),
+			},
+			ready:       notReady,
+			wantLimited: false,
+		},
+		{
+			name: "toleration of another taint",
+			tolerations: []corev1.Toleration{
+				noExecute(corev1.TaintNodeUnreachable, 0),
+			},
+			ready:       notReady,
+			want:        0,
+			wantLimited: true,
+		},
+	}
+
+	for _, test := range tests {
+		pod := &corev1.Pod{Spec: corev1.PodSpec{Tolerations: test.tolerations}}
+
+		got, limited := toleratedFor(pod, test.ready)
+
+		if limited != test.wantLimited || (limited && got != test.want) {
+			t.Errorf("%s: expected %s, %v, got %s, %v", test.name, test.want, test.wantLimited, got, limited)
+		}
+	}
+}
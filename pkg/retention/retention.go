@@ -0,0 +1,207 @@
+// Package retention decides how long incidents and logs are kept in the store,
+// by namespace.
+package retention
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"time"
+
+	"github.com/spf13/viper"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultRetention is how long incidents and logs are kept when no retention
+// is configured
+const DefaultRetention = time.Hour * 24 * 14
+
+var (
+	incidentRetention time.Duration
+	logRetention      time.Duration
+	restoreRetention  time.Duration
+
+	// path to the YAML file holding the retention policy
+	configFile string
+)
+
+func init() {
+	viper.SetDefault("RETENTION_INCIDENTS", DefaultRetention.String())
+	viper.SetDefault("RETENTION_LOGS", DefaultRetention.String())
+	viper.SetDefault("RETENTION_RESTORED", (time.Hour * 24 * 7).String())
+	viper.AutomaticEnv()
+
+	incidentRetention = viper.GetDuration("RETENTION_INCIDENTS")
+	logRetention = viper.GetDuration("RETENTION_LOGS")
+	restoreRetention = viper.GetDuration("RETENTION_RESTORED")
+	configFile = viper.GetString("RETENTION_CONFIG_FILE")
+}
+
+// Policy is the retention policy of the store. An example:
+//
+//	incidents: 336h
+//	logs: 168h
+//	restored: 168h
+//	namespaces:
+//	  - match: ["prod-*"]
+//	    incidents: 720h
+//	    logs: 336h
+//	  - match: ["preview-*"]
+//	    incidents: 72h
+//	    logs: 24h
+//
+// Incidents include their events, pod sets, comments and acknowledgements.
+// Logs are never kept longer than the incident they were captured for.
+// Restored is how long data restored from the archive is kept for.
+type Policy struct {
+	Incidents  metav1.Duration    `json:"incidents"`
+	Logs       metav1.Duration    `json:"logs"`
+	Restored   metav1.Duration    `json:"restored"`
+	Namespaces []*NamespacePolicy `json:"namespaces"`
+}
+
+// NamespacePolicy overrides the retention of the namespaces matching one of
+// the glob patterns of Match. The first matching policy is used, and unset
+// durations fall back to the default ones.
+type NamespacePolicy struct {
+	Match     []string        `json:"match"`
+	Incidents metav1.Duration `json:"incidents"`
+	Logs      metav1.Duration `json:"logs"`
+}
+
+// LoadPolicy reads the retention policy from RETENTION_CONFIG_FILE, with the
+// durations which are not set in it read from RETENTION_INCIDENTS,
+// RETENTION_LOGS and RETENTION_RESTORED
+func LoadPolicy() (*Policy, error) {
+	policy := &Policy{}
+
+	if configFile != "" {
+		data, err := ioutil.ReadFile(configFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading retention config file %s. Error: %w", configFile, err)
+		}
+
+		if err := yaml.UnmarshalStrict(data, policy); err != nil {
+			return nil, fmt.Errorf("error parsing retention config file %s. Error: %w", configFile, err)
+		}
+	}
+
+	if policy.Incidents.Duration == 0 {
+		policy.Incidents.Duration = incidentRetention
+	}
+
+	if policy.Logs.Duration == 0 {
+		policy.Logs.Duration = logRetention
+	}
+
+	if policy.Restored.Duration == 0 {
+		policy.Restored.Duration = restoreRetention
+	}
+
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// NewDefaultPolicy returns the policy which keeps everything for the default
+// retention
+func NewDefaultPolicy() *Policy {
+	return &Policy{
+		Incidents: metav1.Duration{Duration: DefaultRetention},
+		Logs:      metav1.Duration{Duration: DefaultRetention},
+		Restored:  metav1.Duration{Duration: DefaultRetention},
+	}
+}
+
+// Validate checks that the durations of the policy are positive and that the
+// namespace patterns are valid
+func (p *Policy) Validate() error {
+	if p.Incidents.Duration <= 0 || p.Logs.Duration <= 0 || p.Restored.Duration <= 0 {
+		return fmt.Errorf("retention durations must be positive")
+	}
+
+	for _, nsPolicy := range p.Namespaces {
+		if len(nsPolicy.Match) == 0 {
+			return fmt.Errorf("namespace retention policies must match at least one namespace")
+		}
+
+		for _, pattern := range nsPolicy.Match {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid namespace pattern %q. Error: %w", pattern, err)
+			}
+		}
+
+		if nsPolicy.Incidents.Duration < 0 || nsPolicy.Logs.Duration < 0 {
+			return fmt.Errorf("namespace retention durations must not be negative")
+		}
+	}
+
+	return nil
+}
+
+// IncidentRetention returns how long the incidents of the namespace are kept
+// after they are created
+func (p *Policy) IncidentRetention(namespace string) time.Duration {
+	if nsPolicy := p.match(namespace); nsPolicy != nil && nsPolicy.Incidents.Duration > 0 {
+		return nsPolicy.Incidents.Duration
+	}
+
+	return p.Incidents.Duration
+}
+
+// LogRetention returns how long the logs of the namespace are kept after they
+// are captured
+func (p *Policy) LogRetention(namespace string) time.Duration {
+	if nsPolicy := p.match(namespace); nsPolicy != nil && nsPolicy.Logs.Duration > 0 {
+		return nsPolicy.Logs.Duration
+	}
+
+	return p.Logs.Duration
+}
+
+// MinIncidentRetention returns the shortest retention of incidents in any
+// namespace
+func (p *Policy) MinIncidentRetention() time.Duration {
+	min := p.Incidents.Duration
+
+	for _, nsPolicy := range p.Namespaces {
+		if d := nsPolicy.Incidents.Duration; d > 0 && d < min {
+			min = d
+		}
+	}
+
+	return min
+}
+
+// MaxLogRetention returns the longest retention of logs in any namespace,
+// including restored logs
+func (p *Policy) MaxLogRetention() time.Duration {
+	max := p.Logs.Duration
+
+	if p.Restored.Duration > max {
+		max = p.Restored.Duration
+	}
+
+	for _, nsPolicy := range p.Namespaces {
+		if d := nsPolicy.Logs.Duration; d > max {
+			max = d
+		}
+	}
+
+	return max
+}
+
+func (p *Policy) match(namespace string) *NamespacePolicy {
+	for _, nsPolicy := range p.Namespaces {
+		for _, pattern := range nsPolicy.Match {
+			if ok, err := path.Match(pattern, namespace); err == nil && ok {
+				return nsPolicy
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/server/middleware"
+)
+
+func AcknowledgeIncident(c *gin.Context) {
+	handleIncidentAction(c, "acknowledge", redisClient.AcknowledgeIncident)
+}
+
+func ResolveIncident(c *gin.Context) {
+	handleIncidentAction(c, "resolve", redisClient.ResolveIncident)
+}
+
+func ReopenIncident(c *gin.Context) {
+	handleIncidentAction(c, "reopen", redisClient.ReopenIncident)
+}
+
+func handleIncidentAction(c *gin.Context, action string, fn func(ctx context.Context, incidentID, user string) error) {
+	incidentID := c.Param("incidentID")
+	req := &models.IncidentActionRequest{}
+
+	if !authorizeIncident(c, incidentID) {
+		return
+	}
+
+	// the body is optional for callers with an identity
+	if err := c.ShouldBindJSON(req); err != nil && !errors.Is(err, io.EOF) {
+		middleware.AbortWithError(c, middleware.BadRequest(err.Error()))
+		return
+	}
+
+	user := getActingUser(c, req.User)
+	if user == "" {
+		middleware.AbortWithError(c, middleware.BadRequest("user is required"))
+		return
+	}
+
+	if err := fn(c.Copy(), incidentID, user); err != nil {
+		handleError(c, err, "error performing incident action", "action", action, "incidentID", incidentID)
+		return
+	}
+
+	incident, err := redisClient.GetIncidentDetails(c.Copy(), incidentID)
+	if err != nil {
+		handleError(c, err, "error getting incident details", "incidentID", incidentID)
+		return
+	}
+
+	c.JSON(http.StatusOK, incident)
+}
+
+func AddIncidentComment(c *gin.Context) {
+	incidentID := c.Param("incidentID")
+	req := &models.AddCommentRequest{}
+
+	if !authorizeIncident(c, incidentID) {
+		return
+	}
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		middleware.AbortWithError(c, middleware.BadRequest(err.Error()))
+		return
+	}
+
+	user := getActingUser(c, req.User)
+	if user == "" {
+		middleware.AbortWithError(c, middleware.BadRequest("user is required"))
+		return
+	}
+
+	comment, err := redisClient.AddIncidentComment(c.Copy(), incidentID, user, req.Body)
+	if err != nil {
+		handleError(c, err, "error adding comment to incident", "incidentID", incidentID)
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+func GetIncidentComments(c *gin.Context) {
+	incidentID := c.Param("incidentID")
+
+	if !authorizeIncident(c, incidentID) {
+		return
+	}
+
+	exists, err := redisClient.IncidentExists(c.Copy(), incidentID)
+	if err != nil {
+		handleError(c, err, "error checking for existence of incident", "incidentID", incidentID)
+		return
+	}
+
+	if !exists {
+		middleware.AbortWithError(c, middleware.NotFound("invalid incident ID"))
+		return
+	}
+
+	comments, err := redisClient.GetIncidentComments(c.Copy(), incidentID)
+	if err != nil {
+		handleError(c, err, "error getting comments for incident", "incidentID", incidentID)
+		return
+	}
+
+	c.JSON(http.StatusOK, &models.ListCommentsResponse{
+		Comments: comments,
+	})
+}
+
+// GetIncidentRemediations returns the actions taken to remediate an incident,
+// oldest first
+func GetIncidentRemediations(c *gin.Context) {
+	incidentID := c.Param("incidentID")
+
+	if !authorizeIncident(c, incidentID) {
+		return
+	}
+
+	exists, err := redisClient.IncidentExists(c.Copy(), incidentID)
+	if err != nil {
+		handleError(c, err, "error checking for existence of incident", "incidentID", incidentID)
+		return
+	}
+
+	if !exists {
+		middleware.AbortWithError(c, middleware.NotFound("invalid incident ID"))
+		return
+	}
+
+	remediations, err := redisClient.GetRemediations(c.Copy(), incidentID)
+	if err != nil {
+		handleError(c, err, "error getting remediations for incident", "incidentID", incidentID)
+		return
+	}
+
+	c.JSON(http.StatusOK, &models.ListRemediationsResponse{
+		Remediations: remediations,
+	})
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/porter-dev/porter-agent/pkg/archive"
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/server/middleware"
+)
+
+type ArchiveHandler struct {
+	store archive.Store
+}
+
+// NewArchiveHandler returns the archive handler, where store is nil if
+// archiving is disabled
+func NewArchiveHandler(store archive.Store) *ArchiveHandler {
+	return &ArchiveHandler{
+		store: store,
+	}
+}
+
+// RestoreArchive imports archived incidents and logs back into the store. The
+// ones in namespaces the caller cannot access are skipped.
+func (h *ArchiveHandler) RestoreArchive(c *gin.Context) {
+	req := &models.RestoreArchiveRequest{}
+
+	if h.store == nil {
+		middleware.AbortWithError(c, middleware.NotFound("archiving is not enabled"))
+		return
+	}
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		middleware.AbortWithError(c, middleware.BadRequest(err.Error()))
+		return
+	}
+
+	if req.Since != 0 && req.Until != 0 && req.Since > req.Until {
+		middleware.AbortWithError(c, middleware.BadRequest("since must be before until"))
+		return
+	}
+
+	// archives hold many items of the same namespaces
+	access := map[string]bool{}
+
+	allowed := func(namespace string) (bool, error) {
+		if ok, found := access[namespace]; found {
+			return ok, nil
+		}
+
+		ok, err := middleware.CanAccessNamespace(c, namespace)
+		if err != nil {
+			return false, err
+		}
+
+		access[namespace] = ok
+
+		return ok, nil
+	}
+
+	res, err := archive.Restore(c.Copy(), h.store, redisClient, req, allowed)
+	if err != nil {
+		handleError(c, err, "error restoring archive", "namespace", req.Namespace)
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
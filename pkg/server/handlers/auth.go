@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/porter-dev/porter-agent/pkg/server/middleware"
+	"github.com/porter-dev/porter-agent/pkg/utils"
+)
+
+// authorizeNamespace returns false and writes an error response if the caller
+// cannot read incidents in the namespace
+func authorizeNamespace(c *gin.Context, namespace string) bool {
+	allowed, err := middleware.CanAccessNamespace(c, namespace)
+	if err != nil {
+		handleError(c, err, "error authorizing request", "namespace", namespace)
+		return false
+	}
+
+	if !allowed {
+		middleware.AbortWithError(c, middleware.Forbidden())
+		return false
+	}
+
+	return true
+}
+
+// authorizeIncident is authorizeNamespace for the namespace of an incident ID
+func authorizeIncident(c *gin.Context, incidentID string) bool {
+	incidentObj, err := utils.NewIncidentFromString(incidentID)
+	if err != nil {
+		middleware.AbortWithError(c, middleware.NotFound("invalid incident ID"))
+		return false
+	}
+
+	return authorizeNamespace(c, incidentObj.GetNamespace())
+}
+
+// getActingUser returns the user performing an action, which is the
+// authenticated identity if there is one or the user given in the request
+func getActingUser(c *gin.Context, requestUser string) string {
+	if identity := middleware.GetIdentity(c); identity != nil && !identity.Admin {
+		return identity.Username
+	}
+
+	return requestUser
+}
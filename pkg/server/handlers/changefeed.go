@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/porter-dev/porter-agent/pkg/models"
+)
+
+// number of changes buffered for each stream, streams which fall further
+// behind are closed so that their client resumes from its last change
+const changeSubscriberBuffer = 100
+
+var errTooManyStreams = errors.New("too many incident streams")
+
+// changeFeed reads the incident change feed once for all the streams of the
+// server, and fans the changes out to them, so that streams do not each hold
+// a connection of the Redis pool while they wait for changes
+type changeFeed struct {
+	maxSubscribers int
+
+	mu          sync.Mutex
+	started     bool
+	subscribers map[*changeSubscriber]bool
+}
+
+type changeSubscriber struct {
+	// changes is closed when the subscriber falls behind
+	changes chan *models.IncidentChange
+}
+
+func newChangeFeed(maxSubscribers int) *changeFeed {
+	return &changeFeed{
+		maxSubscribers: maxSubscribers,
+		subscribers:    make(map[*changeSubscriber]bool),
+	}
+}
+
+// subscribe returns a subscriber receiving the changes published from now on,
+// and starts reading the feed on the first call
+func (f *changeFeed) subscribe() (*changeSubscriber, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.subscribers) >= f.maxSubscribers {
+		return nil, errTooManyStreams
+	}
+
+	if !f.started {
+		f.started = true
+
+		go redisClient.FollowIncidentChanges(context.Background(), streamHeartbeatInterval, f.publish, func(err error) {
+			httpLogger.Error(err, "error reading incident changes")
+		})
+	}
+
+	sub := &changeSubscriber{
+		changes: make(chan *models.IncidentChange, changeSubscriberBuffer),
+	}
+
+	f.subscribers[sub] = true
+
+	return sub, nil
+}
+
+func (f *changeFeed) unsubscribe(sub *changeSubscriber) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.subscribers[sub] {
+		delete(f.subscribers, sub)
+		close(sub.changes)
+	}
+}
+
+func (f *changeFeed) publish(change *models.IncidentChange) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for sub := range f.subscribers {
+		select {
+		case sub.changes <- change:
+		default:
+			delete(f.subscribers, sub)
+			close(sub.changes)
+		}
+	}
+}
+
+// compareChangeIDs compares the IDs of two changes, which are Redis stream
+// IDs of the form <milliseconds>-<sequence>
+func compareChangeIDs(a, b string) int {
+	aMillis, aSeq := parseChangeID(a)
+	bMillis, bSeq := parseChangeID(b)
+
+	switch {
+	case aMillis < bMillis || (aMillis == bMillis && aSeq < bSeq):
+		return -1
+	case aMillis == bMillis && aSeq == bSeq:
+		return 0
+	default:
+		return 1
+	}
+}
+
+func parseChangeID(id string) (uint64, uint64) {
+	parts := strings.SplitN(id, "-", 2)
+
+	millis, _ := strconv.ParseUint(parts[0], 10, 64)
+
+	var seq uint64
+
+	if len(parts) == 2 {
+		seq, _ = strconv.ParseUint(parts[1], 10, 64)
+	}
+
+	return millis, seq
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/porter-dev/porter-agent/pkg/models"
+)
+
+func TestChangeFeed(t *testing.T) {
+	feed := newChangeFeed(2)
+
+	// the feed is not read from Redis in this test
+	feed.started = true
+
+	first, err := feed.subscribe()
+	if err != nil {
+		t.Fatalf("unexpected error subscribing: %v", err)
+	}
+
+	second, err := feed.subscribe()
+	if err != nil {
+		t.Fatalf("unexpected error subscribing: %v", err)
+	}
+
+	if _, err := feed.subscribe(); !errors.Is(err, errTooManyStreams) {
+		t.Errorf("expected too many streams, got %v", err)
+	}
+
+	// the first subscriber reads its changes while the second falls behind
+	for i := 0; i <= changeSubscriberBuffer; i++ {
+		feed.publish(&models.IncidentChange{ID: "1-0"})
+		<-first.changes
+	}
+
+	for range second.changes {
+	}
+
+	if len(feed.subscribers) != 1 {
+		t.Errorf("expected the subscriber which fell behind to be dropped, got %d subscribers", len(feed.subscribers))
+	}
+
+	// there is room again for a new subscriber
+	if _, err := feed.subscribe(); err != nil {
+		t.Errorf("unexpected error subscribing: %v", err)
+	}
+
+	feed.unsubscribe(first)
+	feed.unsubscribe(first)
+
+	if _, ok := <-first.changes; ok {
+		t.Errorf("expected the changes of an unsubscribed subscriber to be closed")
+	}
+}
+
+func TestCompareChangeIDs(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "1-0", b: "1-0", want: 0},
+		{a: "1-1", b: "1-0", want: 1},
+		{a: "2-0", b: "10-0", want: -1},
+		{a: "1700000000000-5", b: "1700000000000-12", want: -1},
+		{a: "0-0", b: "1-0", want: -1},
+	}
+
+	for _, test := range tests {
+		if got := compareChangeIDs(test.a, test.b); got != test.want {
+			t.Errorf("expected comparing %s and %s to be %d, got %d", test.a, test.b, test.want, got)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	porterErrors "github.com/porter-dev/porter-agent/pkg/errors"
+	"github.com/porter-dev/porter-agent/pkg/redis"
+	"github.com/porter-dev/porter-agent/pkg/server/middleware"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// handleError writes the error response for an error, logging it with the
+// request ID if it is not the caller's fault
+func handleError(c *gin.Context, err error, msg string, keysAndValues ...interface{}) {
+	apiErr := toAPIError(err)
+
+	if apiErr.Status >= http.StatusInternalServerError {
+		middleware.RequestLogger(c, httpLogger).Error(err, msg, keysAndValues...)
+	}
+
+	middleware.AbortWithError(c, apiErr)
+}
+
+// toAPIError maps the sentinel errors of the store to the status codes of the
+// API. Errors which are not known are internal errors.
+func toAPIError(err error) *middleware.Error {
+	var apiErr *middleware.Error
+
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr
+	case errors.Is(err, porterErrors.IncidentNotFoundError):
+		return middleware.NotFound("invalid incident ID")
+	case errors.Is(err, porterErrors.LogsNotFoundError):
+		return middleware.NotFound("no such logs")
+	case errors.Is(err, porterErrors.SnapshotNotFoundError):
+		return middleware.NotFound(porterErrors.SnapshotNotFoundError.Error())
+	case errors.Is(err, porterErrors.IncidentAlreadyResolvedError):
+		return middleware.Conflict(porterErrors.IncidentAlreadyResolvedError.Error())
+	case errors.Is(err, porterErrors.IncidentNotResolvedError):
+		return middleware.Conflict(porterErrors.IncidentNotResolvedError.Error())
+	case errors.Is(err, porterErrors.ActiveIncidentExistsError):
+		return middleware.Conflict(porterErrors.ActiveIncidentExistsError.Error())
+	case redis.IsUnavailableError(err),
+		apierrors.IsServiceUnavailable(err),
+		apierrors.IsServerTimeout(err),
+		apierrors.IsTimeout(err):
+		return middleware.Unavailable()
+	default:
+		return middleware.InternalError()
+	}
+}
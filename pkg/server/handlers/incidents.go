@@ -1,118 +1,264 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/redis"
+	"github.com/porter-dev/porter-agent/pkg/server/middleware"
 	"github.com/porter-dev/porter-agent/pkg/utils"
 )
 
-func GetAllIncidents(c *gin.Context) {
-	incidentIDs, err := redisClient.GetAllIncidents(c.Copy())
-	if err != nil {
-		httpLogger.Error(err, "error getting list of all incidents")
+// incidentFilterParams maps the query parameters of the incident list
+// endpoints to the indexed fields they filter on
+var incidentFilterParams = map[string]string{
+	"state":         redis.IndexFieldState,
+	"severity":      redis.IndexFieldSeverity,
+	"namespace":     redis.IndexFieldNamespace,
+	"release":       redis.IndexFieldRelease,
+	"owner_kind":    redis.IndexFieldOwnerKind,
+	"reason":        redis.IndexFieldReason,
+	"chart_name":    redis.IndexFieldChartName,
+	"fingerprint":   redis.IndexFieldFingerprint,
+	"after_rollout": redis.IndexFieldAfterRollout,
+}
 
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "internal server error",
-		})
-		return
+// parseIncidentQuery reads the filters and sorting of the incident list
+// endpoints. Filters take comma-separated values, time ranges take unix
+// timestamps, sort is created_at or updated_at and order is asc or desc.
+func parseIncidentQuery(c *gin.Context) (*redis.IncidentQuery, error) {
+	query := &redis.IncidentQuery{
+		Filters: make(map[string][]string),
 	}
 
-	var incidents []*models.Incident
+	for param, field := range incidentFilterParams {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
 
-	for _, id := range incidentIDs {
-		incident, err := redisClient.GetIncidentDetails(c.Copy(), id)
-		if err != nil {
-			httpLogger.Error(err, "error getting incident details")
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v == "" {
+				continue
+			}
 
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "internal server error",
-			})
-			return
+			if field == redis.IndexFieldState {
+				v = strings.ToUpper(v)
+			}
+
+			query.Filters[field] = append(query.Filters[field], v)
+		}
+	}
+
+	for param, dest := range map[string]*int64{
+		"created_after":  &query.CreatedAfter,
+		"created_before": &query.CreatedBefore,
+		"updated_after":  &query.UpdatedAfter,
+		"updated_before": &query.UpdatedBefore,
+	} {
+		value := c.Query(param)
+		if value == "" {
+			continue
 		}
 
-		incidents = append(incidents, incident)
+		ts, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || ts <= 0 {
+			return nil, middleware.BadRequest(fmt.Sprintf("%s must be a unix timestamp", param)).
+				WithDetail("parameter", param)
+		}
+
+		*dest = ts
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"incidents": incidents,
-	})
+	switch sortBy := c.DefaultQuery("sort", redis.SortByCreatedAt); sortBy {
+	case redis.SortByCreatedAt, redis.SortByUpdatedAt:
+		query.SortBy = sortBy
+	default:
+		return nil, middleware.BadRequest(fmt.Sprintf("sort must be one of %s or %s", redis.SortByCreatedAt, redis.SortByUpdatedAt)).
+			WithDetail("parameter", "sort")
+	}
+
+	switch order := c.DefaultQuery("order", "desc"); order {
+	case "asc":
+		query.Ascending = true
+	case "desc":
+	default:
+		return nil, middleware.BadRequest("order must be one of asc or desc").WithDetail("parameter", "order")
+	}
+
+	return query, nil
 }
 
-func GetIncidentsByReleaseNamespace(c *gin.Context) {
-	releaseName := c.Param("releaseName")
-	namespace := c.Param("namespace")
+const (
+	defaultIncidentListLimit = 50
+	maxIncidentListLimit     = 500
+)
+
+// parsePagination reads the limit and offset query parameters. The limit
+// defaults to defaultIncidentListLimit and is at most maxIncidentListLimit.
+func parsePagination(c *gin.Context) (int, int, error) {
+	limit := defaultIncidentListLimit
+	offset := 0
+
+	if value := c.Query("limit"); value != "" {
+		l, err := strconv.Atoi(value)
+		if err != nil || l <= 0 {
+			return 0, 0, middleware.BadRequest("limit must be a positive integer").WithDetail("parameter", "limit")
+		}
 
-	incidentIDs, err := redisClient.GetIncidentsByReleaseNamespace(c.Copy(), releaseName, namespace)
+		if l < maxIncidentListLimit {
+			limit = l
+		} else {
+			limit = maxIncidentListLimit
+		}
+	}
+
+	if value := c.Query("offset"); value != "" {
+		o, err := strconv.Atoi(value)
+		if err != nil || o < 0 {
+			return 0, 0, middleware.BadRequest("offset must be a non-negative integer").WithDetail("parameter", "offset")
+		}
+
+		offset = o
+	}
+
+	return limit, offset, nil
+}
+
+// listIncidents responds with a page of the details of the incidents matching
+// the query, leaving out the ones in namespaces the caller cannot access
+func listIncidents(c *gin.Context, query *redis.IncidentQuery) {
+	limit, offset, err := parsePagination(c)
 	if err != nil {
-		httpLogger.Error(err, "error getting incidents for release", "releaseName", releaseName)
+		middleware.AbortWithError(c, toAPIError(err))
+		return
+	}
 
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "internal server error",
-		})
+	incidentIDs, err := redisClient.QueryIncidents(c.Copy(), query)
+	if err != nil {
+		handleError(c, err, "error querying incidents")
 		return
 	}
 
-	var incidents []*models.Incident
+	var allowedIDs []string
+
+	// only return the incidents of the namespaces the caller has access to
+	allowedNamespaces := make(map[string]bool)
 
 	for _, id := range incidentIDs {
-		incident, err := redisClient.GetIncidentDetails(c.Copy(), id)
+		incidentObj, err := utils.NewIncidentFromString(id)
 		if err != nil {
-			httpLogger.Error(err, "error getting incident details")
+			continue
+		}
+
+		namespace := incidentObj.GetNamespace()
 
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "internal server error",
-			})
-			return
+		allowed, ok := allowedNamespaces[namespace]
+		if !ok {
+			allowed, err = middleware.CanAccessNamespace(c, namespace)
+			if err != nil {
+				handleError(c, err, "error authorizing request", "namespace", namespace)
+				return
+			}
+
+			allowedNamespaces[namespace] = allowed
 		}
 
-		incidents = append(incidents, incident)
+		if allowed {
+			allowedIDs = append(allowedIDs, id)
+		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"incidents": incidents,
-	})
+	res := &models.ListIncidentsResponse{
+		Incidents: []*models.Incident{},
+		Total:     len(allowedIDs),
+	}
+
+	if offset > len(allowedIDs) {
+		offset = len(allowedIDs)
+	}
+
+	pageIDs := allowedIDs[offset:]
+
+	if len(pageIDs) > limit {
+		pageIDs = pageIDs[:limit]
+
+		nextOffset := offset + limit
+		res.NextOffset = &nextOffset
+	}
+
+	incidents, err := redisClient.GetIncidentsDetails(c.Copy(), pageIDs)
+	if err != nil {
+		handleError(c, err, "error getting incident details")
+		return
+	}
+
+	res.Incidents = append(res.Incidents, incidents...)
+
+	c.JSON(http.StatusOK, res)
+}
+
+func GetAllIncidents(c *gin.Context) {
+	query, err := parseIncidentQuery(c)
+	if err != nil {
+		middleware.AbortWithError(c, toAPIError(err))
+		return
+	}
+
+	listIncidents(c, query)
+}
+
+func GetIncidentsByReleaseNamespace(c *gin.Context) {
+	releaseName := c.Param("releaseName")
+	namespace := c.Param("namespace")
+
+	if !authorizeNamespace(c, namespace) {
+		return
+	}
+
+	query, err := parseIncidentQuery(c)
+	if err != nil {
+		middleware.AbortWithError(c, toAPIError(err))
+		return
+	}
+
+	query.Filters[redis.IndexFieldNamespace] = []string{namespace}
+	query.Filters[redis.IndexFieldRelease] = []string{releaseName}
+
+	listIncidents(c, query)
 }
 
 func GetIncidentEventsByID(c *gin.Context) {
 	incidentID := c.Param("incidentID")
 
+	if !authorizeIncident(c, incidentID) {
+		return
+	}
+
 	exists, err := redisClient.IncidentExists(c.Copy(), incidentID)
 	if err != nil {
-		httpLogger.Error(err, "error checking for existence of incident", "incidentID", incidentID)
-
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "internal server error",
-		})
+		handleError(c, err, "error checking for existence of incident", "incidentID", incidentID)
 		return
 	}
 
 	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "invalid incident ID",
-		})
+		middleware.AbortWithError(c, middleware.NotFound("invalid incident ID"))
 		return
 	}
 
 	events, err := redisClient.GetIncidentEventsByID(c.Copy(), incidentID)
 	if err != nil {
-		httpLogger.Error(err, "error getting events for incident", "incidentID", incidentID)
-
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "internal server error",
-		})
+		handleError(c, err, "error getting events for incident", "incidentID", incidentID)
 		return
 	}
 
 	resolved, err := redisClient.IsIncidentResolved(c.Copy(), incidentID)
 	if err != nil {
-		httpLogger.Error(err, "error checking if incident is resolved", "incidentID", incidentID)
-
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "internal server error",
-		})
+		handleError(c, err, "error checking if incident is resolved", "incidentID", incidentID)
 		return
 	}
 
@@ -124,60 +270,69 @@ func GetIncidentEventsByID(c *gin.Context) {
 
 	latestEvent, err := redisClient.GetLatestEventForIncident(c.Copy(), incidentID)
 	if err != nil {
-		httpLogger.Error(err, "error fetching latest event", "incidentID", incidentID)
-
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "internal server error",
-		})
+		handleError(c, err, "error fetching latest event", "incidentID", incidentID)
 		return
 	}
 
 	incidentObj, err := utils.NewIncidentFromString(incidentID)
 	if err != nil {
-		httpLogger.Error(err, "error getting incident object from ID:", incidentID)
-
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "internal server error",
-		})
+		handleError(c, err, "error getting incident object from ID:", incidentID)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"incident_id":    incidentID,
-		"release_name":   strings.Split(incidentID, ":")[1],
-		"chart_name":     latestEvent.ChartName,
-		"created_at":     incidentObj.GetTimestamp(),
-		"updated_at":     latestEvent.Timestamp,
-		"latest_state":   latestState,
-		"latest_reason":  latestEvent.Reason,
-		"latest_message": latestEvent.Message,
-		"events":         events,
+	c.JSON(http.StatusOK, &models.IncidentEventsResponse{
+		IncidentID:    incidentID,
+		ReleaseName:   incidentObj.GetReleaseName(),
+		Namespace:     incidentObj.GetNamespace(),
+		ChartName:     latestEvent.ChartName,
+		CreatedAt:     incidentObj.GetTimestamp(),
+		UpdatedAt:     latestEvent.Timestamp,
+		LatestState:   latestState,
+		LatestReason:  latestEvent.Reason,
+		LatestMessage: latestEvent.Message,
+		Events:        events,
 	})
 }
 
+// GetEventSnapshot returns the snapshot of the pod of an event, taken when the
+// event was recorded
+func GetEventSnapshot(c *gin.Context) {
+	incidentID := c.Param("incidentID")
+	eventID := c.Param("eventID")
+
+	if !authorizeIncident(c, incidentID) {
+		return
+	}
+
+	snapshot, err := redisClient.GetSnapshot(c.Copy(), incidentID, eventID)
+	if err != nil {
+		handleError(c, err, "error getting snapshot for event", "incidentID", incidentID, "eventID", eventID)
+		return
+	}
+
+	c.JSON(http.StatusOK, snapshot)
+}
+
 func GetLogs(c *gin.Context) {
 	logID := c.Param("logID")
 
-	logs, err := redisClient.GetLogs(c.Copy(), logID)
+	logObj, err := utils.NewLogFromString(logID)
 	if err != nil {
-		if strings.Contains(err.Error(), "no such logs") {
-			httpLogger.Error(err, "no such logs", "logID", logID)
-
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "no such logs",
-			})
-			return
-		}
+		middleware.AbortWithError(c, middleware.NotFound("no such logs"))
+		return
+	}
 
-		httpLogger.Error(err, "error getting logs", "logID", logID)
+	if !authorizeNamespace(c, logObj.GetIncident().GetNamespace()) {
+		return
+	}
 
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "internal server error",
-		})
+	logs, err := redisClient.GetLogs(c.Copy(), logID)
+	if err != nil {
+		handleError(c, err, "error getting logs", "logID", logID)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"contents": logs,
+	c.JSON(http.StatusOK, &models.LogsResponse{
+		Contents: logs,
 	})
 }
@@ -1,27 +1,35 @@
 package handlers
 
 import (
+	"fmt"
+
 	"github.com/porter-dev/porter-agent/pkg/redis"
 	"github.com/spf13/viper"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
 var (
-	redisHost    string
-	redisPort    string
 	maxTailLines int64
 
 	redisClient *redis.Client
 
+	// changes fans the incident change feed out to the incident streams
+	changes *changeFeed
+
 	httpLogger = ctrl.Log.WithName("HTTP Server")
 )
 
 func init() {
-	viper.SetDefault("REDIS_HOST", "porter-redis-master")
-	viper.SetDefault("REDIS_PORT", "6379")
+	viper.SetDefault("MAX_INCIDENT_STREAMS", 100)
+	viper.AutomaticEnv()
+
 	maxTailLines = viper.GetInt64("MAX_TAIL_LINES")
+	changes = newChangeFeed(viper.GetInt("MAX_INCIDENT_STREAMS"))
+
+	var err error
 
-	redisHost = viper.GetString("REDIS_HOST")
-	redisPort = viper.GetString("REDIS_PORT")
-	redisClient = redis.NewClient(redisHost, redisPort, "", "", redis.PODSTORE, maxTailLines)
+	redisClient, err = redis.NewClientFromEnv(maxTailLines)
+	if err != nil {
+		panic(fmt.Errorf("error creating redis client. Error: %w", err))
+	}
 }
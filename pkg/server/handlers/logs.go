@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/server/middleware"
+	"github.com/porter-dev/porter-agent/pkg/utils"
+)
+
+const (
+	defaultLogSearchLimit = 20
+	maxLogSearchLimit     = 100
+
+	// maximum number of matching lines returned per log
+	maxLogLineMatches = 20
+)
+
+// SearchLogs searches the captured logs. By default q matches whole words,
+// ignoring case, and is looked up in the log index. With regex=true, q is a
+// regular expression which is matched against the most recent logs. Results
+// can be limited to a namespace and to the logs captured since a unix
+// timestamp or a duration ago, such as 24h. Only the logs of the namespaces
+// the caller has access to are read, and the response is marked as truncated
+// if older logs were left out.
+func SearchLogs(c *gin.Context) {
+	q := c.Query("q")
+	if q == "" {
+		middleware.AbortWithError(c, middleware.BadRequest("q is required"))
+		return
+	}
+
+	namespace := c.Query("namespace")
+
+	if namespace != "" && !authorizeNamespace(c, namespace) {
+		return
+	}
+
+	var since int64
+
+	if sinceStr := c.Query("since"); sinceStr != "" {
+		if ts, err := strconv.ParseInt(sinceStr, 10, 64); err == nil {
+			since = ts
+		} else if d, err := time.ParseDuration(sinceStr); err == nil {
+			since = time.Now().Add(-d).Unix()
+		} else {
+			middleware.AbortWithError(c, middleware.BadRequest("since must be a unix timestamp or a duration"))
+			return
+		}
+	}
+
+	limit := defaultLogSearchLimit
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 {
+			middleware.AbortWithError(c, middleware.BadRequest("limit must be a positive integer"))
+			return
+		}
+
+		if l < maxLogSearchLimit {
+			limit = l
+		} else {
+			limit = maxLogSearchLimit
+		}
+	}
+
+	var re *regexp.Regexp
+	var tokens []string
+
+	if c.Query("regex") == "true" {
+		var err error
+
+		re, err = regexp.Compile(q)
+		if err != nil {
+			middleware.AbortWithError(c, middleware.BadRequest("invalid regular expression: "+err.Error()))
+			return
+		}
+	} else {
+		re = wordQueryRegexp(q)
+		tokens = utils.TokenizeLogs(q)
+	}
+
+	// only search the logs of the namespaces the caller has access to
+	var namespaces []string
+
+	if namespace != "" {
+		namespaces = []string{namespace}
+	} else {
+		var ok bool
+
+		namespaces, ok = allowedLogNamespaces(c)
+		if !ok {
+			return
+		}
+	}
+
+	logIDs, truncated, err := redisClient.SearchLogs(c.Copy(), tokens, namespaces, since)
+	if err != nil {
+		handleError(c, err, "error searching logs")
+		return
+	}
+
+	results := []*models.LogSearchResult{}
+
+	for _, logID := range logIDs {
+		if len(results) >= limit {
+			break
+		}
+
+		logObj, err := utils.NewLogFromString(logID)
+		if err != nil {
+			continue
+		}
+
+		incidentObj := logObj.GetIncident()
+
+		logs, err := redisClient.GetLogs(c.Copy(), logID)
+		if err != nil {
+			// the logs have expired since they were indexed
+			continue
+		}
+
+		matches := utils.MatchLogLines(logs, re, maxLogLineMatches)
+		if len(matches) == 0 {
+			continue
+		}
+
+		eventID, containerName, err := redisClient.GetLogEvent(c.Copy(), logID)
+		if err != nil {
+			handleError(c, err, "error getting event for logs", "logID", logID)
+			return
+		}
+
+		results = append(results, &models.LogSearchResult{
+			LogID:       logID,
+			IncidentID:  incidentObj.ToString(),
+			EventID:     eventID,
+			Container:   containerName,
+			ReleaseName: incidentObj.GetReleaseName(),
+			Namespace:   incidentObj.GetNamespace(),
+			Timestamp:   logObj.GetTimestamp(),
+			Matches:     matches,
+		})
+	}
+
+	c.JSON(http.StatusOK, &models.SearchLogsResponse{
+		Results:   results,
+		Truncated: truncated && len(results) < limit,
+	})
+}
+
+// allowedLogNamespaces returns the namespaces with captured logs which the
+// caller has access to, or nil if it has access to all of them. It returns
+// false if the request was aborted.
+func allowedLogNamespaces(c *gin.Context) ([]string, bool) {
+	logNamespaces, err := redisClient.GetLogNamespaces(c.Copy())
+	if err != nil {
+		handleError(c, err, "error getting namespaces of logs")
+		return nil, false
+	}
+
+	namespaces := []string{}
+
+	for _, logNamespace := range logNamespaces {
+		allowed, err := middleware.CanAccessNamespace(c, logNamespace)
+		if err != nil {
+			handleError(c, err, "error authorizing request", "namespace", logNamespace)
+			return nil, false
+		}
+
+		if allowed {
+			namespaces = append(namespaces, logNamespace)
+		}
+	}
+
+	if len(namespaces) == len(logNamespaces) {
+		return nil, true
+	}
+
+	return namespaces, true
+}
+
+// wordQueryRegexp matches q as whole words, ignoring case
+func wordQueryRegexp(q string) *regexp.Regexp {
+	expr := "(?i)" + regexp.QuoteMeta(q)
+
+	runes := []rune(q)
+
+	if isWordRune(runes[0]) {
+		expr = `(?i)\b` + regexp.QuoteMeta(q)
+	}
+
+	if isWordRune(runes[len(runes)-1]) {
+		expr += `\b`
+	}
+
+	return regexp.MustCompile(expr)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
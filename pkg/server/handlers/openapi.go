@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/porter-dev/porter-agent/pkg/server/openapi"
+)
+
+var (
+	openAPIOnce sync.Once
+	openAPIJSON []byte
+	openAPIErr  error
+)
+
+func GetOpenAPI(c *gin.Context) {
+	openAPIOnce.Do(func() {
+		openAPIJSON, openAPIErr = openapi.JSON()
+	})
+
+	if openAPIErr != nil {
+		handleError(c, openAPIErr, "error converting OpenAPI document to JSON")
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json", openAPIJSON)
+}
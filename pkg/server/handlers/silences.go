@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/porter-dev/porter-agent/api/v1alpha1"
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/server/middleware"
+	"github.com/porter-dev/porter-agent/pkg/silence"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type SilenceHandler struct {
+	store *silence.Store
+}
+
+func NewSilenceHandler(store *silence.Store) *SilenceHandler {
+	return &SilenceHandler{
+		store: store,
+	}
+}
+
+func (h *SilenceHandler) ListSilences(c *gin.Context) {
+	silences, err := h.store.List(c.Copy())
+	if err != nil {
+		handleError(c, err, "error listing silences")
+		return
+	}
+
+	res := make([]*models.SilenceResponse, 0, len(silences))
+
+	for i := range silences {
+		res = append(res, toSilenceResponse(&silences[i]))
+	}
+
+	c.JSON(http.StatusOK, &models.ListSilencesResponse{
+		Silences: res,
+	})
+}
+
+func (h *SilenceHandler) GetSilence(c *gin.Context) {
+	name := c.Param("name")
+
+	s, err := h.store.Get(c.Copy(), name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			middleware.AbortWithError(c, middleware.NotFound("no such silence"))
+			return
+		}
+
+		handleError(c, err, "error getting silence", "name", name)
+		return
+	}
+
+	c.JSON(http.StatusOK, toSilenceResponse(s))
+}
+
+func (h *SilenceHandler) CreateSilence(c *gin.Context) {
+	req := &models.CreateSilenceRequest{}
+
+	if !authorizeSilences(c) {
+		return
+	}
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		middleware.AbortWithError(c, middleware.BadRequest(err.Error()))
+		return
+	}
+
+	req.CreatedBy = getActingUser(c, req.CreatedBy)
+	if req.CreatedBy == "" {
+		middleware.AbortWithError(c, middleware.BadRequest("created_by is required"))
+		return
+	}
+
+	s := &v1alpha1.Silence{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: req.Name,
+		},
+		Spec: v1alpha1.SilenceSpec{
+			Namespaces: req.Namespaces,
+			Releases:   req.Releases,
+			Reasons:    req.Reasons,
+			Selector:   req.Selector,
+			Schedule:   req.Schedule,
+			CreatedBy:  req.CreatedBy,
+			Comment:    req.Comment,
+		},
+	}
+
+	if req.StartsAt != nil {
+		s.Spec.StartsAt = &metav1.Time{Time: *req.StartsAt}
+	}
+
+	if req.EndsAt != nil {
+		s.Spec.EndsAt = &metav1.Time{Time: *req.EndsAt}
+	}
+
+	if req.Duration != "" {
+		duration, err := time.ParseDuration(req.Duration)
+		if err != nil {
+			middleware.AbortWithError(c, middleware.BadRequest("invalid duration"))
+			return
+		}
+
+		s.Spec.Duration = &metav1.Duration{Duration: duration}
+	}
+
+	if err := silence.Validate(s); err != nil {
+		middleware.AbortWithError(c, middleware.BadRequest(err.Error()))
+		return
+	}
+
+	if err := h.store.Create(c.Copy(), s); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			middleware.AbortWithError(c, middleware.Conflict("silence already exists"))
+			return
+		}
+
+		handleError(c, err, "error creating silence")
+		return
+	}
+
+	c.JSON(http.StatusCreated, toSilenceResponse(s))
+}
+
+func (h *SilenceHandler) DeleteSilence(c *gin.Context) {
+	name := c.Param("name")
+
+	if !authorizeSilences(c) {
+		return
+	}
+
+	if err := h.store.Delete(c.Copy(), name); err != nil {
+		if apierrors.IsNotFound(err) {
+			middleware.AbortWithError(c, middleware.NotFound("no such silence"))
+			return
+		}
+
+		handleError(c, err, "error deleting silence", "name", name)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func authorizeSilences(c *gin.Context) bool {
+	allowed, err := middleware.CanManageSilences(c)
+	if err != nil {
+		handleError(c, err, "error authorizing request")
+		return false
+	}
+
+	if !allowed {
+		middleware.AbortWithError(c, middleware.Forbidden())
+		return false
+	}
+
+	return true
+}
+
+func toSilenceResponse(s *v1alpha1.Silence) *models.SilenceResponse {
+	active, _ := silence.IsActive(s, time.Now())
+
+	return &models.SilenceResponse{
+		Name:      s.Name,
+		CreatedAt: s.CreationTimestamp.Unix(),
+		Active:    active,
+		Spec:      s.Spec,
+	}
+}
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/server/middleware"
+)
+
+const (
+	// how long to wait for a change before sending a heartbeat, which keeps
+	// proxies from closing idle streams
+	streamHeartbeatInterval = 15 * time.Second
+
+	// how often the access of the caller of a stream to namespaces is checked
+	// again, so that revoked access ends the stream
+	streamAuthInterval = time.Minute
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// incidentStream writes changes to a client over SSE or WebSocket
+type incidentStream interface {
+	WriteChange(change *models.IncidentChange) error
+	WriteHeartbeat() error
+}
+
+// StreamIncidents streams the changes of incidents as server-sent events, or
+// as JSON messages if the request is a WebSocket upgrade. The stream can be
+// filtered with the namespace and release query parameters, and resumes after
+// the change given in the Last-Event-ID header or the last_event_id query
+// parameter. The access of the caller to namespaces is checked again every
+// streamAuthInterval.
+func StreamIncidents(c *gin.Context) {
+	namespace := c.Query("namespace")
+	releaseName := c.Query("release")
+
+	if namespace != "" && !authorizeNamespace(c, namespace) {
+		return
+	}
+
+	// subscribe before reading the changes the client missed, so that none
+	// are lost in between
+	sub, err := changes.subscribe()
+	if err != nil {
+		middleware.AbortWithError(c, middleware.Unavailable().WithDetail("reason", err.Error()))
+		return
+	}
+	defer changes.unsubscribe(sub)
+
+	lastID := c.GetHeader("Last-Event-ID")
+	if lastID == "" {
+		lastID = c.Query("last_event_id")
+	}
+
+	if lastID == "" {
+		var err error
+
+		lastID, err = redisClient.GetLatestIncidentChangeID(c.Copy())
+		if err != nil {
+			handleError(c, err, "error getting latest incident change")
+			return
+		}
+	}
+
+	var stream incidentStream
+
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			// the upgrader has already written an error response
+			middleware.RequestLogger(c, httpLogger).Error(err, "error upgrading incident stream to websocket")
+			return
+		}
+		defer conn.Close()
+
+		wsStream := newWebSocketStream(conn)
+		stream = wsStream
+
+		// stop streaming once the client closes the connection
+		go wsStream.readUntilClosed()
+	} else {
+		flusher, ok := c.Writer.(http.Flusher)
+		if !ok {
+			handleError(c, fmt.Errorf("response writer does not support flushing"), "error streaming incidents")
+			return
+		}
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		flusher.Flush()
+
+		stream = &sseStream{writer: c.Writer, flusher: flusher}
+	}
+
+	ctx := c.Request.Context()
+	logger := middleware.RequestLogger(c, httpLogger)
+
+	// namespaces the caller can read, checked once per namespace until the
+	// next authorization check
+	allowedNamespaces := make(map[string]bool)
+
+	// writeChange writes a change if it matches the filters and the caller
+	// can read it, and returns false if the stream should end
+	writeChange := func(change *models.IncidentChange) bool {
+		lastID = change.ID
+
+		if namespace != "" && change.Namespace != namespace {
+			return true
+		}
+
+		if releaseName != "" && change.ReleaseName != releaseName {
+			return true
+		}
+
+		allowed, ok := allowedNamespaces[change.Namespace]
+		if !ok {
+			var err error
+
+			allowed, err = middleware.CanAccessNamespace(c, change.Namespace)
+			if err != nil {
+				logger.Error(err, "error authorizing request", "namespace", change.Namespace)
+				return false
+			}
+
+			allowedNamespaces[change.Namespace] = allowed
+		}
+
+		if !allowed {
+			return true
+		}
+
+		return stream.WriteChange(change) == nil
+	}
+
+	// catch up with the changes made after the last change of the client
+	for {
+		missed, err := redisClient.ReadIncidentChanges(ctx, lastID, -1)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Error(err, "error reading incident changes")
+			}
+			return
+		}
+
+		if len(missed) == 0 {
+			break
+		}
+
+		for _, change := range missed {
+			if !writeChange(change) {
+				return
+			}
+		}
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	authCheck := time.NewTicker(streamAuthInterval)
+	defer authCheck.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if err := stream.WriteHeartbeat(); err != nil {
+				return
+			}
+		case <-authCheck.C:
+			allowedNamespaces = make(map[string]bool)
+
+			if namespace == "" {
+				continue
+			}
+
+			// end the stream once the caller can no longer read its namespace
+			if allowed, err := middleware.CanAccessNamespace(c, namespace); err != nil || !allowed {
+				return
+			}
+		case change, ok := <-sub.changes:
+			// the stream fell behind, its client resumes from its last change
+			if !ok {
+				return
+			}
+
+			// skip the changes which were already caught up with
+			if compareChangeIDs(change.ID, lastID) <= 0 {
+				continue
+			}
+
+			if !writeChange(change) {
+				return
+			}
+		}
+	}
+}
+
+type sseStream struct {
+	writer  gin.ResponseWriter
+	flusher http.Flusher
+}
+
+func (s *sseStream) WriteChange(change *models.IncidentChange) error {
+	changeJSON, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(s.writer, "id: %s\nevent: %s\ndata: %s\n\n", change.ID, change.Type, changeJSON); err != nil {
+		return err
+	}
+
+	s.flusher.Flush()
+
+	return nil
+}
+
+func (s *sseStream) WriteHeartbeat() error {
+	if _, err := fmt.Fprint(s.writer, ": heartbeat\n\n"); err != nil {
+		return err
+	}
+
+	s.flusher.Flush()
+
+	return nil
+}
+
+type webSocketStream struct {
+	conn   *websocket.Conn
+	closed chan struct{}
+}
+
+func newWebSocketStream(conn *websocket.Conn) *webSocketStream {
+	return &webSocketStream{
+		conn:   conn,
+		closed: make(chan struct{}),
+	}
+}
+
+// readUntilClosed discards the messages sent by the client, which also
+// handles the control messages, until the connection is closed
+func (s *webSocketStream) readUntilClosed() {
+	defer close(s.closed)
+
+	for {
+		if _, _, err := s.conn.NextReader(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *webSocketStream) WriteChange(change *models.IncidentChange) error {
+	select {
+	case <-s.closed:
+		return websocket.ErrCloseSent
+	default:
+	}
+
+	s.conn.SetWriteDeadline(time.Now().Add(streamHeartbeatInterval))
+
+	return s.conn.WriteJSON(change)
+}
+
+func (s *webSocketStream) WriteHeartbeat() error {
+	select {
+	case <-s.closed:
+		return websocket.ErrCloseSent
+	default:
+	}
+
+	return s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamHeartbeatInterval))
+}
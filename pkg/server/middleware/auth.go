@@ -0,0 +1,459 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	AuthModeToken       = "token"
+	AuthModeTokenReview = "tokenreview"
+	AuthModeMTLS        = "mtls"
+
+	identityKey = "porter-agent.identity"
+	authKey     = "porter-agent.auth"
+
+	// decisions of the API server are cached for a short time so that
+	// listing incidents does not cost a review per incident
+	reviewCacheTTL = time.Minute
+)
+
+var (
+	authModes      []string
+	apiTokens      []string
+	anonymousReads bool
+
+	authLogger = ctrl.Log.WithName("HTTP Auth")
+)
+
+func init() {
+	viper.SetDefault("AUTH_MODES", AuthModeToken)
+	viper.SetDefault("AUTH_ANONYMOUS_READS", false)
+	viper.AutomaticEnv()
+
+	authModes = splitList(viper.GetString("AUTH_MODES"))
+	apiTokens = splitList(viper.GetString("API_TOKENS"))
+	anonymousReads = viper.GetBool("AUTH_ANONYMOUS_READS")
+}
+
+// Identity is an authenticated caller of the API
+type Identity struct {
+	Username string
+	UID      string
+	Groups   []string
+	Extra    map[string]authorizationv1.ExtraValue
+
+	// Admin identities, such as static API tokens, are not subject to
+	// Kubernetes authorization
+	Admin bool
+}
+
+// Authenticator returns the identity of the caller of a request, or nil if
+// the request does not carry credentials it understands
+type Authenticator interface {
+	Authenticate(req *http.Request) (*Identity, error)
+}
+
+// Authorizer decides which namespaces an identity can read incidents from,
+// and whether it can manage silences
+type Authorizer interface {
+	CanAccessNamespace(ctx context.Context, identity *Identity, namespace string) (bool, error)
+	CanManageSilences(ctx context.Context, identity *Identity) (bool, error)
+}
+
+// Auth holds the configured authenticators and authorizer of the API
+type Auth struct {
+	authenticators []Authenticator
+	authorizer     Authorizer
+	anonymousReads bool
+
+	// full paths of the routes which can be read anonymously, when anonymous
+	// reads are enabled
+	anonymousPaths map[string]bool
+}
+
+//+kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+//+kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// NewAuth builds the authenticators listed in AUTH_MODES. Static tokens are
+// read from API_TOKENS as a comma-separated list of "<token>" or
+// "<username>:<token>" items.
+func NewAuth(kubeClient kubernetes.Interface) (*Auth, error) {
+	auth := &Auth{
+		authorizer:     NewSubjectAccessReviewAuthorizer(kubeClient),
+		anonymousReads: anonymousReads,
+		anonymousPaths: make(map[string]bool),
+	}
+
+	for _, mode := range authModes {
+		switch mode {
+		case AuthModeToken:
+			auth.authenticators = append(auth.authenticators, NewStaticTokenAuthenticator(apiTokens))
+		case AuthModeTokenReview:
+			auth.authenticators = append(auth.authenticators, NewTokenReviewAuthenticator(kubeClient))
+		case AuthModeMTLS:
+			auth.authenticators = append(auth.authenticators, &ClientCertAuthenticator{})
+		default:
+			return nil, fmt.Errorf("unknown auth mode: %s", mode)
+		}
+	}
+
+	return auth, nil
+}
+
+// AllowAnonymousReads marks routes, by their full path, as readable without
+// credentials when AUTH_ANONYMOUS_READS is set. Routes which return logs,
+// snapshots or the change feed should never be marked.
+func (a *Auth) AllowAnonymousReads(paths ...string) {
+	for _, path := range paths {
+		a.anonymousPaths[path] = true
+	}
+}
+
+// Authenticate identifies the caller of every request. Unauthenticated
+// requests are rejected, except for reads of the routes allowed by
+// AllowAnonymousReads when anonymous reads are enabled.
+func (a *Auth) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(authKey, a)
+
+		for _, authenticator := range a.authenticators {
+			identity, err := authenticator.Authenticate(c.Request)
+			if err != nil {
+				RequestLogger(c, authLogger).Error(err, "error authenticating request")
+				AbortWithError(c, InternalError())
+				return
+			}
+
+			if identity != nil {
+				c.Set(identityKey, identity)
+				c.Next()
+				return
+			}
+		}
+
+		if a.canReadAnonymously(c) {
+			c.Next()
+			return
+		}
+
+		AbortWithError(c, Unauthorized())
+	}
+}
+
+func (a *Auth) canReadAnonymously(c *gin.Context) bool {
+	if !a.anonymousReads || (c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead) {
+		return false
+	}
+
+	return a.anonymousPaths[c.FullPath()]
+}
+
+// GetIdentity returns the identity of the caller, or nil for anonymous requests
+func GetIdentity(c *gin.Context) *Identity {
+	if identity, ok := c.Get(identityKey); ok {
+		return identity.(*Identity)
+	}
+
+	return nil
+}
+
+// CanAccessNamespace returns true if the caller can read the incidents and
+// logs of the namespace, that is if it can get pods/log in the namespace.
+func CanAccessNamespace(c *gin.Context, namespace string) (bool, error) {
+	auth, identity, anonymous := getAuth(c)
+	if anonymous {
+		return auth != nil && auth.canReadAnonymously(c), nil
+	}
+
+	if identity.Admin {
+		return true, nil
+	}
+
+	return auth.authorizer.CanAccessNamespace(c.Request.Context(), identity, namespace)
+}
+
+// CanManageSilences returns true if the caller can create and delete silences
+func CanManageSilences(c *gin.Context) (bool, error) {
+	auth, identity, anonymous := getAuth(c)
+	if anonymous {
+		return false, nil
+	}
+
+	if identity.Admin {
+		return true, nil
+	}
+
+	return auth.authorizer.CanManageSilences(c.Request.Context(), identity)
+}
+
+func getAuth(c *gin.Context) (*Auth, *Identity, bool) {
+	identity := GetIdentity(c)
+
+	value, ok := c.Get(authKey)
+	if !ok {
+		return nil, identity, identity == nil
+	}
+
+	return value.(*Auth), identity, identity == nil
+}
+
+// StaticTokenAuthenticator accepts a fixed list of bearer tokens, each mapped
+// to an admin identity
+type StaticTokenAuthenticator struct {
+	tokens map[string]string
+}
+
+func NewStaticTokenAuthenticator(tokens []string) *StaticTokenAuthenticator {
+	a := &StaticTokenAuthenticator{
+		tokens: make(map[string]string),
+	}
+
+	for _, token := range tokens {
+		username := "api-token"
+
+		if segments := strings.SplitN(token, ":", 2); len(segments) == 2 {
+			username = segments[0]
+			token = segments[1]
+		}
+
+		a.tokens[token] = username
+	}
+
+	return a
+}
+
+func (a *StaticTokenAuthenticator) Authenticate(req *http.Request) (*Identity, error) {
+	token := getBearerToken(req)
+	if token == "" {
+		return nil, nil
+	}
+
+	for apiToken, username := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(apiToken)) == 1 {
+			return &Identity{
+				Username: username,
+				Admin:    true,
+			}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// TokenReviewAuthenticator validates bearer tokens, such as ServiceAccount
+// tokens, with a TokenReview against the Kubernetes API server
+type TokenReviewAuthenticator struct {
+	kubeClient kubernetes.Interface
+	cache      *reviewCache
+}
+
+func NewTokenReviewAuthenticator(kubeClient kubernetes.Interface) *TokenReviewAuthenticator {
+	return &TokenReviewAuthenticator{
+		kubeClient: kubeClient,
+		cache:      newReviewCache(),
+	}
+}
+
+func (a *TokenReviewAuthenticator) Authenticate(req *http.Request) (*Identity, error) {
+	token := getBearerToken(req)
+	if token == "" {
+		return nil, nil
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	cacheKey := hex.EncodeToString(sum[:])
+
+	if value, ok := a.cache.get(cacheKey); ok {
+		identity, _ := value.(*Identity)
+		return identity, nil
+	}
+
+	review, err := a.kubeClient.AuthenticationV1().TokenReviews().Create(req.Context(), &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{
+			Token: token,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error creating token review. Error: %w", err)
+	}
+
+	var identity *Identity
+
+	if review.Status.Authenticated {
+		identity = &Identity{
+			Username: review.Status.User.Username,
+			UID:      review.Status.User.UID,
+			Groups:   review.Status.User.Groups,
+			Extra:    make(map[string]authorizationv1.ExtraValue),
+		}
+
+		for key, value := range review.Status.User.Extra {
+			identity.Extra[key] = authorizationv1.ExtraValue(value)
+		}
+	}
+
+	a.cache.set(cacheKey, identity)
+
+	return identity, nil
+}
+
+// ClientCertAuthenticator identifies callers by their verified TLS client
+// certificate, using the common name as the username and the organizations
+// as the groups, like the Kubernetes API server does
+type ClientCertAuthenticator struct{}
+
+func (a *ClientCertAuthenticator) Authenticate(req *http.Request) (*Identity, error) {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+		return nil, nil
+	}
+
+	cert := req.TLS.VerifiedChains[0][0]
+
+	return &Identity{
+		Username: cert.Subject.CommonName,
+		Groups:   cert.Subject.Organization,
+	}, nil
+}
+
+// SubjectAccessReviewAuthorizer delegates authorization decisions to the
+// Kubernetes API server
+type SubjectAccessReviewAuthorizer struct {
+	kubeClient kubernetes.Interface
+	cache      *reviewCache
+}
+
+func NewSubjectAccessReviewAuthorizer(kubeClient kubernetes.Interface) *SubjectAccessReviewAuthorizer {
+	return &SubjectAccessReviewAuthorizer{
+		kubeClient: kubeClient,
+		cache:      newReviewCache(),
+	}
+}
+
+func (a *SubjectAccessReviewAuthorizer) CanAccessNamespace(ctx context.Context, identity *Identity, namespace string) (bool, error) {
+	return a.review(ctx, identity, &authorizationv1.ResourceAttributes{
+		Namespace:   namespace,
+		Verb:        "get",
+		Resource:    "pods",
+		Subresource: "log",
+	})
+}
+
+func (a *SubjectAccessReviewAuthorizer) CanManageSilences(ctx context.Context, identity *Identity) (bool, error) {
+	return a.review(ctx, identity, &authorizationv1.ResourceAttributes{
+		Verb:     "create",
+		Group:    "agent.porter.run",
+		Resource: "silences",
+	})
+}
+
+func (a *SubjectAccessReviewAuthorizer) review(
+	ctx context.Context, identity *Identity, attributes *authorizationv1.ResourceAttributes,
+) (bool, error) {
+	cacheKey := fmt.Sprintf("%s/%s/%s/%s/%s/%s/%s", identity.Username, identity.UID, attributes.Verb,
+		attributes.Group, attributes.Resource, attributes.Subresource, attributes.Namespace)
+
+	if value, ok := a.cache.get(cacheKey); ok {
+		return value.(bool), nil
+	}
+
+	review, err := a.kubeClient.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: attributes,
+			User:               identity.Username,
+			UID:                identity.UID,
+			Groups:             identity.Groups,
+			Extra:              identity.Extra,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return false, fmt.Errorf("error creating subject access review. Error: %w", err)
+	}
+
+	a.cache.set(cacheKey, review.Status.Allowed)
+
+	return review.Status.Allowed, nil
+}
+
+type reviewCacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+type reviewCache struct {
+	entries map[string]*reviewCacheEntry
+	mu      sync.Mutex
+}
+
+func newReviewCache() *reviewCache {
+	return &reviewCache{
+		entries: make(map[string]*reviewCacheEntry),
+	}
+}
+
+func (c *reviewCache) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+
+	return entry.value, true
+}
+
+func (c *reviewCache) set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+
+	for k, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+
+	c.entries[key] = &reviewCacheEntry{
+		value:   value,
+		expires: now.Add(reviewCacheTTL),
+	}
+}
+
+func getBearerToken(req *http.Request) string {
+	header := req.Header.Get("Authorization")
+
+	if !strings.HasPrefix(header, "Bearer ") {
+		return ""
+	}
+
+	return strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+}
+
+func splitList(value string) []string {
+	var res []string
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			res = append(res, item)
+		}
+	}
+
+	return res
+}
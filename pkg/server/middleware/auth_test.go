@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newTestKubeClient returns a client whose API server authenticates the
+// "alice" and "bob" tokens, and only lets alice read the prod namespace
+func newTestKubeClient() *fake.Clientset {
+	kubeClient := fake.NewSimpleClientset()
+
+	kubeClient.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+
+		switch review.Spec.Token {
+		case "alice", "bob":
+			review.Status.Authenticated = true
+			review.Status.User.Username = review.Spec.Token
+		}
+
+		return true, review, nil
+	})
+
+	kubeClient.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+
+		review.Status.Allowed = review.Spec.User == "alice" && review.Spec.ResourceAttributes.Namespace == "prod"
+
+		return true, review, nil
+	})
+
+	return kubeClient
+}
+
+func newTestRouter(auth *Auth) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(auth.Authenticate())
+
+	// reads the incidents of the prod namespace, like the handlers do
+	handler := func(c *gin.Context) {
+		allowed, err := CanAccessNamespace(c, "prod")
+		if err != nil {
+			AbortWithError(c, InternalError())
+			return
+		} else if !allowed {
+			AbortWithError(c, Forbidden())
+			return
+		}
+
+		c.Status(http.StatusOK)
+	}
+
+	for _, group := range []*gin.RouterGroup{&router.RouterGroup, router.Group("/v2", ErrorEnvelope())} {
+		group.GET("/incidents", handler)
+		group.GET("/incidents/logs/:logID", handler)
+
+		auth.AllowAnonymousReads(path.Join(group.BasePath(), "/incidents"))
+	}
+
+	return router
+}
+
+func TestAuth(t *testing.T) {
+	tests := []struct {
+		name           string
+		modes          []string
+		anonymousReads bool
+		path           string
+		token          string
+		want           int
+	}{
+		{
+			name:  "static token",
+			modes: []string{AuthModeToken},
+			path:  "/incidents/logs/log",
+			token: "secret",
+			want:  http.StatusOK,
+		},
+		{
+			name:  "rejected static token",
+			modes: []string{AuthModeToken},
+			path:  "/incidents/logs/log",
+			token: "wrong",
+			want:  http.StatusUnauthorized,
+		},
+		{
+			name:  "missing token",
+			modes: []string{AuthModeToken, AuthModeTokenReview},
+			path:  "/incidents",
+			want:  http.StatusUnauthorized,
+		},
+		{
+			name:  "token review allowed by access review",
+			modes: []string{AuthModeToken, AuthModeTokenReview},
+			path:  "/incidents/logs/log",
+			token: "alice",
+			want:  http.StatusOK,
+		},
+		{
+			name:  "token review denied by access review",
+			modes: []string{AuthModeToken, AuthModeTokenReview},
+			path:  "/incidents/logs/log",
+			token: "bob",
+			want:  http.StatusForbidden,
+		},
+		{
+			name:  "rejected token review",
+			modes: []string{AuthModeTokenReview},
+			path:  "/incidents",
+			token: "mallory",
+			want:  http.StatusUnauthorized,
+		},
+		{
+			name:           "anonymous list",
+			modes:          []string{AuthModeToken},
+			anonymousReads: true,
+			path:           "/incidents",
+			want:           http.StatusOK,
+		},
+		{
+			name:           "anonymous list without anonymous reads",
+			modes:          []string{AuthModeToken},
+			anonymousReads: false,
+			path:           "/incidents",
+			want:           http.StatusUnauthorized,
+		},
+		{
+			name:           "anonymous read of a route which is not a list",
+			modes:          []string{AuthModeToken},
+			anonymousReads: true,
+			path:           "/incidents/logs/log",
+			want:           http.StatusUnauthorized,
+		},
+	}
+
+	defer func(modes, tokens []string, anonymous bool) {
+		authModes, apiTokens, anonymousReads = modes, tokens, anonymous
+	}(authModes, apiTokens, anonymousReads)
+
+	for _, test := range tests {
+		for _, prefix := range []string{"", "/v2"} {
+			authModes = test.modes
+			apiTokens = []string{"secret"}
+			anonymousReads = test.anonymousReads
+
+			auth, err := NewAuth(newTestKubeClient())
+			if err != nil {
+				t.Fatalf("%s: unexpected error creating auth: %v", test.name, err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, prefix+test.path, nil)
+			if test.token != "" {
+				req.Header.Set("Authorization", "Bearer "+test.token)
+			}
+
+			rec := httptest.NewRecorder()
+			newTestRouter(auth).ServeHTTP(rec, req)
+
+			if rec.Code != test.want {
+				t.Errorf("%s: expected status %d for %s, got %d", test.name, test.want, prefix+test.path, rec.Code)
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/porter-dev/porter-agent/pkg/models"
+)
+
+const envelopeKey = "errorEnvelope"
+
+// Error is an error response of the API. It is written as the error
+// envelope of the /v2 API, or as {"error": <message>} for the original API.
+type Error struct {
+	Status  int
+	Code    models.ErrorCode
+	Message string
+	Details map[string]interface{}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// WithDetail adds a detail to the error, only returned by the /v2 API
+func (e *Error) WithDetail(key string, value interface{}) *Error {
+	if e.Details == nil {
+		e.Details = make(map[string]interface{})
+	}
+
+	e.Details[key] = value
+
+	return e
+}
+
+func BadRequest(message string) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: models.ErrorCodeBadRequest, Message: message}
+}
+
+func Unauthorized() *Error {
+	return &Error{Status: http.StatusUnauthorized, Code: models.ErrorCodeUnauthorized, Message: "unauthorized"}
+}
+
+func Forbidden() *Error {
+	return &Error{Status: http.StatusForbidden, Code: models.ErrorCodeForbidden, Message: "forbidden"}
+}
+
+func NotFound(message string) *Error {
+	return &Error{Status: http.StatusNotFound, Code: models.ErrorCodeNotFound, Message: message}
+}
+
+func Conflict(message string) *Error {
+	return &Error{Status: http.StatusConflict, Code: models.ErrorCodeConflict, Message: message}
+}
+
+func InternalError() *Error {
+	return &Error{Status: http.StatusInternalServerError, Code: models.ErrorCodeInternal, Message: "internal server error"}
+}
+
+func Unavailable() *Error {
+	return &Error{Status: http.StatusServiceUnavailable, Code: models.ErrorCodeUnavailable, Message: "service unavailable"}
+}
+
+// ErrorEnvelope makes the routes it is used on return errors in the error
+// envelope of the /v2 API
+func ErrorEnvelope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(envelopeKey, true)
+		c.Next()
+	}
+}
+
+// AbortWithError writes the error response and stops the handler chain
+func AbortWithError(c *gin.Context, err *Error) {
+	if !c.GetBool(envelopeKey) {
+		c.AbortWithStatusJSON(err.Status, &models.ErrorResponse{
+			Error: err.Message,
+		})
+		return
+	}
+
+	c.AbortWithStatusJSON(err.Status, &models.ErrorEnvelope{
+		Error: &models.APIError{
+			Code:      err.Code,
+			Message:   err.Message,
+			Details:   err.Details,
+			RequestID: GetRequestID(c),
+		},
+	})
+}
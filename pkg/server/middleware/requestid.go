@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-logr/logr"
+	"github.com/google/uuid"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+
+	RequestIDKey = "requestID"
+
+	// longer request IDs given by callers are replaced
+	maxRequestIDLength = 128
+)
+
+// RequestID assigns every request an ID, which is taken from the X-Request-ID
+// header if the caller set one, and returns it in the same header
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+
+		if id == "" || len(id) > maxRequestIDLength {
+			id = uuid.New().String()
+		}
+
+		c.Set(RequestIDKey, id)
+		c.Header(RequestIDHeader, id)
+
+		c.Next()
+	}
+}
+
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(RequestIDKey)
+}
+
+// RequestLogger returns the logger with the ID of the request
+func RequestLogger(c *gin.Context, logger logr.Logger) logr.Logger {
+	return logger.WithValues("requestID", GetRequestID(c))
+}
@@ -0,0 +1,16 @@
+// Package openapi holds the OpenAPI document of the agent API.
+package openapi
+
+import (
+	_ "embed"
+
+	"sigs.k8s.io/yaml"
+)
+
+//go:embed openapi.yaml
+var spec []byte
+
+// JSON returns the OpenAPI document as JSON
+func JSON() ([]byte, error) {
+	return yaml.YAMLToJSON(spec)
+}
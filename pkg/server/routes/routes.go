@@ -1,17 +1,94 @@
 package routes
 
 import (
+	"fmt"
+	"path"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"github.com/porter-dev/porter-agent/pkg/archive"
 	"github.com/porter-dev/porter-agent/pkg/server/handlers"
+	"github.com/porter-dev/porter-agent/pkg/server/middleware"
+	"github.com/porter-dev/porter-agent/pkg/silence"
 )
 
-func NewRouter() *gin.Engine {
-	router := gin.Default()
+func NewRouter(silenceStore *silence.Store, archiveStore archive.Store, auth *middleware.Auth) *gin.Engine {
+	router := gin.New()
+
+	router.Use(middleware.RequestID())
+	router.Use(gin.LoggerWithFormatter(logFormatter))
+	router.Use(gin.Recovery())
+
+	// the API document is public, routes registered before the auth
+	// middleware is added are not authenticated
+	router.GET("/openapi.json", handlers.GetOpenAPI)
+
+	// every request is authenticated, handlers then authorize access to
+	// the namespace of the incidents they return
+	router.Use(auth.Authenticate())
 
-	router.GET("/incidents", handlers.GetAllIncidents)
-	router.GET("/incidents/:incidentID", handlers.GetIncidentEventsByID)
-	router.GET("/incidents/namespaces/:namespace/releases/:releaseName", handlers.GetIncidentsByReleaseNamespace)
-	router.GET("/incidents/logs/:logID", handlers.GetLogs)
+	silenceHandler := handlers.NewSilenceHandler(silenceStore)
+	archiveHandler := handlers.NewArchiveHandler(archiveStore)
+
+	// the unversioned routes keep returning errors as {"error": message} for
+	// existing clients, the /v2 routes return error envelopes
+	registerRoutes(&router.RouterGroup, auth, silenceHandler, archiveHandler)
+	registerRoutes(router.Group("/v2", middleware.ErrorEnvelope()), auth, silenceHandler, archiveHandler)
 
 	return router
 }
+
+func registerRoutes(
+	group *gin.RouterGroup,
+	auth *middleware.Auth,
+	silenceHandler *handlers.SilenceHandler,
+	archiveHandler *handlers.ArchiveHandler,
+) {
+	group.GET("/incidents", handlers.GetAllIncidents)
+	group.GET("/incidents/stream", handlers.StreamIncidents)
+	group.GET("/incidents/:incidentID", handlers.GetIncidentEventsByID)
+	group.GET("/incidents/namespaces/:namespace/releases/:releaseName", handlers.GetIncidentsByReleaseNamespace)
+	group.GET("/incidents/logs/:logID", handlers.GetLogs)
+	group.GET("/incidents/:incidentID/comments", handlers.GetIncidentComments)
+	group.GET("/incidents/:incidentID/events/:eventID/snapshot", handlers.GetEventSnapshot)
+	group.GET("/incidents/:incidentID/remediations", handlers.GetIncidentRemediations)
+	group.GET("/logs/search", handlers.SearchLogs)
+
+	// only the incident lists can be read anonymously, when it is enabled
+	auth.AllowAnonymousReads(
+		path.Join(group.BasePath(), "/incidents"),
+		path.Join(group.BasePath(), "/incidents/namespaces/:namespace/releases/:releaseName"),
+	)
+
+	group.POST("/incidents/:incidentID/acknowledge", handlers.AcknowledgeIncident)
+	group.POST("/incidents/:incidentID/resolve", handlers.ResolveIncident)
+	group.POST("/incidents/:incidentID/reopen", handlers.ReopenIncident)
+	group.POST("/incidents/:incidentID/comments", handlers.AddIncidentComment)
+
+	group.GET("/silences", silenceHandler.ListSilences)
+	group.GET("/silences/:name", silenceHandler.GetSilence)
+	group.POST("/silences", silenceHandler.CreateSilence)
+	group.DELETE("/silences/:name", silenceHandler.DeleteSilence)
+
+	group.POST("/archive/restore", archiveHandler.RestoreArchive)
+}
+
+// logFormatter is the default gin log format with the request ID
+func logFormatter(param gin.LogFormatterParams) string {
+	requestID, _ := param.Keys[middleware.RequestIDKey].(string)
+
+	if param.Latency > time.Minute {
+		param.Latency = param.Latency.Truncate(time.Second)
+	}
+
+	return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v | %s\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		param.StatusCode,
+		param.Latency,
+		param.ClientIP,
+		param.Method,
+		param.Path,
+		requestID,
+		param.ErrorMessage,
+	)
+}
@@ -0,0 +1,346 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/server/middleware"
+	"github.com/porter-dev/porter-agent/pkg/server/openapi"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// the types of the request and response bodies of each operation of the
+// OpenAPI document, by operation ID
+var (
+	requestTypes = map[string]interface{}{
+		"addIncidentComment":  models.AddCommentRequest{},
+		"acknowledgeIncident": models.IncidentActionRequest{},
+		"resolveIncident":     models.IncidentActionRequest{},
+		"reopenIncident":      models.IncidentActionRequest{},
+		"createSilence":       models.CreateSilenceRequest{},
+		"restoreArchive":      models.RestoreArchiveRequest{},
+	}
+
+	responseTypes = map[string]interface{}{
+		"listIncidents":            models.ListIncidentsResponse{},
+		"streamIncidents":          models.IncidentChange{},
+		"getIncident":              models.IncidentEventsResponse{},
+		"listReleaseIncidents":     models.ListIncidentsResponse{},
+		"getLogs":                  models.LogsResponse{},
+		"listIncidentComments":     models.ListCommentsResponse{},
+		"addIncidentComment":       models.IncidentComment{},
+		"listIncidentRemediations": models.ListRemediationsResponse{},
+		"getEventSnapshot":         models.PodSnapshot{},
+		"acknowledgeIncident":      models.Incident{},
+		"resolveIncident":          models.Incident{},
+		"reopenIncident":           models.Incident{},
+		"searchLogs":               models.SearchLogsResponse{},
+		"listSilences":             models.ListSilencesResponse{},
+		"createSilence":            models.SilenceResponse{},
+		"getSilence":               models.SilenceResponse{},
+		"restoreArchive":           models.RestoreArchiveResponse{},
+	}
+)
+
+var pathParamRegex = regexp.MustCompile(`:([a-zA-Z]+)`)
+
+type document map[string]interface{}
+
+func loadDocument(t *testing.T) document {
+	t.Helper()
+
+	data, err := openapi.JSON()
+	if err != nil {
+		t.Fatalf("error reading OpenAPI document: %v", err)
+	}
+
+	doc := document{}
+
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("error parsing OpenAPI document: %v", err)
+	}
+
+	return doc
+}
+
+// resolve follows the $ref of an object of the document, if it has one
+func (d document) resolve(obj map[string]interface{}) map[string]interface{} {
+	ref, ok := obj["$ref"].(string)
+	if !ok {
+		return obj
+	}
+
+	resolved := map[string]interface{}(d)
+
+	for _, segment := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		resolved, _ = resolved[segment].(map[string]interface{})
+	}
+
+	return d.resolve(resolved)
+}
+
+// operations returns the operations of the document by method and path
+func (d document) operations() map[string]map[string]interface{} {
+	operations := make(map[string]map[string]interface{})
+
+	for path, item := range d["paths"].(map[string]interface{}) {
+		for method, operation := range item.(map[string]interface{}) {
+			if method == "parameters" {
+				continue
+			}
+
+			operations[strings.ToUpper(method)+" "+path] = operation.(map[string]interface{})
+		}
+	}
+
+	return operations
+}
+
+// schema returns the schema of the first content of a request body or
+// response, or nil if it has none
+func (d document) schema(obj map[string]interface{}) map[string]interface{} {
+	content, _ := d.resolve(obj)["content"].(map[string]interface{})
+
+	for _, media := range content {
+		if schema, ok := media.(map[string]interface{})["schema"].(map[string]interface{}); ok {
+			return d.resolve(schema)
+		}
+	}
+
+	return nil
+}
+
+func newTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+
+	auth, err := middleware.NewAuth(fake.NewSimpleClientset())
+	if err != nil {
+		t.Fatalf("unexpected error creating auth: %v", err)
+	}
+
+	return NewRouter(nil, nil, auth)
+}
+
+func TestRoutesMatchOpenAPI(t *testing.T) {
+	operations := loadDocument(t).operations()
+
+	served := make(map[string]bool)
+
+	for _, route := range newTestRouter(t).Routes() {
+		path := pathParamRegex.ReplaceAllString(route.Path, "{$1}")
+		key := route.Method + " " + strings.TrimPrefix(path, "/v2")
+
+		if _, ok := operations[key]; !ok {
+			t.Errorf("route %s %s is not in the OpenAPI document", route.Method, route.Path)
+		}
+
+		if strings.HasPrefix(path, "/v2/") {
+			served["v2 "+key] = true
+		} else {
+			served[key] = true
+		}
+	}
+
+	for key := range operations {
+		if !served[key] {
+			t.Errorf("operation %s is not served", key)
+		}
+
+		// the API document is only served unversioned
+		if key != "GET /openapi.json" && !served["v2 "+key] {
+			t.Errorf("operation %s is not served under /v2", key)
+		}
+	}
+}
+
+func TestBodiesMatchOpenAPI(t *testing.T) {
+	doc := loadDocument(t)
+	documented := make(map[string]bool)
+
+	for key, operation := range doc.operations() {
+		operationID, _ := operation["operationId"].(string)
+
+		if body, ok := operation["requestBody"].(map[string]interface{}); ok {
+			documented["request "+operationID] = true
+
+			typ, ok := requestTypes[operationID]
+			if !ok {
+				t.Errorf("no request type for operation %s (%s)", operationID, key)
+			} else {
+				checkSchema(t, doc, doc.schema(body), reflect.TypeOf(typ), operationID+" request")
+			}
+		}
+
+		responses, _ := operation["responses"].(map[string]interface{})
+
+		for code, response := range responses {
+			schema := doc.schema(response.(map[string]interface{}))
+			if !strings.HasPrefix(code, "2") || schema == nil || schema["properties"] == nil {
+				continue
+			}
+
+			documented["response "+operationID] = true
+
+			typ, ok := responseTypes[operationID]
+			if !ok {
+				t.Errorf("no response type for operation %s (%s)", operationID, key)
+			} else {
+				checkSchema(t, doc, schema, reflect.TypeOf(typ), operationID+" response")
+			}
+		}
+	}
+
+	for operationID := range requestTypes {
+		if !documented["request "+operationID] {
+			t.Errorf("operation %s has no documented request body", operationID)
+		}
+	}
+
+	for operationID := range responseTypes {
+		if !documented["response "+operationID] {
+			t.Errorf("operation %s has no documented response body", operationID)
+		}
+	}
+}
+
+func TestErrorsMatchOpenAPI(t *testing.T) {
+	doc := loadDocument(t)
+	router := newTestRouter(t)
+
+	tests := []struct {
+		path   string
+		schema string
+		typ    interface{}
+	}{
+		{path: "/incidents/logs/log", schema: "ErrorResponse", typ: models.ErrorResponse{}},
+		{path: "/v2/incidents/logs/log", schema: "ErrorEnvelope", typ: models.ErrorEnvelope{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, test.path, nil))
+
+			if rec.Code != http.StatusUnauthorized {
+				t.Fatalf("expected status code 401, got %d", rec.Code)
+			}
+
+			schema := doc.resolve(map[string]interface{}{"$ref": "#/components/schemas/" + test.schema})
+			checkSchema(t, doc, schema, reflect.TypeOf(test.typ), test.schema)
+
+			body := map[string]interface{}{}
+
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("error parsing response body: %v", err)
+			}
+
+			checkValue(t, doc, schema, body, test.schema)
+		})
+	}
+}
+
+// checkSchema checks that the JSON fields of a struct are the properties of an
+// object schema, and recursively for the fields referring to other schemas
+func checkSchema(t *testing.T, doc document, schema map[string]interface{}, typ reflect.Type, name string) {
+	t.Helper()
+
+	typ = elemType(typ)
+
+	if typ.Kind() != reflect.Struct || typ == reflect.TypeOf(time.Time{}) {
+		return
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	fields := jsonFields(typ)
+
+	for field := range fields {
+		if _, ok := properties[field]; !ok {
+			t.Errorf("field %s of %s is not documented", field, name)
+		}
+	}
+
+	for property, propertySchema := range properties {
+		fieldType, ok := fields[property]
+		if !ok {
+			t.Errorf("property %s of %s is not returned", property, name)
+			continue
+		}
+
+		propertySchema := doc.resolve(propertySchema.(map[string]interface{}))
+
+		if items, ok := propertySchema["items"].(map[string]interface{}); ok {
+			propertySchema = doc.resolve(items)
+		}
+
+		if propertySchema["properties"] != nil {
+			checkSchema(t, doc, propertySchema, fieldType, name+"."+property)
+		}
+	}
+}
+
+// checkValue checks that a decoded JSON object has the required properties of
+// a schema and no other properties
+func checkValue(t *testing.T, doc document, schema map[string]interface{}, value map[string]interface{}, name string) {
+	t.Helper()
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	required, _ := schema["required"].([]interface{})
+
+	for _, property := range required {
+		if _, ok := value[property.(string)]; !ok {
+			t.Errorf("required property %s of %s is missing", property, name)
+		}
+	}
+
+	for property, propertyValue := range value {
+		propertySchema, ok := properties[property].(map[string]interface{})
+		if !ok {
+			t.Errorf("property %s of %s is not documented", property, name)
+			continue
+		}
+
+		if object, ok := propertyValue.(map[string]interface{}); ok {
+			checkValue(t, doc, doc.resolve(propertySchema), object, name+"."+property)
+		}
+	}
+}
+
+// jsonFields returns the types of the fields of a struct by JSON name
+func jsonFields(typ reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" || field.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		fields[name] = field.Type
+	}
+
+	return fields
+}
+
+func elemType(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice {
+		typ = typ.Elem()
+	}
+
+	return typ
+}
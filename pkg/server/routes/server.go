@@ -0,0 +1,96 @@
+package routes
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+var (
+	tlsCertFile  string
+	tlsKeyFile   string
+	clientCAFile string
+
+	// serving is set to 1 while Run accepts connections
+	serving int32
+)
+
+func init() {
+	viper.AutomaticEnv()
+
+	tlsCertFile = viper.GetString("HTTP_TLS_CERT_FILE")
+	tlsKeyFile = viper.GetString("HTTP_TLS_KEY_FILE")
+	clientCAFile = viper.GetString("HTTP_TLS_CLIENT_CA_FILE")
+}
+
+// Run serves the router on the given address. TLS is enabled when a
+// certificate and key are configured, and client certificates signed by the
+// client CA are verified when one is configured, for the mtls auth mode.
+func Run(router *gin.Engine, addr string) error {
+	if tlsCertFile == "" || tlsKeyFile == "" {
+		return serve(addr, func(listener net.Listener) error {
+			return router.RunListener(listener)
+		})
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if clientCAFile != "" {
+		caPEM, err := ioutil.ReadFile(clientCAFile)
+		if err != nil {
+			return fmt.Errorf("error reading client CA file %s. Error: %w", clientCAFile, err)
+		}
+
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caPEM) {
+			return fmt.Errorf("no certificates found in client CA file %s", clientCAFile)
+		}
+
+		// clients without a certificate can still use the other auth modes
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	server := &http.Server{
+		Addr:      addr,
+		Handler:   router,
+		TLSConfig: tlsConfig,
+	}
+
+	return serve(addr, func(listener net.Listener) error {
+		return server.ServeTLS(listener, tlsCertFile, tlsKeyFile)
+	})
+}
+
+// serve listens on the address and marks the server as serving until fn
+// returns
+func serve(addr string, fn func(listener net.Listener) error) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("error listening on %s. Error: %w", addr, err)
+	}
+
+	atomic.StoreInt32(&serving, 1)
+	defer atomic.StoreInt32(&serving, 0)
+
+	return fn(listener)
+}
+
+// ServerCheck fails if the HTTP server is not accepting connections
+func ServerCheck(_ *http.Request) error {
+	if atomic.LoadInt32(&serving) == 0 {
+		return errors.New("HTTP server is not serving")
+	}
+
+	return nil
+}
@@ -0,0 +1,258 @@
+package signature
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testNow = time.Unix(1700000000, 0)
+
+func newTestSigner(secrets ...string) *Signer {
+	s := NewSigner(secrets...)
+	s.now = func() time.Time { return testNow }
+
+	return s
+}
+
+func newTestVerifier(t *testing.T, secrets []string, tolerance time.Duration) *Verifier {
+	t.Helper()
+
+	v, err := NewVerifierWithTolerance(secrets, tolerance)
+	if err != nil {
+		t.Fatalf("unexpected error creating verifier: %v", err)
+	}
+
+	v.now = func() time.Time { return testNow }
+
+	return v
+}
+
+func TestNewSignerWithoutSecrets(t *testing.T) {
+	if s := NewSigner("", " "); s != nil {
+		t.Errorf("expected no signer without secrets, got %v", s)
+	}
+}
+
+func TestSignRequest(t *testing.T) {
+	body := []byte(`{"type":"new"}`)
+
+	req, _ := http.NewRequest(http.MethodPost, "http://example.com", nil)
+	newTestSigner("secret").SignRequest(req, body)
+
+	if got := req.Header.Get(TimestampHeader); got != strconv.FormatInt(testNow.Unix(), 10) {
+		t.Errorf("expected timestamp header %d, got %s", testNow.Unix(), got)
+	}
+
+	if err := newTestVerifier(t, []string{"secret"}, DefaultTolerance).VerifyRequest(req, body); err != nil {
+		t.Errorf("expected signed request to verify, got %v", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"type":"new"}`)
+	timestamp := testNow.Unix()
+
+	tests := []struct {
+		name            string
+		signerSecrets   []string
+		verifierSecrets []string
+		timestamp       string
+		signature       string
+		body            []byte
+		wantErr         error
+	}{
+		{
+			name:            "valid signature",
+			signerSecrets:   []string{"secret"},
+			verifierSecrets: []string{"secret"},
+			body:            body,
+		},
+		{
+			name:            "receiver with the old secret during a rotation",
+			signerSecrets:   []string{"new", "old"},
+			verifierSecrets: []string{"old"},
+			body:            body,
+		},
+		{
+			name:            "receiver with both secrets during a rotation",
+			signerSecrets:   []string{"new"},
+			verifierSecrets: []string{"old", "new"},
+			body:            body,
+		},
+		{
+			name:            "wrong secret",
+			signerSecrets:   []string{"secret"},
+			verifierSecrets: []string{"other"},
+			body:            body,
+			wantErr:         ErrNoValidSignature,
+		},
+		{
+			name:            "tampered body",
+			signerSecrets:   []string{"secret"},
+			verifierSecrets: []string{"secret"},
+			body:            []byte(`{"type":"resolved"}`),
+			wantErr:         ErrNoValidSignature,
+		},
+		{
+			name:            "unknown scheme",
+			verifierSecrets: []string{"secret"},
+			signature:       "v0=" + computeHMAC([]byte("secret"), timestamp, body),
+			body:            body,
+			wantErr:         ErrNoValidSignature,
+		},
+		{
+			name:            "missing signature",
+			verifierSecrets: []string{"secret"},
+			body:            body,
+			wantErr:         ErrMissingHeaders,
+		},
+		{
+			name:            "invalid timestamp",
+			signerSecrets:   []string{"secret"},
+			verifierSecrets: []string{"secret"},
+			timestamp:       "yesterday",
+			body:            body,
+			wantErr:         ErrInvalidTimestamp,
+		},
+		{
+			name:            "timestamp too old",
+			signerSecrets:   []string{"secret"},
+			verifierSecrets: []string{"secret"},
+			timestamp:       strconv.FormatInt(testNow.Add(-DefaultTolerance-time.Second).Unix(), 10),
+			body:            body,
+			wantErr:         ErrTimestampExpired,
+		},
+		{
+			name:            "timestamp in the future",
+			signerSecrets:   []string{"secret"},
+			verifierSecrets: []string{"secret"},
+			timestamp:       strconv.FormatInt(testNow.Add(DefaultTolerance+time.Second).Unix(), 10),
+			body:            body,
+			wantErr:         ErrTimestampExpired,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			timestampHeader := test.timestamp
+			if timestampHeader == "" {
+				timestampHeader = strconv.FormatInt(timestamp, 10)
+			}
+
+			signature := test.signature
+			if signature == "" && len(test.signerSecrets) > 0 {
+				ts, err := strconv.ParseInt(timestampHeader, 10, 64)
+				if err != nil {
+					ts = timestamp
+				}
+
+				signature = newTestSigner(test.signerSecrets...).Sign(ts, body)
+			}
+
+			v := newTestVerifier(t, test.verifierSecrets, DefaultTolerance)
+
+			if err := v.Verify(timestampHeader, signature, test.body); !errors.Is(err, test.wantErr) {
+				t.Errorf("expected error %v, got %v", test.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVerifyReplay(t *testing.T) {
+	body := []byte(`{"type":"new"}`)
+	timestamp := strconv.FormatInt(testNow.Unix(), 10)
+	signature := newTestSigner("secret").Sign(testNow.Unix(), body)
+
+	v := newTestVerifier(t, []string{"secret"}, DefaultTolerance)
+
+	if err := v.Verify(timestamp, signature, body); err != nil {
+		t.Fatalf("expected first request to verify, got %v", err)
+	}
+
+	if err := v.Verify(timestamp, signature, body); !errors.Is(err, ErrReplayedSignature) {
+		t.Errorf("expected replayed request to be rejected, got %v", err)
+	}
+
+	// once the signature is outside of the tolerance it is rejected because of
+	// its timestamp, and forgotten when the next signature is seen
+	later := testNow.Add(DefaultTolerance + time.Second)
+	v.now = func() time.Time { return later }
+
+	if err := v.Verify(timestamp, signature, body); !errors.Is(err, ErrTimestampExpired) {
+		t.Errorf("expected expired request to be rejected, got %v", err)
+	}
+
+	laterTimestamp := strconv.FormatInt(later.Unix(), 10)
+
+	if err := v.Verify(laterTimestamp, newTestSigner("secret").Sign(later.Unix(), body), body); err != nil {
+		t.Fatalf("expected new request to verify, got %v", err)
+	}
+
+	if _, ok := v.seen[seenKey(timestamp, body)]; ok || len(v.seen) != 1 {
+		t.Errorf("expected expired requests to be forgotten, %d are remembered", len(v.seen))
+	}
+}
+
+func TestVerifyReplayDuringRotation(t *testing.T) {
+	body := []byte(`{"type":"new"}`)
+	timestamp := strconv.FormatInt(testNow.Unix(), 10)
+	signatures := strings.Split(newTestSigner("new", "old").Sign(testNow.Unix(), body), ",")
+
+	v := newTestVerifier(t, []string{"old", "new"}, DefaultTolerance)
+
+	if err := v.Verify(timestamp, strings.Join(signatures, ","), body); err != nil {
+		t.Fatalf("expected first request to verify, got %v", err)
+	}
+
+	// replaying the request with only one of its signatures is still a replay
+	for _, signature := range signatures {
+		if err := v.Verify(timestamp, signature, body); !errors.Is(err, ErrReplayedSignature) {
+			t.Errorf("expected request replayed with %s to be rejected, got %v", signature, err)
+		}
+	}
+}
+
+func TestVerifySeenRequestsAreBounded(t *testing.T) {
+	defer func(max int) { maxSeenRequests = max }(maxSeenRequests)
+	maxSeenRequests = 100
+
+	v := newTestVerifier(t, []string{"secret"}, DefaultTolerance)
+
+	for i := 0; i < maxSeenRequests; i++ {
+		if err := v.markSeen(strconv.Itoa(i), testNow, testNow); err != nil {
+			t.Fatalf("expected request %d to be new, got %v", i, err)
+		}
+	}
+
+	// requests within the tolerance are not forgotten to make room for new ones
+	if err := v.markSeen("new", testNow, testNow); !errors.Is(err, ErrTooManyRequests) {
+		t.Errorf("expected new request to be rejected when full, got %v", err)
+	}
+
+	if err := v.markSeen("0", testNow, testNow); !errors.Is(err, ErrReplayedSignature) {
+		t.Errorf("expected remembered request to still be a replay, got %v", err)
+	}
+
+	// once they expire there is room again
+	later := testNow.Add(DefaultTolerance + time.Second)
+
+	if err := v.markSeen("new", later, later); err != nil {
+		t.Errorf("expected new request to be accepted once others expired, got %v", err)
+	}
+
+	if len(v.seen) != 1 {
+		t.Errorf("expected expired requests to be forgotten, %d are remembered", len(v.seen))
+	}
+}
+
+func TestNewVerifierWithTolerance(t *testing.T) {
+	for _, tolerance := range []time.Duration{0, -time.Second} {
+		if _, err := NewVerifierWithTolerance([]string{"secret"}, tolerance); err == nil {
+			t.Errorf("expected tolerance %s to be rejected", tolerance)
+		}
+	}
+}
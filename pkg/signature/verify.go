@@ -0,0 +1,177 @@
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultTolerance = 5 * time.Minute
+
+// maximum number of requests remembered to detect replays, new requests are
+// rejected once it is reached until the remembered ones expire
+var maxSeenRequests = 100000
+
+var (
+	ErrMissingHeaders    = errors.New("missing signature headers")
+	ErrInvalidTimestamp  = errors.New("invalid signature timestamp")
+	ErrTimestampExpired  = errors.New("signature timestamp outside of tolerance")
+	ErrNoValidSignature  = errors.New("no valid signature found")
+	ErrReplayedSignature = errors.New("signature has already been used")
+	ErrTooManyRequests   = errors.New("too many requests within the signature tolerance")
+)
+
+// Verifier checks the signatures of requests sent by the agent. It is meant
+// to be used by the services receiving agent notifications:
+//
+//	v := signature.NewVerifier([]string{os.Getenv("PORTER_AGENT_SIGNING_SECRET")})
+//	body, _ := io.ReadAll(req.Body)
+//	if err := v.VerifyRequest(req, body); err != nil {
+//		// reject the request
+//	}
+type Verifier struct {
+	secrets   [][]byte
+	tolerance time.Duration
+	now       func() time.Time
+
+	// seen holds the timestamps and body hashes of the requests verified
+	// within the tolerance window, along with the time they expire at, so that
+	// the same request cannot be replayed with any of its signatures
+	seen      map[string]time.Time
+	nextPrune time.Time
+	seenMu    sync.Mutex
+}
+
+// NewVerifier returns a Verifier accepting signatures made with any of the
+// given secrets within DefaultTolerance, which allows receivers to accept
+// both secrets during a rotation.
+func NewVerifier(secrets []string) *Verifier {
+	v, _ := NewVerifierWithTolerance(secrets, DefaultTolerance)
+	return v
+}
+
+// NewVerifierWithTolerance returns a Verifier accepting signatures made with
+// any of the given secrets at most tolerance before or after they are
+// verified. The tolerance must be positive, since it bounds both the window
+// in which requests could be replayed and the signatures remembered to
+// prevent it.
+func NewVerifierWithTolerance(secrets []string, tolerance time.Duration) (*Verifier, error) {
+	if tolerance <= 0 {
+		return nil, fmt.Errorf("signature tolerance must be positive, got %s", tolerance)
+	}
+
+	v := &Verifier{
+		tolerance: tolerance,
+		now:       time.Now,
+		seen:      make(map[string]time.Time),
+	}
+
+	for _, secret := range secrets {
+		if secret = strings.TrimSpace(secret); secret != "" {
+			v.secrets = append(v.secrets, []byte(secret))
+		}
+	}
+
+	return v, nil
+}
+
+// VerifyRequest verifies the signature headers of the request against its body.
+func (v *Verifier) VerifyRequest(req *http.Request, body []byte) error {
+	return v.Verify(req.Header.Get(TimestampHeader), req.Header.Get(SignatureHeader), body)
+}
+
+// Verify verifies the raw header values against the body. A request is only
+// accepted once within the tolerance window, whichever of its signatures is
+// presented.
+func (v *Verifier) Verify(timestampHeader, signatureHeader string, body []byte) error {
+	if timestampHeader == "" || signatureHeader == "" {
+		return ErrMissingHeaders
+	}
+
+	timestamp, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidTimestamp, timestampHeader)
+	}
+
+	now := v.now()
+	signedAt := time.Unix(timestamp, 0)
+
+	if now.Sub(signedAt) > v.tolerance || signedAt.Sub(now) > v.tolerance {
+		return ErrTimestampExpired
+	}
+
+	for _, secret := range v.secrets {
+		expected := computeHMAC(secret, timestamp, body)
+
+		for _, sig := range strings.Split(signatureHeader, ",") {
+			parts := strings.SplitN(strings.TrimSpace(sig), "=", 2)
+			if len(parts) != 2 || parts[0] != schemeV1 {
+				continue
+			}
+
+			if hmac.Equal([]byte(parts[1]), []byte(expected)) {
+				return v.markSeen(seenKey(timestampHeader, body), signedAt, now)
+			}
+		}
+	}
+
+	return ErrNoValidSignature
+}
+
+// seenKey identifies a request by its timestamp and body rather than by its
+// signatures, since a request signed with several secrets carries several
+func seenKey(timestampHeader string, body []byte) string {
+	sum := sha256.Sum256(body)
+	return timestampHeader + "." + hex.EncodeToString(sum[:])
+}
+
+// markSeen remembers a request until its timestamp is outside of the
+// tolerance, after which it is rejected anyway, and returns an error if it was
+// already seen or if too many requests are remembered
+func (v *Verifier) markSeen(key string, signedAt, now time.Time) error {
+	v.seenMu.Lock()
+	defer v.seenMu.Unlock()
+
+	if now.After(v.nextPrune) {
+		v.pruneExpired(now)
+	}
+
+	if expiresAt, ok := v.seen[key]; ok && !now.After(expiresAt) {
+		return ErrReplayedSignature
+	}
+
+	if len(v.seen) >= maxSeenRequests {
+		return ErrTooManyRequests
+	}
+
+	expiresAt := signedAt.Add(v.tolerance)
+	v.seen[key] = expiresAt
+
+	if expiresAt.Before(v.nextPrune) {
+		v.nextPrune = expiresAt
+	}
+
+	return nil
+}
+
+// pruneExpired forgets the requests whose timestamp is outside of the
+// tolerance. It only runs once the earliest remembered request expires, so
+// that verifying a request does not scan every remembered one.
+func (v *Verifier) pruneExpired(now time.Time) {
+	v.nextPrune = now.Add(v.tolerance)
+
+	for k, expiresAt := range v.seen {
+		if now.After(expiresAt) {
+			delete(v.seen, k)
+		} else if expiresAt.Before(v.nextPrune) {
+			v.nextPrune = expiresAt
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package silence
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/porter-dev/porter-agent/api/v1alpha1"
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/robfig/cron/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var cronParser = cron.NewParser(
+	cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+//+kubebuilder:rbac:groups=agent.porter.run,resources=silences,verbs=get;list;watch;create;update;patch;delete
+
+// Store reads and writes the Silence custom resources. Reads go directly to
+// the API server so that new silences take effect right away.
+type Store struct {
+	reader client.Reader
+	writer client.Writer
+	now    func() time.Time
+}
+
+func NewStore(reader client.Reader, writer client.Writer) *Store {
+	return &Store{
+		reader: reader,
+		writer: writer,
+		now:    time.Now,
+	}
+}
+
+func (s *Store) List(ctx context.Context) ([]v1alpha1.Silence, error) {
+	silences := &v1alpha1.SilenceList{}
+
+	if err := s.reader.List(ctx, silences); err != nil {
+		return nil, fmt.Errorf("error listing silences. Error: %w", err)
+	}
+
+	return silences.Items, nil
+}
+
+func (s *Store) Get(ctx context.Context, name string) (*v1alpha1.Silence, error) {
+	silence := &v1alpha1.Silence{}
+
+	if err := s.reader.Get(ctx, client.ObjectKey{Name: name}, silence); err != nil {
+		return nil, err
+	}
+
+	return silence, nil
+}
+
+// Create validates and creates a new silence. A name is generated if the
+// silence does not have one.
+func (s *Store) Create(ctx context.Context, silence *v1alpha1.Silence) error {
+	if err := Validate(silence); err != nil {
+		return err
+	}
+
+	if silence.Name == "" && silence.GenerateName == "" {
+		silence.GenerateName = "silence-"
+	}
+
+	return s.writer.Create(ctx, silence)
+}
+
+func (s *Store) Delete(ctx context.Context, name string) error {
+	return s.writer.Delete(ctx, &v1alpha1.Silence{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+	})
+}
+
+// GetMatchingSilence returns an active silence matching the incident, or nil
+// if the incident is not silenced
+func (s *Store) GetMatchingSilence(ctx context.Context, incident *models.Incident) (*v1alpha1.Silence, error) {
+	silences, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+
+	for i := range silences {
+		silence := &silences[i]
+
+		if active, err := IsActive(silence, now); err != nil || !active {
+			continue
+		}
+
+		if matches, err := Matches(silence, incident); err == nil && matches {
+			return silence, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Validate checks the time range, schedule and selector of a silence. A
+// silence needs at least one matcher, so that it does not silence every
+// incident, and an end time or a schedule, so that it does not last forever.
+func Validate(silence *v1alpha1.Silence) error {
+	spec := silence.Spec
+
+	if len(spec.Namespaces) == 0 && len(spec.Releases) == 0 && len(spec.Reasons) == 0 &&
+		(spec.Selector == nil || (len(spec.Selector.MatchLabels) == 0 && len(spec.Selector.MatchExpressions) == 0)) {
+		return fmt.Errorf("a namespace, release, reason or selector is required")
+	}
+
+	if spec.EndsAt == nil && spec.Schedule == "" {
+		return fmt.Errorf("an end time or a schedule is required")
+	}
+
+	if spec.StartsAt != nil && spec.EndsAt != nil && !spec.EndsAt.After(spec.StartsAt.Time) {
+		return fmt.Errorf("silence must end after it starts")
+	}
+
+	if spec.Schedule != "" {
+		if _, err := cronParser.Parse(spec.Schedule); err != nil {
+			return fmt.Errorf("invalid schedule %q. Error: %w", spec.Schedule, err)
+		}
+
+		if spec.Duration == nil || spec.Duration.Duration <= 0 {
+			return fmt.Errorf("a positive duration is required for a scheduled silence")
+		}
+	}
+
+	if spec.Selector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(spec.Selector); err != nil {
+			return fmt.Errorf("invalid selector. Error: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// IsActive returns true if the silence is active at the given time
+func IsActive(silence *v1alpha1.Silence, now time.Time) (bool, error) {
+	spec := silence.Spec
+
+	if spec.StartsAt != nil && now.Before(spec.StartsAt.Time) {
+		return false, nil
+	}
+
+	if spec.EndsAt != nil && !now.Before(spec.EndsAt.Time) {
+		return false, nil
+	}
+
+	if spec.Schedule == "" {
+		return true, nil
+	}
+
+	if spec.Duration == nil {
+		return false, fmt.Errorf("missing duration for scheduled silence %s", silence.Name)
+	}
+
+	schedule, err := cronParser.Parse(spec.Schedule)
+	if err != nil {
+		return false, fmt.Errorf("invalid schedule for silence %s. Error: %w", silence.Name, err)
+	}
+
+	// a window is open if one was started within the last duration
+	windowStart := schedule.Next(now.Add(-spec.Duration.Duration))
+
+	return !windowStart.After(now), nil
+}
+
+// Matches returns true if every non-empty matcher of the silence matches the incident
+func Matches(silence *v1alpha1.Silence, incident *models.Incident) (bool, error) {
+	spec := silence.Spec
+
+	if len(spec.Namespaces) > 0 && !matchesGlob(spec.Namespaces, incident.Namespace) {
+		return false, nil
+	}
+
+	if len(spec.Releases) > 0 && !matchesGlob(spec.Releases, incident.ReleaseName) {
+		return false, nil
+	}
+
+	if len(spec.Reasons) > 0 {
+		found := false
+
+		for _, reason := range spec.Reasons {
+			if reason == incident.LatestFilterReason {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false, nil
+		}
+	}
+
+	if spec.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(spec.Selector)
+		if err != nil {
+			return false, err
+		}
+
+		if !selector.Matches(labels.Set(incident.Labels)) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func matchesGlob(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, value); err == nil && ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,75 @@
+package silence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/porter-dev/porter-agent/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidate(t *testing.T) {
+	now := time.Now()
+	endsAt := &metav1.Time{Time: now.Add(time.Hour)}
+
+	tests := []struct {
+		name    string
+		spec    v1alpha1.SilenceSpec
+		wantErr bool
+	}{
+		{
+			name: "namespace until an end time",
+			spec: v1alpha1.SilenceSpec{Namespaces: []string{"prod"}, EndsAt: endsAt},
+		},
+		{
+			name: "selector on a schedule",
+			spec: v1alpha1.SilenceSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				Schedule: "0 2 * * SAT",
+				Duration: &metav1.Duration{Duration: 2 * time.Hour},
+			},
+		},
+		{
+			name:    "no matcher",
+			spec:    v1alpha1.SilenceSpec{EndsAt: endsAt},
+			wantErr: true,
+		},
+		{
+			name:    "empty selector",
+			spec:    v1alpha1.SilenceSpec{Selector: &metav1.LabelSelector{}, EndsAt: endsAt},
+			wantErr: true,
+		},
+		{
+			name:    "no end",
+			spec:    v1alpha1.SilenceSpec{Reasons: []string{"OOMKilled"}},
+			wantErr: true,
+		},
+		{
+			name: "end before start",
+			spec: v1alpha1.SilenceSpec{
+				Releases: []string{"web"},
+				StartsAt: endsAt,
+				EndsAt:   &metav1.Time{Time: now},
+			},
+			wantErr: true,
+		},
+		{
+			name: "schedule without duration",
+			spec: v1alpha1.SilenceSpec{
+				Releases: []string{"web"},
+				Schedule: "0 2 * * SAT",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		err := Validate(&v1alpha1.Silence{Spec: test.spec})
+
+		if test.wantErr && err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		} else if !test.wantErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+	}
+}
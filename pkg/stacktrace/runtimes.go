@@ -0,0 +1,275 @@
+package stacktrace
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/porter-dev/porter-agent/pkg/models"
+)
+
+// Go panics and fatal errors print the message, then the stack of each
+// goroutine as function and file lines:
+//
+//	panic: runtime error: index out of range [5] with length 3
+//
+//	goroutine 1 [running]:
+//	main.main()
+//		/app/main.go:12 +0x1d
+var (
+	goPanicRegexp     = regexp.MustCompile(`^(panic|fatal error): (.*?)( \[recovered\])?$`)
+	goGoroutineRegexp = regexp.MustCompile(`^goroutine \d+ \[.*\]:$`)
+	goFileRegexp      = regexp.MustCompile(`^\t(.+?):(\d+)(?: .*)?$`)
+	goArgsRegexp      = regexp.MustCompile(`\([^()]*\)$`)
+)
+
+// the message of a panic is looked for this many lines before the stack
+const goMaxMessageLines = 20
+
+func parseGo(lines []string) (*models.StackTrace, int) {
+	start := lastIndex(lines, goPanicRegexp)
+	if start == -1 {
+		return nil, -1
+	}
+
+	match := goPanicRegexp.FindStringSubmatch(lines[start])
+
+	trace := &models.StackTrace{
+		Runtime: "go",
+		Type:    match[1],
+		Message: match[2],
+	}
+
+	last := start
+
+	i := start + 1
+	for ; i < len(lines) && i <= start+goMaxMessageLines; i++ {
+		if goGoroutineRegexp.MatchString(lines[i]) {
+			break
+		}
+	}
+
+	// only the goroutine which panicked is read
+	for i++; i+1 < len(lines); i += 2 {
+		function, file := lines[i], goFileRegexp.FindStringSubmatch(lines[i+1])
+		if function == "" || file == nil || strings.HasPrefix(function, "created by ") {
+			break
+		}
+
+		line, _ := strconv.Atoi(file[2])
+
+		trace.Frames = append(trace.Frames, &models.StackFrame{
+			Function: goArgsRegexp.ReplaceAllString(function, ""),
+			File:     file[1],
+			Line:     line,
+		})
+
+		last = i + 1
+	}
+
+	return trace, last
+}
+
+// Python prints tracebacks outermost frame first, followed by the exception:
+//
+//	Traceback (most recent call last):
+//	  File "app.py", line 3, in <module>
+//	    foo()
+//	ValueError: invalid literal
+var (
+	pythonTracebackRegexp = regexp.MustCompile(`^Traceback \(most recent call last\):$`)
+	pythonFrameRegexp     = regexp.MustCompile(`^\s+File "(.+)", line (\d+), in (.+)$`)
+	pythonErrorRegexp     = regexp.MustCompile(`^([A-Za-z_][\w.]*)(?:: (.*))?$`)
+)
+
+func parsePython(lines []string) (*models.StackTrace, int) {
+	start := lastIndex(lines, pythonTracebackRegexp)
+	if start == -1 {
+		return nil, -1
+	}
+
+	trace := &models.StackTrace{
+		Runtime: "python",
+	}
+
+	for i := start + 1; i < len(lines); i++ {
+		line := lines[i]
+
+		if match := pythonFrameRegexp.FindStringSubmatch(line); match != nil {
+			lineNumber, _ := strconv.Atoi(match[2])
+
+			// innermost first
+			trace.Frames = append([]*models.StackFrame{{
+				Function: match[3],
+				File:     match[1],
+				Line:     lineNumber,
+			}}, trace.Frames...)
+
+			continue
+		}
+
+		// source lines and carets are indented
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+
+		if match := pythonErrorRegexp.FindStringSubmatch(line); match != nil {
+			trace.Type, trace.Message = match[1], match[2]
+		} else {
+			trace.Type = line
+		}
+
+		return trace, i
+	}
+
+	// the exception was not logged yet
+	return nil, -1
+}
+
+// Node.js prints uncaught exceptions as the error followed by its frames:
+//
+//	TypeError: Cannot read properties of undefined (reading 'id')
+//	    at getUser (/app/users.js:12:20)
+//	    at /app/index.js:8:3
+var (
+	nodeFrameRegexp        = regexp.MustCompile(`^\s+at (?:(.+?) \((.+?):(\d+):\d+\)|(.+?):(\d+):\d+)$`)
+	nodeContinuationRegexp = regexp.MustCompile(`^\s+at `)
+	nodeErrorRegexp        = regexp.MustCompile(`^(?:Uncaught )?([\w$.]+)(?: \[\w+\])?: (.*)$`)
+)
+
+func parseNode(lines []string) (*models.StackTrace, int) {
+	start, end := lastBlock(lines, nodeFrameRegexp, nodeContinuationRegexp)
+	if start == -1 {
+		return nil, -1
+	}
+
+	trace := &models.StackTrace{
+		Runtime: "node",
+		Type:    "Error",
+	}
+
+	if start > 0 {
+		if match := nodeErrorRegexp.FindStringSubmatch(lines[start-1]); match != nil {
+			trace.Type, trace.Message = match[1], match[2]
+		} else {
+			trace.Message = strings.TrimSpace(lines[start-1])
+		}
+	}
+
+	for _, line := range lines[start : end+1] {
+		match := nodeFrameRegexp.FindStringSubmatch(line)
+		if match == nil {
+			// frames such as "at async Promise.all (index 0)"
+			trace.Frames = append(trace.Frames, &models.StackFrame{
+				Function: strings.TrimPrefix(strings.TrimSpace(line), "at "),
+			})
+
+			continue
+		}
+
+		frame := &models.StackFrame{
+			Function: match[1],
+			File:     match[2],
+		}
+
+		frame.Line, _ = strconv.Atoi(match[3])
+
+		if match[4] != "" {
+			frame.Function = "<anonymous>"
+			frame.File = match[4]
+			frame.Line, _ = strconv.Atoi(match[5])
+		}
+
+		trace.Frames = append(trace.Frames, frame)
+	}
+
+	return trace, end
+}
+
+// Java prints the exception followed by its frames, and then each of its
+// causes, of which the last one is the root cause:
+//
+//	Exception in thread "main" java.lang.IllegalStateException: failed
+//		at com.example.App.run(App.java:10)
+//	Caused by: java.io.IOException: connection reset
+//		at com.example.Client.read(Client.java:42)
+//		... 1 more
+var (
+	javaFrameRegexp        = regexp.MustCompile(`^\s+at ([\w$.<>/]+)\(([^)]*)\)$`)
+	javaContinuationRegexp = regexp.MustCompile(`^\s+(at |\.\.\. \d+ more)`)
+	javaErrorRegexp        = regexp.MustCompile(`^(?:Exception in thread ".*?" |Caused by: )?([\w$.]+)(?:: (.*))?$`)
+)
+
+func parseJava(lines []string) (*models.StackTrace, int) {
+	start, end := lastBlock(lines, javaFrameRegexp, javaContinuationRegexp)
+	if start == -1 {
+		return nil, -1
+	}
+
+	trace := &models.StackTrace{
+		Runtime: "java",
+	}
+
+	if start > 0 {
+		if match := javaErrorRegexp.FindStringSubmatch(strings.TrimSpace(lines[start-1])); match != nil {
+			trace.Type, trace.Message = match[1], match[2]
+		} else {
+			trace.Message = strings.TrimSpace(lines[start-1])
+		}
+	}
+
+	for _, line := range lines[start : end+1] {
+		match := javaFrameRegexp.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		frame := &models.StackFrame{
+			Function: match[1],
+			File:     match[2],
+		}
+
+		// the location is File.java:10, Native Method or Unknown Source
+		if i := strings.LastIndexByte(match[2], ':'); i != -1 {
+			if line, err := strconv.Atoi(match[2][i+1:]); err == nil {
+				frame.File, frame.Line = match[2][:i], line
+			}
+		}
+
+		trace.Frames = append(trace.Frames, frame)
+	}
+
+	return trace, end
+}
+
+// lastIndex returns the index of the last line matching the regexp, or -1
+func lastIndex(lines []string, re *regexp.Regexp) int {
+	for i := len(lines) - 1; i >= 0; i-- {
+		if re.MatchString(lines[i]) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// lastBlock returns the first and last index of the last block of lines
+// matching the continuation regexp which contains a frame, or -1
+func lastBlock(lines []string, frame, continuation *regexp.Regexp) (int, int) {
+	end := lastIndex(lines, frame)
+	if end == -1 {
+		return -1, -1
+	}
+
+	// a block can end with lines such as "... 1 more"
+	for end+1 < len(lines) && continuation.MatchString(lines[end+1]) {
+		end++
+	}
+
+	start := end
+	for start > 0 && continuation.MatchString(lines[start-1]) {
+		start--
+	}
+
+	return start, end
+}
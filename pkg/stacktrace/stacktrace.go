@@ -0,0 +1,97 @@
+// Package stacktrace extracts the error and stack trace of a crash from the
+// logs of a container, for Go panics, Python tracebacks, Node.js uncaught
+// exceptions and Java stack traces.
+package stacktrace
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/porter-dev/porter-agent/pkg/models"
+)
+
+const (
+	// maximum number of frames kept for a crash
+	maxFrames = 50
+
+	// number of innermost frames which identify a crash
+	fingerprintFrames = 5
+
+	// maximum length of a summary
+	maxSummaryLength = 200
+)
+
+// parser returns the last crash of a runtime in the lines of the logs, and
+// the index of its last line
+type parser func(lines []string) (*models.StackTrace, int)
+
+var parsers = []parser{parseGo, parsePython, parseNode, parseJava}
+
+// Extract returns the last crash in the logs, or nil if there is none
+func Extract(logs string) *models.StackTrace {
+	lines := strings.Split(strings.ReplaceAll(logs, "\r\n", "\n"), "\n")
+
+	var res *models.StackTrace
+	end := -1
+
+	for _, parse := range parsers {
+		if trace, last := parse(lines); trace != nil && last > end {
+			res, end = trace, last
+		}
+	}
+
+	if res == nil {
+		return nil
+	}
+
+	if len(res.Frames) > maxFrames {
+		res.Frames = res.Frames[:maxFrames]
+	}
+
+	res.Summary = Summary(res)
+	res.Fingerprint = fingerprint(res)
+
+	return res
+}
+
+// Summary returns the error of a crash on a single line, such as
+// "panic: runtime error: invalid memory address or nil pointer dereference"
+func Summary(trace *models.StackTrace) string {
+	summary := trace.Type
+
+	if message := strings.TrimSpace(trace.Message); message != "" {
+		if i := strings.IndexByte(message, '\n'); i != -1 {
+			message = message[:i]
+		}
+
+		summary = fmt.Sprintf("%s: %s", summary, message)
+	}
+
+	if runes := []rune(summary); len(runes) > maxSummaryLength {
+		summary = string(runes[:maxSummaryLength-3]) + "..."
+	}
+
+	return summary
+}
+
+// fingerprint hashes the runtime, the error type and the innermost frames of
+// a crash, leaving out line numbers and directories, which change between
+// builds
+func fingerprint(trace *models.StackTrace) string {
+	h := sha256.New()
+
+	fmt.Fprintf(h, "%s\n%s\n", trace.Runtime, trace.Type)
+
+	for i, frame := range trace.Frames {
+		if i == fingerprintFrames {
+			break
+		}
+
+		fmt.Fprintf(h, "%s@%s\n", frame.Function, path.Base(frame.File))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
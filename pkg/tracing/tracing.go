@@ -0,0 +1,145 @@
+// Package tracing sets up OpenTelemetry tracing of the agent, which exports
+// spans over OTLP/HTTP when it is enabled.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/porter-dev/porter-agent"
+
+var (
+	enabled     bool
+	endpoint    string
+	urlPath     string
+	insecure    bool
+	sampleRatio float64
+
+	// propagator is used to carry trace contexts through the notification
+	// queue, regardless of the global propagator
+	propagator = propagation.TraceContext{}
+)
+
+func init() {
+	viper.SetDefault("TRACING_ENABLED", false)
+	viper.SetDefault("TRACING_OTLP_ENDPOINT", "localhost:4318")
+	viper.SetDefault("TRACING_OTLP_URL_PATH", "/v1/traces")
+	viper.SetDefault("TRACING_OTLP_INSECURE", false)
+	viper.SetDefault("TRACING_SAMPLE_RATIO", 1.0)
+	viper.AutomaticEnv()
+
+	enabled = viper.GetBool("TRACING_ENABLED")
+	endpoint = viper.GetString("TRACING_OTLP_ENDPOINT")
+	urlPath = viper.GetString("TRACING_OTLP_URL_PATH")
+	insecure = viper.GetBool("TRACING_OTLP_INSECURE")
+	sampleRatio = viper.GetFloat64("TRACING_SAMPLE_RATIO")
+}
+
+// Init sets up the global tracer provider to export spans to the OTLP
+// endpoint, if tracing is enabled. The returned function flushes the spans
+// which have not been exported yet and stops the exporter.
+func Init(ctx context.Context) (func(context.Context) error, error) {
+	if !enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(endpoint),
+		otlptracehttp.WithURLPath(urlPath),
+	}
+
+	if insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating OTLP trace exporter. Error: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceNameKey.String("porter-agent"),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("error creating trace resource. Error: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return provider.Shutdown, nil
+}
+
+// StartSpan starts a span as a child of the span of the context. Without a
+// call to Init, the span is not recorded.
+func StartSpan(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, opts...)
+}
+
+// EndSpan records the error on the span, if there is one, and ends it
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// TraceParent returns the W3C traceparent of the span of the context, or an
+// empty string if the context has no sampled span
+func TraceParent(ctx context.Context) string {
+	if !trace.SpanContextFromContext(ctx).IsSampled() {
+		return ""
+	}
+
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+
+	return carrier.Get("traceparent")
+}
+
+// LinkFromTraceParent returns a link to the span of a W3C traceparent, and
+// false if the traceparent is empty or invalid
+func LinkFromTraceParent(traceParent string) (trace.Link, bool) {
+	if traceParent == "" {
+		return trace.Link{}, false
+	}
+
+	ctx := propagator.Extract(context.Background(), propagation.MapCarrier{
+		"traceparent": traceParent,
+	})
+
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return trace.Link{}, false
+	}
+
+	return trace.Link{SpanContext: spanContext}, true
+}
+
+// LinkFromContext returns a link to the span of the context, and false if the
+// context has no valid span
+func LinkFromContext(ctx context.Context) (trace.Link, bool) {
+	spanContext := trace.SpanContextFromContext(ctx)
+
+	return trace.Link{SpanContext: spanContext}, spanContext.IsValid()
+}
@@ -7,7 +7,11 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/tracing"
 	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
@@ -18,6 +22,8 @@ var porterHost string
 type FilteredMessageResult struct {
 	PodSummary        string
 	PodDetails        string
+	PodReason         string
+	PodSeverity       models.Severity
 	ContainerStatuses []*FilteredMessageContainerResult
 }
 
@@ -25,10 +31,15 @@ type FilteredMessageContainerResult struct {
 	ContainerName string
 	Summary       string
 	Details       string
+
+	// Reason is the machine-readable Kubernetes reason which caused this
+	// result, such as OOMKilled or ErrImagePull
+	Reason   string
+	Severity models.Severity
 }
 
 type PodFilter interface {
-	Filter(*corev1.Pod, bool) *FilteredMessageResult
+	Filter(context.Context, *corev1.Pod, bool) *FilteredMessageResult
 }
 
 type AgentPodFilter struct {
@@ -45,7 +56,13 @@ func NewAgentPodFilter(kubeClient *kubernetes.Clientset) PodFilter {
 	}
 }
 
-func (f *AgentPodFilter) Filter(pod *corev1.Pod, isJob bool) *FilteredMessageResult {
+func (f *AgentPodFilter) Filter(ctx context.Context, pod *corev1.Pod, isJob bool) *FilteredMessageResult {
+	ctx, span := tracing.StartSpan(ctx, "filter.Filter", trace.WithAttributes(
+		attribute.String("pod", pod.Name),
+		attribute.String("namespace", pod.Namespace),
+	))
+	defer span.End()
+
 	res := &FilteredMessageResult{}
 
 	for i := len(pod.Status.ContainerStatuses) - 1; i >= 0; i-- {
@@ -55,7 +72,7 @@ func (f *AgentPodFilter) Filter(pod *corev1.Pod, isJob bool) *FilteredMessageRes
 			continue
 		}
 
-		scaleDownEvent := f.getContainerEventForReasons(pod.Name, pod.Namespace, status.Name, "ScaleDown")
+		scaleDownEvent := f.getContainerEventForReasons(ctx, pod.Name, pod.Namespace, status.Name, "ScaleDown")
 		if scaleDownEvent != nil && strings.Contains(scaleDownEvent.Message, "deleting pod for node scale down") {
 			continue
 		}
@@ -66,7 +83,7 @@ func (f *AgentPodFilter) Filter(pod *corev1.Pod, isJob bool) *FilteredMessageRes
 		if (status.State.Terminated != nil && status.State.Terminated.ExitCode == 255) ||
 			(status.LastTerminationState.Terminated != nil && status.LastTerminationState.Terminated.ExitCode == 255) {
 			pods, err := f.kubeClient.CoreV1().Pods(pod.Namespace).List(
-				context.Background(), v1.ListOptions{
+				ctx, v1.ListOptions{
 					LabelSelector: fmt.Sprintf("app.kubernetes.io/instance=%s", pod.Labels["app.kubernetes.io/instance"]),
 				},
 			)
@@ -97,11 +114,12 @@ func (f *AgentPodFilter) Filter(pod *corev1.Pod, isJob bool) *FilteredMessageRes
 					if status.LastTerminationState.Terminated.Reason == "Error" {
 						containerResult.Summary = fmt.Sprintf("The application exited with exit code %d",
 							status.LastTerminationState.Terminated.ExitCode)
+						containerResult.Reason = status.LastTerminationState.Terminated.Reason
 
 						if status.LastTerminationState.Terminated.ExitCode == 137 {
 							// check for possible Killing or Unhealthy events for this container
 							event := f.getContainerEventForReasons(
-								pod.Name, pod.Namespace, status.Name, "Killing", "Unhealthy",
+								ctx, pod.Name, pod.Namespace, status.Name, "Killing", "Unhealthy",
 							)
 
 							if event != nil {
@@ -117,6 +135,7 @@ func (f *AgentPodFilter) Filter(pod *corev1.Pod, isJob bool) *FilteredMessageRes
 						}
 					} else if status.LastTerminationState.Terminated.Reason == "OOMKilled" {
 						containerResult.Summary = "The application was killed because it used too much memory"
+						containerResult.Reason = status.LastTerminationState.Terminated.Reason
 						containerResult.Details = fmt.Sprintf("The application exceeded its memory limit of %s. ",
 							pod.Spec.Containers[0].Resources.Limits.Memory().String())
 
@@ -126,12 +145,14 @@ func (f *AgentPodFilter) Filter(pod *corev1.Pod, isJob bool) *FilteredMessageRes
 					} else if status.LastTerminationState.Terminated.Reason == "ContainerCannotRun" ||
 						status.LastTerminationState.Terminated.Reason == "StartError" {
 						containerResult.Summary = "The application could not start running"
+						containerResult.Reason = status.LastTerminationState.Terminated.Reason
 						containerResult.Details = getFilteredMessage(status.LastTerminationState.Terminated.Message)
 					}
 				}
 			} else if status.State.Waiting.Reason == "ErrImagePull" ||
 				status.State.Waiting.Reason == "ImagePullBackOff" {
 				containerResult.Summary = "The image could not be pulled from the registry"
+				containerResult.Reason = status.State.Waiting.Reason
 				containerResult.Details = fmt.Sprintf("The application was unable to pull image %s. "+
 					"Please make sure you have linked this image registry to Porter by navigating to %s/"+
 					"integrations/registry. See documentation for linking your registry here: "+
@@ -139,6 +160,7 @@ func (f *AgentPodFilter) Filter(pod *corev1.Pod, isJob bool) *FilteredMessageRes
 					status.Image, porterHost)
 			} else if status.State.Waiting.Reason == "InvalidImageName" {
 				containerResult.Summary = "The image could not be pulled from the registry because the image URI is invalid"
+				containerResult.Reason = status.State.Waiting.Reason
 				containerResult.Details = fmt.Sprintf("The specified image %s is not a valid image URI.", status.Image)
 			}
 			// FIXME: check for RunContainerError
@@ -146,11 +168,12 @@ func (f *AgentPodFilter) Filter(pod *corev1.Pod, isJob bool) *FilteredMessageRes
 			if status.State.Terminated.Reason == "Error" {
 				containerResult.Summary = fmt.Sprintf("The application exited with exit code %d",
 					status.State.Terminated.ExitCode)
+				containerResult.Reason = status.State.Terminated.Reason
 
 				if status.State.Terminated.ExitCode == 137 {
 					// check for possible Killing or Unhealthy events for this container
 					event := f.getContainerEventForReasons(
-						pod.Name, pod.Namespace, status.Name, "Killing", "Unhealthy",
+						ctx, pod.Name, pod.Namespace, status.Name, "Killing", "Unhealthy",
 					)
 
 					if event != nil {
@@ -166,6 +189,7 @@ func (f *AgentPodFilter) Filter(pod *corev1.Pod, isJob bool) *FilteredMessageRes
 				}
 			} else if status.State.Terminated.Reason == "OOMKilled" {
 				containerResult.Summary = "The application was killed because it used too much memory"
+				containerResult.Reason = status.State.Terminated.Reason
 				containerResult.Details = fmt.Sprintf("The application exceeded its memory limit of %s. ",
 					pod.Spec.Containers[0].Resources.Limits.Memory().String())
 
@@ -175,11 +199,13 @@ func (f *AgentPodFilter) Filter(pod *corev1.Pod, isJob bool) *FilteredMessageRes
 			} else if status.State.Terminated.Reason == "ContainerCannotRun" ||
 				status.State.Terminated.Reason == "StartError" {
 				containerResult.Summary = "The application could not start running"
+				containerResult.Reason = status.State.Terminated.Reason
 				containerResult.Details = getFilteredMessage(status.State.Terminated.Message)
 			}
 		} else if status.State.Terminated != nil && status.State.Terminated.Reason == "" {
 			containerResult.Summary = fmt.Sprintf("The application exited with exit code %d",
 				status.State.Terminated.ExitCode)
+			containerResult.Reason = "Error"
 			containerResult.Details = fmt.Sprintf("The application exited with exit code %d. "+
 				"We recommend looking into https://docs.porter.run/managing-applications/alerting/pod-exit-codes "+
 				"to further debug the reason for the crash.",
@@ -187,6 +213,7 @@ func (f *AgentPodFilter) Filter(pod *corev1.Pod, isJob bool) *FilteredMessageRes
 		}
 
 		if containerResult.Details != "" && containerResult.Summary != "" {
+			containerResult.Severity = getSeverityForReason(containerResult.Reason, isJob)
 			res.ContainerStatuses = append(res.ContainerStatuses, containerResult)
 		}
 	}
@@ -196,6 +223,8 @@ func (f *AgentPodFilter) Filter(pod *corev1.Pod, isJob bool) *FilteredMessageRes
 	} else if len(res.ContainerStatuses) == 1 {
 		res.PodSummary = res.ContainerStatuses[0].Summary
 		res.PodDetails = res.ContainerStatuses[0].Details
+		res.PodReason = res.ContainerStatuses[0].Reason
+		res.PodSeverity = res.ContainerStatuses[0].Severity
 	} else { // more than one container
 		summary := ""
 		details := ""
@@ -203,6 +232,12 @@ func (f *AgentPodFilter) Filter(pod *corev1.Pod, isJob bool) *FilteredMessageRes
 		for _, containerResult := range res.ContainerStatuses {
 			summary += fmt.Sprintf("Container: %s. Summary: %s\n", containerResult.ContainerName, containerResult.Summary)
 			details += fmt.Sprintf("Container: %s. Details: %s\n", containerResult.ContainerName, containerResult.Details)
+
+			// the pod takes the reason and severity of its most severe container
+			if res.PodSeverity == "" || containerResult.Severity.Higher(res.PodSeverity) {
+				res.PodReason = containerResult.Reason
+				res.PodSeverity = containerResult.Severity
+			}
 		}
 
 		res.PodSummary = summary
@@ -213,11 +248,17 @@ func (f *AgentPodFilter) Filter(pod *corev1.Pod, isJob bool) *FilteredMessageRes
 }
 
 func (f *AgentPodFilter) getContainerEventForReasons(
-	podName, namespace, containerName string, reasons ...string,
+	ctx context.Context, podName, namespace, containerName string, reasons ...string,
 ) *corev1.Event {
+	ctx, span := tracing.StartSpan(ctx, "filter.getContainerEventForReasons", trace.WithAttributes(
+		attribute.String("container", containerName),
+		attribute.StringSlice("reasons", reasons),
+	))
+	defer span.End()
+
 	for _, reason := range reasons {
 		events, err := f.kubeClient.CoreV1().Events(namespace).List(
-			context.Background(), v1.ListOptions{
+			ctx, v1.ListOptions{
 				FieldSelector: fmt.Sprintf(
 					"involvedObject.name=%s,reason=%s,involvedObject.fieldPath=spec.containers{%s}",
 					podName, reason, containerName),
@@ -239,6 +280,22 @@ func (f *AgentPodFilter) sortEventsByCreationTimestamp(events []corev1.Event) {
 	})
 }
 
+func getSeverityForReason(reason string, isJob bool) models.Severity {
+	switch reason {
+	case "OOMKilled", "ContainerCannotRun", "StartError":
+		return models.SeverityCritical
+	case "Error":
+		// a failing job run is only critical for the next run of the job
+		if isJob {
+			return models.SeverityWarning
+		}
+
+		return models.SeverityCritical
+	}
+
+	return models.SeverityWarning
+}
+
 func getFilteredMessage(message string) string {
 	regex := regexp.MustCompile("starting container process caused:.*$")
 	matches := regex.FindStringSubmatch(message)
@@ -7,7 +7,9 @@ import (
 	"time"
 )
 
-// logs are of the form "log:<incident_id>:<timestamp>"
+// logs are of the form "log:<incident_id>:<timestamp>:<container_name>", so
+// that the logs of the containers of a pod captured at once have distinct
+// IDs. Logs captured by earlier versions have no container name.
 type Log struct {
 	incident  *Incident
 	timestamp int64
@@ -23,7 +25,7 @@ func NewLog(incident *Incident, timestamp int64) *Log {
 func NewLogFromString(id string) (*Log, error) {
 	segments := strings.Split(id, ":")
 
-	if len(segments) != 6 || (len(segments) > 0 && segments[0] != "log") {
+	if (len(segments) != 6 && len(segments) != 7) || segments[0] != "log" {
 		return nil, fmt.Errorf("invalid log of the form: %s", id)
 	}
 
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/porter-dev/porter-agent/pkg/models"
+)
+
+const (
+	minTokenLength = 2
+	maxTokenLength = 64
+)
+
+// TokenizeLogs splits text into the lowercase alphanumeric tokens used to
+// index and search logs, without duplicates
+func TokenizeLogs(text string) []string {
+	seen := make(map[string]bool)
+	var tokens []string
+
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for _, field := range fields {
+		if len(field) < minTokenLength || len(field) > maxTokenLength || seen[field] {
+			continue
+		}
+
+		seen[field] = true
+		tokens = append(tokens, field)
+	}
+
+	return tokens
+}
+
+// MatchLogLines returns up to max lines of the logs matching the expression
+func MatchLogLines(logs string, re *regexp.Regexp, max int) []*models.LogLineMatch {
+	var matches []*models.LogLineMatch
+
+	for i, line := range strings.Split(logs, "\n") {
+		locs := re.FindAllStringIndex(line, -1)
+		if len(locs) == 0 {
+			continue
+		}
+
+		var highlighted strings.Builder
+		prev := 0
+
+		for _, loc := range locs {
+			if loc[0] == loc[1] {
+				continue
+			}
+
+			highlighted.WriteString(html.EscapeString(line[prev:loc[0]]))
+			highlighted.WriteString("<mark>")
+			highlighted.WriteString(html.EscapeString(line[loc[0]:loc[1]]))
+			highlighted.WriteString("</mark>")
+
+			prev = loc[1]
+		}
+
+		highlighted.WriteString(html.EscapeString(line[prev:]))
+
+		matches = append(matches, &models.LogLineMatch{
+			LineNumber:  i + 1,
+			Line:        line,
+			Highlighted: highlighted.String(),
+		})
+
+		if len(matches) >= max {
+			break
+		}
+	}
+
+	return matches
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"context"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RevisionAnnotation is the revision of a deployment which a ReplicaSet runs
+const RevisionAnnotation = "deployment.kubernetes.io/revision"
+
+// GetPreviousReplicaSet returns the ReplicaSet of a deployment with the highest
+// revision before the one of rs, or nil if there is none
+func GetPreviousReplicaSet(ctx context.Context, c client.Reader, rs *appsv1.ReplicaSet, deploymentUID types.UID) (*appsv1.ReplicaSet, error) {
+	current, err := strconv.ParseInt(rs.Annotations[RevisionAnnotation], 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+
+	rsList := &appsv1.ReplicaSetList{}
+
+	if err := c.List(ctx, rsList, client.InNamespace(rs.Namespace)); err != nil {
+		return nil, err
+	}
+
+	var previous *appsv1.ReplicaSet
+	var previousRevision int64
+
+	for i := range rsList.Items {
+		candidate := &rsList.Items[i]
+
+		if owner := metav1.GetControllerOf(candidate); owner == nil || owner.UID != deploymentUID {
+			continue
+		}
+
+		revision, err := strconv.ParseInt(candidate.Annotations[RevisionAnnotation], 10, 64)
+		if err != nil || revision >= current {
+			continue
+		}
+
+		if previous == nil || revision > previousRevision {
+			previous, previousRevision = candidate, revision
+		}
+	}
+
+	return previous, nil
+}
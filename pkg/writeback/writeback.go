@@ -0,0 +1,343 @@
+package writeback
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/porter-dev/porter-agent/pkg/models"
+	"github.com/porter-dev/porter-agent/pkg/redis"
+	"github.com/porter-dev/porter-agent/pkg/tracing"
+	"github.com/spf13/viper"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var writeBackLog = ctrl.Log.WithName("write-back")
+
+const (
+	// annotations of the workload of an incident while it is active
+	IncidentIDAnnotation      = "porter.run/incident-id"
+	IncidentSummaryAnnotation = "porter.run/incident-summary"
+
+	// reasons of the Kubernetes events emitted on the workload
+	ReasonIncidentOpened   = "IncidentOpened"
+	ReasonIncidentReopened = "IncidentReopened"
+	ReasonIncidentResolved = "IncidentResolved"
+
+	// the API server rejects longer event messages
+	maxMessageLength = 1024
+	maxSummaryLength = 256
+
+	// how long to wait for a change before reading the feed again
+	readBlock = 15 * time.Second
+)
+
+// whether incidents are written back to their workloads
+var enabled bool
+
+func init() {
+	viper.SetDefault("WRITE_BACK_ENABLED", false)
+	viper.AutomaticEnv()
+
+	enabled = viper.GetBool("WRITE_BACK_ENABLED")
+}
+
+// Enabled returns whether incidents are written back to their workloads
+func Enabled() bool {
+	return enabled
+}
+
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;patch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;patch
+
+// Writer writes incidents back to the Deployment or Job they were discovered
+// on, so that they show up in kubectl describe. When an incident opens or is
+// reopened, it emits a Warning event with the summary of the incident on the
+// workload and annotates it with the incident ID, and when the incident is
+// resolved it emits a Normal event and removes the annotations. The workload
+// is recorded on the incident, so that it is found again without listing the
+// workloads of the namespace.
+type Writer struct {
+	redisClient *redis.Client
+	client      client.Client
+	recorder    record.EventRecorder
+}
+
+func NewWriter(redisClient *redis.Client, client client.Client, recorder record.EventRecorder) *Writer {
+	return &Writer{
+		redisClient: redisClient,
+		client:      client,
+		recorder:    recorder,
+	}
+}
+
+// Start writes back the changes of incidents made after it starts until the
+// context is done. It is run by the manager, so that only the leader writes
+// back, and changes made while there is no leader are not written back.
+func (w *Writer) Start(ctx context.Context) error {
+	w.redisClient.FollowIncidentChanges(ctx, readBlock, func(change *models.IncidentChange) {
+		if err := w.writeBack(ctx, change); err != nil {
+			writeBackLog.Error(err, "error writing back incident change", "incidentID", change.IncidentID,
+				"type", change.Type)
+		}
+	}, func(err error) {
+		writeBackLog.Error(err, "error reading incident changes")
+	})
+
+	return nil
+}
+
+func (w *Writer) writeBack(ctx context.Context, change *models.IncidentChange) error {
+	switch change.Type {
+	case models.IncidentChangeCreated:
+		if change.Event == nil {
+			return nil
+		}
+
+		return w.incidentOpened(ctx, change.IncidentID, change.Event)
+	case models.IncidentChangeStateChanged:
+		if change.State != "reopened" {
+			return nil
+		}
+
+		return w.incidentReopened(ctx, change)
+	case models.IncidentChangeResolved:
+		return w.incidentResolved(ctx, change)
+	}
+
+	return nil
+}
+
+// incidentOpened annotates the workload of the pod of the first event of an
+// incident, and emits an event on it
+func (w *Writer) incidentOpened(ctx context.Context, incidentID string, event *models.PodEvent) error {
+	ctx, span := tracing.StartSpan(ctx, "writeback.incidentOpened")
+	defer span.End()
+
+	workload, err := w.getWorkload(ctx, event.Namespace, event.PodName)
+	if err != nil {
+		return fmt.Errorf("error getting workload of pod %s. Error: %w", event.PodName, err)
+	} else if workload == nil {
+		return nil
+	}
+
+	if err := w.annotate(ctx, workload, incidentID, event); err != nil {
+		return err
+	}
+
+	w.recorder.Event(workload, corev1.EventTypeWarning, ReasonIncidentOpened,
+		truncate(fmt.Sprintf("%s (incident %s)", eventMessage(event), incidentID), maxMessageLength))
+
+	return nil
+}
+
+// incidentReopened annotates the workload recorded on a reopened incident again,
+// or the workload of the pod of its latest event if none was recorded, and
+// emits an event on it
+func (w *Writer) incidentReopened(ctx context.Context, change *models.IncidentChange) error {
+	ctx, span := tracing.StartSpan(ctx, "writeback.incidentReopened")
+	defer span.End()
+
+	events, err := w.redisClient.GetIncidentEventsByID(ctx, change.IncidentID)
+	if err != nil {
+		return fmt.Errorf("error getting events of incident %s. Error: %w", change.IncidentID, err)
+	} else if len(events) == 0 {
+		return nil
+	}
+
+	// the events are sorted from the latest
+	event := events[0]
+
+	workload, err := w.getRecordedWorkload(ctx, change.IncidentID, change.Namespace)
+	if err != nil {
+		return err
+	}
+
+	if workload == nil {
+		workload, err = w.getWorkload(ctx, event.Namespace, event.PodName)
+		if err != nil {
+			return fmt.Errorf("error getting workload of pod %s. Error: %w", event.PodName, err)
+		} else if workload == nil {
+			return nil
+		}
+	}
+
+	if err := w.annotate(ctx, workload, change.IncidentID, event); err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("Incident %s was reopened", change.IncidentID)
+	if change.User != "" {
+		message = fmt.Sprintf("%s by %s", message, change.User)
+	}
+
+	w.recorder.Event(workload, corev1.EventTypeWarning, ReasonIncidentReopened,
+		truncate(fmt.Sprintf("%s: %s", message, eventMessage(event)), maxMessageLength))
+
+	return nil
+}
+
+// incidentResolved removes the annotations of the workload recorded on a
+// resolved incident, and emits an event on it
+func (w *Writer) incidentResolved(ctx context.Context, change *models.IncidentChange) error {
+	ctx, span := tracing.StartSpan(ctx, "writeback.incidentResolved")
+	defer span.End()
+
+	workload, err := w.getRecordedWorkload(ctx, change.IncidentID, change.Namespace)
+	if err != nil {
+		return err
+	} else if workload == nil || workload.GetAnnotations()[IncidentIDAnnotation] != change.IncidentID {
+		// the workload is gone or annotated with a newer incident
+		return nil
+	}
+
+	patch := client.MergeFrom(workload.DeepCopyObject().(client.Object))
+
+	annotations := workload.GetAnnotations()
+	delete(annotations, IncidentIDAnnotation)
+	delete(annotations, IncidentSummaryAnnotation)
+
+	workload.SetAnnotations(annotations)
+
+	if err := w.client.Patch(ctx, workload, patch); err != nil {
+		return fmt.Errorf("error removing incident annotations from %s. Error: %w", workload.GetName(), err)
+	}
+
+	message := fmt.Sprintf("Incident %s was resolved", change.IncidentID)
+	if change.User != "" {
+		message = fmt.Sprintf("%s by %s", message, change.User)
+	}
+
+	w.recorder.Event(workload, corev1.EventTypeNormal, ReasonIncidentResolved, message)
+
+	return nil
+}
+
+// annotate annotates a workload with an incident and the summary of its event,
+// and records the workload on the incident
+func (w *Writer) annotate(ctx context.Context, workload client.Object, incidentID string, event *models.PodEvent) error {
+	patch := client.MergeFrom(workload.DeepCopyObject().(client.Object))
+
+	annotations := workload.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+
+	annotations[IncidentIDAnnotation] = incidentID
+	annotations[IncidentSummaryAnnotation] = truncate(event.Reason, maxSummaryLength)
+
+	workload.SetAnnotations(annotations)
+
+	if err := w.client.Patch(ctx, workload, patch); err != nil {
+		return fmt.Errorf("error annotating %s with incident ID: %s. Error: %w", workload.GetName(), incidentID, err)
+	}
+
+	kind := "Deployment"
+	if _, ok := workload.(*batchv1.Job); ok {
+		kind = "Job"
+	}
+
+	if err := w.redisClient.SetIncidentWorkload(ctx, incidentID, kind, workload.GetName()); err != nil {
+		return fmt.Errorf("error recording workload %s of incident ID: %s. Error: %w", workload.GetName(), incidentID, err)
+	}
+
+	return nil
+}
+
+// getRecordedWorkload returns the workload recorded on an incident, or nil if
+// none was recorded or it is gone
+func (w *Writer) getRecordedWorkload(ctx context.Context, incidentID, namespace string) (client.Object, error) {
+	kind, name, err := w.redisClient.GetIncidentWorkload(ctx, incidentID)
+	if err != nil {
+		return nil, err
+	}
+
+	var workload client.Object
+
+	switch kind {
+	case "Deployment":
+		workload = &appsv1.Deployment{}
+	case "Job":
+		workload = &batchv1.Job{}
+	default:
+		return nil, nil
+	}
+
+	if err := w.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, workload); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+
+	return workload, nil
+}
+
+// getWorkload returns the Deployment or Job owning a pod, or nil if the pod is
+// gone or has neither
+func (w *Writer) getWorkload(ctx context.Context, namespace, podName string) (client.Object, error) {
+	pod := &corev1.Pod{}
+
+	if err := w.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: podName}, pod); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return nil, nil
+	}
+
+	switch owner.Kind {
+	case "Job":
+		job := &batchv1.Job{}
+
+		if err := w.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: owner.Name}, job); err != nil {
+			return nil, client.IgnoreNotFound(err)
+		}
+
+		return job, nil
+	case "ReplicaSet":
+		rs := &appsv1.ReplicaSet{}
+
+		if err := w.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: owner.Name}, rs); err != nil {
+			return nil, client.IgnoreNotFound(err)
+		}
+
+		owner = metav1.GetControllerOf(rs)
+		if owner == nil || owner.Kind != "Deployment" {
+			return nil, nil
+		}
+
+		deployment := &appsv1.Deployment{}
+
+		if err := w.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: owner.Name}, deployment); err != nil {
+			return nil, client.IgnoreNotFound(err)
+		}
+
+		return deployment, nil
+	}
+
+	return nil, nil
+}
+
+// eventMessage returns the summary of an event followed by its details
+func eventMessage(event *models.PodEvent) string {
+	if event.Message == "" {
+		return event.Reason
+	}
+
+	return fmt.Sprintf("%s: %s", event.Reason, event.Message)
+}
+
+func truncate(s string, length int) string {
+	if len(s) <= length {
+		return s
+	}
+
+	return s[:length-3] + "..."
+}